@@ -0,0 +1,314 @@
+package cm
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// A Bag is a multiset, wrapping a map[Type]int of values to the number
+// of times they appear in the bag.
+//
+// As with Set, redundant operations with map are not implemented. To get
+// the number of distinct values in the bag, use len(); to iterate over
+// the values and their counts, use range in a for loop, etc.
+//
+// Only positive counts are stored. The methods on Bag remove values whose
+// count drops to zero or below. It is safe to manipulate a bag directly
+// with map syntax, but you should do the same, or the methods may
+// produce surprising results.
+//
+// Like Set, the Bag offers mutating operations; for instance, Sum will
+// add the counts of the target Bag into the Bag the method is called on.
+// The exception is Intersect, which, as on Set, returns a new Bag.
+//
+// The nil Bag is mostly legal and functions as an empty bag, except you
+// can not .Add, .Union, or .Sum it.
+type Bag[M comparable] map[M]int
+
+// BagCount is a value in a Bag and its count, as returned by MostCommon.
+type BagCount[M comparable] struct {
+	Value M
+	Count int
+}
+
+// BagFromSlice loads a bag in from a slice, counting each time a value
+// appears.
+func BagFromSlice[M comparable](l []M) Bag[M] {
+	b := Bag[M]{}
+
+	for _, val := range l {
+		b[val]++
+	}
+
+	return b
+}
+
+// BagFromIter loads a bag in from an iterator, counting each time a
+// value appears.
+func BagFromIter[M comparable](i iter.Seq[M]) Bag[M] {
+	b := Bag[M]{}
+
+	for val := range i {
+		b[val]++
+	}
+
+	return b
+}
+
+// BagFromSet returns a bag containing each value in the set once.
+func BagFromSet[M comparable](s Set[M]) Bag[M] {
+	b := make(Bag[M], len(s))
+
+	for val := range s {
+		b[val] = 1
+	}
+
+	return b
+}
+
+// AsSet returns the set of distinct values in the bag.
+func (b Bag[M]) AsSet() Set[M] {
+	s := make(Set[M], len(b))
+	for val := range b {
+		s[val] = void
+	}
+	return s
+}
+
+// Add adds the given value to the bag n times.
+//
+// This will panic if called on a nil Bag, or with a negative n.
+func (b Bag[M]) Add(v M, n int) {
+	if b == nil {
+		panic("Add called on nil Bag")
+	}
+	if n < 0 {
+		panic("Add called with a negative count")
+	}
+	if n == 0 {
+		return
+	}
+	b[v] += n
+}
+
+// Remove removes the given value from the bag n times. If the count
+// drops to zero or below, the value is removed from the bag entirely.
+//
+// This will panic if called with a negative n.
+func (b Bag[M]) Remove(v M, n int) {
+	if n < 0 {
+		panic("Remove called with a negative count")
+	}
+	count, exists := b[v]
+	if !exists {
+		return
+	}
+	if count <= n {
+		delete(b, v)
+	} else {
+		b[v] = count - n
+	}
+}
+
+// Count returns the number of times the value is in the bag.
+func (b Bag[M]) Count(v M) int {
+	return b[v]
+}
+
+// Contains returns true if the value is in the bag at least once.
+func (b Bag[M]) Contains(v M) bool {
+	return b[v] > 0
+}
+
+// Total returns the number of values in the bag, counting each value as
+// many times as it appears. len() gives the number of distinct values.
+func (b Bag[M]) Total() int {
+	total := 0
+	for _, count := range b {
+		total += count
+	}
+	return total
+}
+
+// Clone returns a copy of the bag.
+func (b Bag[M]) Clone() Bag[M] {
+	if b == nil {
+		return nil
+	}
+	newBag := make(Bag[M], len(b))
+	for val, count := range b {
+		newBag[val] = count
+	}
+	return newBag
+}
+
+// Equal returns if two bags contain the same values with the same
+// counts.
+func (b Bag[M]) Equal(r Bag[M]) bool {
+	if len(b) != len(r) {
+		return false
+	}
+	for val, count := range b {
+		if r[val] != count {
+			return false
+		}
+	}
+	return true
+}
+
+// Union sets the count of each value in this bag to the larger of its
+// count in this bag and its count in the passed-in bag. This bag is then
+// returned, allowing chaining.
+func (b Bag[M]) Union(r Bag[M]) Bag[M] {
+	if b == nil {
+		panic("Union called on nil Bag")
+	}
+	for val, count := range r {
+		if count > b[val] {
+			b[val] = count
+		}
+	}
+	return b
+}
+
+// Sum adds the counts of the passed-in bag to this bag. This bag is then
+// returned, allowing chaining.
+func (b Bag[M]) Sum(r Bag[M]) Bag[M] {
+	if b == nil {
+		panic("Sum called on nil Bag")
+	}
+	for val, count := range r {
+		b[val] += count
+	}
+	return b
+}
+
+// Subtract removes the counts of the passed-in bag from this bag,
+// removing any values whose count drops to zero or below.
+//
+// The bag this is called on is returned, allowing for chaining.
+func (b Bag[M]) Subtract(r Bag[M]) Bag[M] {
+	for val, count := range r {
+		b.Remove(val, count)
+	}
+	return b
+}
+
+// Intersect returns a new bag containing the values in both bags, each
+// with the smaller of its two counts.
+func (b Bag[M]) Intersect(r Bag[M]) Bag[M] {
+	small, large := b, r
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+
+	intersection := Bag[M]{}
+	for val, count := range small {
+		if rCount := large[val]; rCount > 0 {
+			intersection[val] = min(count, rCount)
+		}
+	}
+	return intersection
+}
+
+// MostCommon returns the k values with the highest counts, in descending
+// order of count. Values with equal counts are returned in an
+// unspecified order. If k is negative or larger than the number of
+// distinct values, all values are returned.
+func (b Bag[M]) MostCommon(k int) []BagCount[M] {
+	counts := make([]BagCount[M], 0, len(b))
+	for val, count := range b {
+		counts = append(counts, BagCount[M]{val, count})
+	}
+	slices.SortFunc(counts, func(a, b BagCount[M]) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+
+	if k >= 0 && k < len(counts) {
+		counts = counts[:k]
+	}
+	return counts
+}
+
+// A MapBag is a map that contains bags. It is to Bag what MapSet is to
+// Set, and the same comments about direct access apply; in particular,
+// reading a count out of a nil bag via mb[key].Count(val) is legal and
+// will not vivify the bag.
+//
+// The methods on MapBag create bags as necessary when writing, and
+// remove bags that become empty.
+type MapBag[K, V comparable] map[K]Bag[V]
+
+// Add adds the given value to the bag for the given key n times,
+// creating the bag if necessary.
+//
+// This will panic if called on a nil MapBag, or with a negative n.
+func (mb MapBag[K, V]) Add(key K, val V, n int) {
+	if n < 0 {
+		panic("Add called with a negative count")
+	}
+	mb.bag(key, "Add").Add(val, n)
+	mb.cleanup(key)
+}
+
+// AddByTuple will add to the bag via the given tuple n times.
+func (mb MapBag[K, V]) AddByTuple(key Tuple2[K, V], n int) {
+	mb.Add(key.Key1, key.Key2, n)
+}
+
+// Remove removes the given value from the bag for the given key n
+// times. If the bag becomes empty, it is removed from the MapBag.
+//
+// This will panic if called with a negative n.
+func (mb MapBag[K, V]) Remove(key K, val V, n int) {
+	b := mb[key]
+	b.Remove(val, n)
+	if len(b) == 0 {
+		delete(mb, key)
+	}
+}
+
+// Sum adds the counts of the passed-in bag into the bag for the given
+// key, creating it if necessary.
+func (mb MapBag[K, V]) Sum(key K, r Bag[V]) {
+	mb.bag(key, "Sum").Sum(r)
+	mb.cleanup(key)
+}
+
+// Union unions the passed-in bag into the bag for the given key,
+// creating it if necessary.
+func (mb MapBag[K, V]) Union(key K, r Bag[V]) {
+	mb.bag(key, "Union").Union(r)
+	mb.cleanup(key)
+}
+
+// AllValueBag returns a single bag containing the sum of all the bags in
+// the MapBag.
+func (mb MapBag[K, V]) AllValueBag() Bag[V] {
+	r := Bag[V]{}
+	for _, b := range mb {
+		r.Sum(b)
+	}
+	return r
+}
+
+// bag returns the bag for the given key, creating it if necessary.
+func (mb MapBag[K, V]) bag(key K, method string) Bag[V] {
+	if mb == nil {
+		panic(method + " called on a nil MapBag")
+	}
+	b := mb[key]
+	if b == nil {
+		b = Bag[V]{}
+		mb[key] = b
+	}
+	return b
+}
+
+// cleanup removes the bag for the given key if it is empty.
+func (mb MapBag[K, V]) cleanup(key K) {
+	if len(mb[key]) == 0 {
+		delete(mb, key)
+	}
+}
@@ -0,0 +1,140 @@
+package cm
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBag(t *testing.T) {
+	b := BagFromSlice([]string{"a", "b", "a", "c", "a", "b"})
+	if !reflect.DeepEqual(b, Bag[string]{"a": 3, "b": 2, "c": 1}) {
+		t.Fatal("incorrect BagFromSlice")
+	}
+	if !BagFromIter(slices.Values([]string{"a", "a"})).Equal(Bag[string]{"a": 2}) {
+		t.Fatal("incorrect BagFromIter")
+	}
+	if b.Count("a") != 3 || b.Count("z") != 0 || !b.Contains("c") ||
+		b.Contains("z") || b.Total() != 6 {
+		t.Fatal("incorrect counts")
+	}
+
+	b.Add("d", 2)
+	b.Add("e", 0)
+	if b.Count("d") != 2 || len(b) != 4 {
+		t.Fatal("incorrect Add")
+	}
+	b.Remove("d", 1)
+	b.Remove("c", 5)
+	b.Remove("z", 1)
+	if !b.Equal(Bag[string]{"a": 3, "b": 2, "d": 1}) {
+		t.Fatal("incorrect Remove")
+	}
+
+	var nilBag Bag[string]
+	panics(t, "can Add to a nil bag", func() { nilBag.Add("a", 1) })
+	panics(t, "can Add a negative count", func() { b.Add("a", -1) })
+	panics(t, "can Remove a negative count", func() { b.Remove("a", -1) })
+	if b["a"] != 3 {
+		t.Fatal("panicking Remove changed the count")
+	}
+	panics(t, "can Union a nil bag", func() { nilBag.Union(b) })
+	panics(t, "can Sum a nil bag", func() { nilBag.Sum(b) })
+	if nilBag.Clone() != nil || nilBag.Subtract(b) != nil || nilBag.Total() != 0 {
+		t.Fatal("nil bag doesn't act empty")
+	}
+
+	clone := b.Clone()
+	if !clone.Equal(b) || clone.Equal(Bag[string]{"a": 3, "b": 2, "d": 2}) ||
+		clone.Equal(Bag[string]{}) {
+		t.Fatal("incorrect Clone or Equal")
+	}
+
+	r := Bag[string]{"a": 1, "b": 5, "x": 2}
+	if !b.Clone().Union(r).Equal(Bag[string]{"a": 3, "b": 5, "d": 1, "x": 2}) {
+		t.Fatal("incorrect Union")
+	}
+	if !b.Clone().Sum(r).Equal(Bag[string]{"a": 4, "b": 7, "d": 1, "x": 2}) {
+		t.Fatal("incorrect Sum")
+	}
+	if !b.Clone().Subtract(r).Equal(Bag[string]{"a": 2, "d": 1}) {
+		t.Fatal("incorrect Subtract")
+	}
+	expected := Bag[string]{"a": 1, "b": 2}
+	if !b.Intersect(r).Equal(expected) || !r.Intersect(b).Equal(expected) {
+		t.Fatal("incorrect Intersect")
+	}
+	if !b.Intersect(Bag[string]{"a": 9}).Equal(Bag[string]{"a": 3}) {
+		t.Fatal("incorrect Intersect with a smaller bag")
+	}
+
+	if !b.AsSet().Equal(SetFromSlice([]string{"a", "b", "d"})) {
+		t.Fatal("incorrect AsSet")
+	}
+	if !BagFromSet(SetFromSlice([]int{1, 2})).Equal(Bag[int]{1: 1, 2: 1}) {
+		t.Fatal("incorrect BagFromSet")
+	}
+}
+
+func TestBagMostCommon(t *testing.T) {
+	b := Bag[string]{"a": 3, "b": 5, "c": 1, "d": 4}
+
+	if !reflect.DeepEqual(b.MostCommon(2), []BagCount[string]{
+		{"b", 5}, {"d", 4},
+	}) {
+		t.Fatal("incorrect MostCommon")
+	}
+	if len(b.MostCommon(-1)) != 4 || len(b.MostCommon(10)) != 4 ||
+		len(b.MostCommon(0)) != 0 {
+		t.Fatal("incorrect MostCommon bounds")
+	}
+	if b.MostCommon(-1)[3] != (BagCount[string]{"c", 1}) {
+		t.Fatal("incorrect MostCommon order")
+	}
+}
+
+func TestMapBag(t *testing.T) {
+	mb := MapBag[string, int]{}
+
+	mb.Add("a", 1, 2)
+	mb.AddByTuple(Tuple2[string, int]{"a", 2}, 1)
+	mb.Add("b", 1, 0)
+	if _, exists := mb["b"]; exists {
+		t.Fatal("adding nothing left an empty bag")
+	}
+	if mb["a"].Count(1) != 2 || mb["z"].Count(1) != 0 {
+		t.Fatal("incorrect counts")
+	}
+
+	mb.Sum("b", Bag[int]{1: 1})
+	mb.Union("b", Bag[int]{1: 3})
+	if mb["b"].Count(1) != 3 {
+		t.Fatal("incorrect Sum or Union")
+	}
+	mb.Sum("c", nil)
+	mb.Union("c", nil)
+	if _, exists := mb["c"]; exists {
+		t.Fatal("empty Sum or Union left an empty bag")
+	}
+
+	if !mb.AllValueBag().Equal(Bag[int]{1: 5, 2: 1}) {
+		t.Fatal("incorrect AllValueBag")
+	}
+
+	mb.Remove("a", 1, 2)
+	mb.Remove("a", 2, 1)
+	mb.Remove("z", 1, 1)
+	if _, exists := mb["a"]; exists {
+		t.Fatal("Remove didn't clean up the empty bag")
+	}
+
+	panics(t, "can Add a negative count", func() { mb.Add("n", 1, -1) })
+	panics(t, "can Remove a negative count", func() { mb.Remove("z", 1, -1) })
+	if _, exists := mb["n"]; exists {
+		t.Fatal("panicking Add left an empty bag")
+	}
+
+	var nilMB MapBag[string, int]
+	panics(t, "can Add to a nil MapBag", func() { nilMB.Add("a", 1, 1) })
+	nilMB.Remove("a", 1, 1)
+}
@@ -0,0 +1,298 @@
+package cm
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// A BiMap is a strict one-to-one mapping between primary and secondary
+// keys, a bijection. Every primary key has at most one secondary key and
+// every secondary key has at most one primary key, so either can be used
+// to look up the other.
+//
+// Contrast this with the DualMap, where the full key (P, S) must be
+// unique, but there can be any number of secondaries for a given primary
+// and vice versa.
+//
+// What happens when a Set would violate the one-to-one property is
+// controlled by the OnConflict field. The zero value is OnConflictError.
+//
+// The zero-value of this struct is safe to use. When Set or Inverse is
+// first used, the maps will be initialized.
+//
+// As with the DualMap, direct read access to the maps is permissible, but
+// you should not directly write to them. BiMap makes no guarantees if you
+// directly write to the internal maps.
+type BiMap[P, S comparable] struct {
+	Primary    map[P]S
+	Reverse    map[S]P
+	OnConflict ConflictPolicy
+}
+
+// ConflictPolicy selects what a BiMap does when a Set would map a key to
+// a second value.
+type ConflictPolicy int
+
+const (
+	// OnConflictError refuses the Set, leaving the BiMap unchanged, and
+	// returns a *BiMapConflict describing the conflict.
+	OnConflictError ConflictPolicy = iota
+
+	// OnConflictOverwrite evicts any existing mappings for either key,
+	// then sets the new mapping. This can remove up to two existing
+	// mappings.
+	OnConflictOverwrite
+
+	// OnConflictPanic panics with a *BiMapConflict describing the
+	// conflict, leaving the BiMap unchanged.
+	OnConflictPanic
+)
+
+// ErrBiMapConflict is the error wrapped by all BiMapConflict errors, so
+// that conflicts can be detected with errors.Is without knowing the key
+// types.
+var ErrBiMapConflict = errors.New("conflicting BiMap mapping")
+
+// BiMapConflict describes a Set on a BiMap that conflicted with existing
+// mappings. At least one of HasExistingSecondary and HasExistingPrimary
+// will be true.
+type BiMapConflict[P, S comparable] struct {
+	// The mapping that was being set.
+	Primary   P
+	Secondary S
+
+	// If the primary key was already mapped to a different secondary
+	// key, HasExistingSecondary is true and ExistingSecondary is that
+	// key.
+	ExistingSecondary    S
+	HasExistingSecondary bool
+
+	// If the secondary key was already mapped to a different primary
+	// key, HasExistingPrimary is true and ExistingPrimary is that key.
+	ExistingPrimary    P
+	HasExistingPrimary bool
+}
+
+// Error implements the error interface.
+func (bc *BiMapConflict[P, S]) Error() string {
+	msg := fmt.Sprintf("can not map %v to %v:", bc.Primary, bc.Secondary)
+	if bc.HasExistingSecondary {
+		msg += fmt.Sprintf(" %v is already mapped to %v",
+			bc.Primary, bc.ExistingSecondary)
+		if bc.HasExistingPrimary {
+			msg += " and"
+		}
+	}
+	if bc.HasExistingPrimary {
+		msg += fmt.Sprintf(" %v is already mapped to %v",
+			bc.Secondary, bc.ExistingPrimary)
+	}
+	return msg
+}
+
+// Unwrap returns ErrBiMapConflict.
+func (bc *BiMapConflict[P, S]) Unwrap() error {
+	return ErrBiMapConflict
+}
+
+func (bm *BiMap[P, S]) init() {
+	if bm.Primary == nil {
+		bm.Primary = map[P]S{}
+		bm.Reverse = map[S]P{}
+	}
+}
+
+// Set maps the primary key to the secondary key, and vice versa.
+//
+// If either key is already mapped to something else, the OnConflict
+// policy determines the result. Under OnConflictError a *BiMapConflict is
+// returned; otherwise the returned error is always nil. Setting a mapping
+// that already exists is not a conflict.
+func (bm *BiMap[P, S]) Set(p P, s S) error {
+	bm.init()
+
+	existingS, hasS := bm.Primary[p]
+	existingP, hasP := bm.Reverse[s]
+	hasS = hasS && existingS != s
+	hasP = hasP && existingP != p
+
+	if hasS || hasP {
+		switch bm.OnConflict {
+		case OnConflictOverwrite:
+			if hasS {
+				delete(bm.Reverse, existingS)
+			}
+			if hasP {
+				delete(bm.Primary, existingP)
+			}
+		default:
+			conflict := &BiMapConflict[P, S]{
+				Primary:              p,
+				Secondary:            s,
+				ExistingSecondary:    existingS,
+				HasExistingSecondary: hasS,
+				ExistingPrimary:      existingP,
+				HasExistingPrimary:   hasP,
+			}
+			if bm.OnConflict == OnConflictPanic {
+				panic(conflict)
+			}
+			return conflict
+		}
+	}
+
+	bm.Primary[p] = s
+	bm.Reverse[s] = p
+	return nil
+}
+
+// SetByTuple sets the mapping given as a tuple in primary/secondary
+// order. See Set.
+func (bm *BiMap[P, S]) SetByTuple(key Tuple2[P, S]) error {
+	return bm.Set(key.Key1, key.Key2)
+}
+
+// GetByPrimary returns the secondary key mapped to the given primary key.
+// The second value is true if the key exists, false otherwise.
+func (bm *BiMap[P, S]) GetByPrimary(p P) (s S, exists bool) {
+	s, exists = bm.Primary[p]
+	return s, exists
+}
+
+// GetBySecondary returns the primary key mapped to the given secondary
+// key. The second value is true if the key exists, false otherwise.
+func (bm *BiMap[P, S]) GetBySecondary(s S) (p P, exists bool) {
+	p, exists = bm.Reverse[s]
+	return p, exists
+}
+
+// DeleteByPrimary deletes the mapping for the given primary key, if any.
+func (bm *BiMap[P, S]) DeleteByPrimary(p P) {
+	s, exists := bm.Primary[p]
+	if !exists {
+		return
+	}
+	delete(bm.Primary, p)
+	delete(bm.Reverse, s)
+}
+
+// DeleteBySecondary deletes the mapping for the given secondary key, if
+// any.
+func (bm *BiMap[P, S]) DeleteBySecondary(s S) {
+	p, exists := bm.Reverse[s]
+	if !exists {
+		return
+	}
+	delete(bm.Reverse, s)
+	delete(bm.Primary, p)
+}
+
+// Inverse returns a view of this BiMap with the primary and secondary
+// keys swapped. The view shares its maps with this BiMap, so changes
+// made through either are visible in both.
+//
+// The view starts with the same OnConflict policy as this BiMap, but the
+// policies are independent afterwards.
+func (bm *BiMap[P, S]) Inverse() *BiMap[S, P] {
+	bm.init()
+	return &BiMap[S, P]{
+		Primary:    bm.Reverse,
+		Reverse:    bm.Primary,
+		OnConflict: bm.OnConflict,
+	}
+}
+
+// Clone returns a copy of the BiMap, with the same OnConflict policy.
+func (bm *BiMap[P, S]) Clone() *BiMap[P, S] {
+	r := &BiMap[P, S]{OnConflict: bm.OnConflict}
+	if bm.Primary != nil {
+		r.Primary = make(map[P]S, len(bm.Primary))
+		r.Reverse = make(map[S]P, len(bm.Reverse))
+		for p, s := range bm.Primary {
+			r.Primary[p] = s
+			r.Reverse[s] = p
+		}
+	}
+	return r
+}
+
+// Equal returns if this BiMap contains the same mappings as the
+// passed-in BiMap. The OnConflict policy is not compared.
+func (bm *BiMap[P, S]) Equal(r *BiMap[P, S]) bool {
+	if len(bm.Primary) != len(r.Primary) {
+		return false
+	}
+	for p, s := range bm.Primary {
+		rS, exists := r.Primary[p]
+		if !exists || rS != s {
+			return false
+		}
+	}
+	return true
+}
+
+// All returns an iterator over the mappings, yielding the primary key in
+// the key slot and the secondary key in the value slot.
+func (bm *BiMap[P, S]) All() iter.Seq2[P, S] {
+	return func(yield func(P, S) bool) {
+		for p, s := range bm.Primary {
+			if !yield(p, s) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the primary keys.
+func (bm *BiMap[P, S]) Keys() iter.Seq[P] {
+	return func(yield func(P) bool) {
+		for p := range bm.Primary {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the secondary keys.
+func (bm *BiMap[P, S]) Values() iter.Seq[S] {
+	return func(yield func(S) bool) {
+		for s := range bm.Reverse {
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
+
+// KeySlice returns the mappings as a slice of Tuple2 values, in
+// primary/secondary order.
+//
+// A nil map will return a nil slice.
+func (bm *BiMap[P, S]) KeySlice() []Tuple2[P, S] {
+	if bm.Primary == nil {
+		return nil
+	}
+
+	r := make([]Tuple2[P, S], 0, len(bm.Primary))
+	for p, s := range bm.Primary {
+		r = append(r, Tuple2[P, S]{p, s})
+	}
+	return r
+}
+
+// ValueSlice returns a slice containing all the secondary keys in a
+// nondeterministic order.
+func (bm *BiMap[P, S]) ValueSlice() []S {
+	r := make([]S, 0, len(bm.Reverse))
+	for s := range bm.Reverse {
+		r = append(r, s)
+	}
+	return r
+}
+
+// Len returns the number of mappings in the BiMap.
+func (bm *BiMap[P, S]) Len() int {
+	return len(bm.Primary)
+}
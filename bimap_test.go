@@ -0,0 +1,179 @@
+package cm
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestBiMap(t *testing.T) {
+	var bm BiMap[int, string]
+
+	if bm.KeySlice() != nil {
+		t.Fatal("zero BiMap doesn't return a nil key slice")
+	}
+
+	if bm.Set(1, "a") != nil || bm.SetByTuple(Tuple2[int, string]{2, "b"}) != nil {
+		t.Fatal("couldn't set")
+	}
+	if bm.Set(1, "a") != nil {
+		t.Fatal("re-setting an existing mapping is a conflict")
+	}
+
+	s, exists := bm.GetByPrimary(1)
+	if s != "a" || !exists {
+		t.Fatal("couldn't get by primary")
+	}
+	p, exists := bm.GetBySecondary("b")
+	if p != 2 || !exists {
+		t.Fatal("couldn't get by secondary")
+	}
+	_, exists = bm.GetBySecondary("z")
+	if exists {
+		t.Fatal("got a nonexistent secondary")
+	}
+	if bm.Len() != 2 {
+		t.Fatal("incorrect Len")
+	}
+
+	keys := bm.KeySlice()
+	slices.SortFunc(keys, func(a, b Tuple2[int, string]) int { return a.Key1 - b.Key1 })
+	if !reflect.DeepEqual(keys, []Tuple2[int, string]{{1, "a"}, {2, "b"}}) {
+		t.Fatal("incorrect KeySlice")
+	}
+	values := bm.ValueSlice()
+	slices.Sort(values)
+	if !reflect.DeepEqual(values, []string{"a", "b"}) {
+		t.Fatal("incorrect ValueSlice")
+	}
+
+	count := 0
+	for p, s := range bm.All() {
+		if bm.Primary[p] != s {
+			t.Fatal("incorrect All")
+		}
+		count++
+	}
+	for range bm.Keys() {
+		count++
+	}
+	for range bm.Values() {
+		count++
+	}
+	if count != 6 {
+		t.Fatal("incorrect iteration")
+	}
+	for range bm.All() {
+		break
+	}
+	for range bm.Keys() {
+		break
+	}
+	for range bm.Values() {
+		break
+	}
+
+	clone := bm.Clone()
+	if !clone.Equal(&bm) {
+		t.Fatal("clone isn't equal")
+	}
+	clone.DeleteByPrimary(1)
+	clone.DeleteByPrimary(9)
+	if clone.Equal(&bm) || bm.Len() != 2 {
+		t.Fatal("clone isn't independent")
+	}
+	clone.Set(1, "z")
+	if clone.Equal(&bm) {
+		t.Fatal("different mappings are equal")
+	}
+	clone.DeleteBySecondary("z")
+	clone.DeleteBySecondary("b")
+	clone.DeleteBySecondary("q")
+	if clone.Len() != 0 || len(clone.Reverse) != 0 {
+		t.Fatal("couldn't delete")
+	}
+
+	var empty BiMap[int, string]
+	if empty.Clone().Primary != nil {
+		t.Fatal("cloning an empty BiMap created maps")
+	}
+}
+
+func TestBiMapConflicts(t *testing.T) {
+	bm := BiMap[int, string]{}
+	bm.Set(1, "a")
+	bm.Set(2, "b")
+
+	err := bm.Set(1, "b")
+	var conflict *BiMapConflict[int, string]
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrBiMapConflict) {
+		t.Fatal("incorrect conflict error")
+	}
+	if !reflect.DeepEqual(conflict, &BiMapConflict[int, string]{
+		Primary:              1,
+		Secondary:            "b",
+		ExistingSecondary:    "a",
+		HasExistingSecondary: true,
+		ExistingPrimary:      2,
+		HasExistingPrimary:   true,
+	}) {
+		t.Fatal("incorrect conflict contents")
+	}
+	if err.Error() != "can not map 1 to b: 1 is already mapped to a and b is already mapped to 2" {
+		t.Fatalf("incorrect error message: %s", err)
+	}
+	if bm.Primary[1] != "a" || bm.Primary[2] != "b" {
+		t.Fatal("conflicting Set changed the BiMap")
+	}
+
+	err = bm.Set(3, "a")
+	if err.Error() != "can not map 3 to a: a is already mapped to 1" {
+		t.Fatalf("incorrect error message: %s", err)
+	}
+	err = bm.Set(1, "c")
+	if err.Error() != "can not map 1 to c: 1 is already mapped to a" {
+		t.Fatalf("incorrect error message: %s", err)
+	}
+
+	bm.OnConflict = OnConflictPanic
+	panics(t, "conflict didn't panic", func() { bm.Set(1, "b") })
+	if bm.Len() != 2 {
+		t.Fatal("panicking Set changed the BiMap")
+	}
+
+	bm.OnConflict = OnConflictOverwrite
+	if bm.Set(1, "b") != nil {
+		t.Fatal("overwriting returned an error")
+	}
+	if !reflect.DeepEqual(bm.Primary, map[int]string{1: "b"}) ||
+		!reflect.DeepEqual(bm.Reverse, map[string]int{"b": 1}) {
+		t.Fatal("overwrite didn't evict both mappings")
+	}
+}
+
+func TestBiMapInverse(t *testing.T) {
+	var bm BiMap[int, string]
+	bm.OnConflict = OnConflictOverwrite
+
+	inv := bm.Inverse()
+	if inv.OnConflict != OnConflictOverwrite {
+		t.Fatal("Inverse didn't copy the policy")
+	}
+
+	inv.Set("a", 1)
+	if s, _ := bm.GetByPrimary(1); s != "a" {
+		t.Fatal("Inverse of a zero BiMap isn't a view")
+	}
+	bm.Set(2, "b")
+	if p, _ := inv.GetByPrimary("b"); p != 2 {
+		t.Fatal("Inverse isn't a view")
+	}
+	inv.DeleteByPrimary("a")
+	if _, exists := bm.GetByPrimary(1); exists {
+		t.Fatal("deleting through the Inverse didn't work")
+	}
+	if !inv.Inverse().Equal(&bm) {
+		t.Fatal("double Inverse isn't equal")
+	}
+}
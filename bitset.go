@@ -0,0 +1,264 @@
+package cm
+
+import (
+	"iter"
+	"math/bits"
+
+	"golang.org/x/exp/constraints"
+)
+
+// A BitSet is a set of non-negative integers stored as a bitmap, one bit
+// per possible value up to the largest value in the set. For dense sets
+// of small integers, such as IDs allocated sequentially, this is vastly
+// more compact than a Set, and the set operations work on 64 values at a
+// time.
+//
+// The memory used is proportional to the largest value in the set, not
+// the number of values, so for sparse sets or large values use a
+// RoaringSet instead.
+//
+// BitSet offers the same methods as Set. Adding a negative value, or one
+// greater than BitSetMaxValue, panics; such values are never contained in
+// the set.
+//
+// The zero-value of this struct is an empty set, ready to use.
+type BitSet[I constraints.Integer] struct {
+	words []uint64
+}
+
+// BitSetMaxValue is the largest value a BitSet can hold. A BitSet holding
+// it uses 512MiB; beyond it, use a RoaringSet.
+const BitSetMaxValue = 1<<32 - 1
+
+// BitSetFromSlice loads a BitSet in from a slice.
+func BitSetFromSlice[I constraints.Integer](l []I) *BitSet[I] {
+	bs := &BitSet[I]{}
+	for _, val := range l {
+		bs.Add(val)
+	}
+	return bs
+}
+
+// BitSetFromIter loads a BitSet in from an iterator.
+func BitSetFromIter[I constraints.Integer](i iter.Seq[I]) *BitSet[I] {
+	bs := &BitSet[I]{}
+	for val := range i {
+		bs.Add(val)
+	}
+	return bs
+}
+
+// BitSetFromSet loads a BitSet in from a Set.
+func BitSetFromSet[I constraints.Integer](s Set[I]) *BitSet[I] {
+	bs := &BitSet[I]{}
+	for val := range s {
+		bs.Add(val)
+	}
+	return bs
+}
+
+// bitPos returns the word index and bit mask of the value, which must be
+// between 0 and BitSetMaxValue, so that the index fits in an int even on
+// 32-bit platforms.
+func bitPos[I constraints.Integer](v I) (int, uint64) {
+	return int(uint64(v) / 64), 1 << (uint64(v) % 64)
+}
+
+// Add will add the given value in to the set.
+func (bs *BitSet[I]) Add(v I) {
+	if v < 0 {
+		panic("negative value added to BitSet")
+	}
+	if uint64(v) > BitSetMaxValue {
+		panic("value greater than BitSetMaxValue added to BitSet")
+	}
+	word, mask := bitPos(v)
+	if word >= len(bs.words) {
+		bs.grow(word + 1)
+	}
+	bs.words[word] |= mask
+}
+
+// grow extends the set to hold at least n words.
+func (bs *BitSet[I]) grow(n int) {
+	if n > cap(bs.words) {
+		words := make([]uint64, n, max(n, 2*cap(bs.words)))
+		copy(words, bs.words)
+		bs.words = words
+		return
+	}
+	bs.words = bs.words[:n]
+}
+
+// Remove will remove the given value from the set if it exists.
+func (bs *BitSet[I]) Remove(v I) {
+	if !bs.Contains(v) {
+		return
+	}
+	word, mask := bitPos(v)
+	bs.words[word] &^= mask
+}
+
+// Contains returns true if the set contains the given value.
+func (bs *BitSet[I]) Contains(v I) bool {
+	if v < 0 || uint64(v) > BitSetMaxValue {
+		return false
+	}
+	word, mask := bitPos(v)
+	return word < len(bs.words) && bs.words[word]&mask != 0
+}
+
+// Len returns the number of values in the set.
+func (bs *BitSet[I]) Len() int {
+	count := 0
+	for _, w := range bs.words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// Clone returns a copy of the set.
+func (bs *BitSet[I]) Clone() *BitSet[I] {
+	return &BitSet[I]{bs.trimmed()}
+}
+
+// trimmed returns a copy of the words without any trailing zero words.
+func (bs *BitSet[I]) trimmed() []uint64 {
+	n := len(bs.words)
+	for n > 0 && bs.words[n-1] == 0 {
+		n--
+	}
+	if n == 0 {
+		return nil
+	}
+	words := make([]uint64, n)
+	copy(words, bs.words)
+	return words
+}
+
+// Equal returns if two sets are equal.
+func (bs *BitSet[I]) Equal(r *BitSet[I]) bool {
+	l, s := bs.words, r.words
+	if len(l) < len(s) {
+		l, s = s, l
+	}
+	for i, w := range s {
+		if l[i] != w {
+			return false
+		}
+	}
+	return allZero(l[len(s):])
+}
+
+// SubsetOf returns true if the set this is called on is a subset of the
+// passed-in set.
+func (bs *BitSet[I]) SubsetOf(r *BitSet[I]) bool {
+	for i, w := range bs.words {
+		if i >= len(r.words) {
+			return allZero(bs.words[i:])
+		}
+		if w&^r.words[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SupersetOf returns true if the set this is called on is a superset of
+// the passed-in set.
+func (bs *BitSet[I]) SupersetOf(r *BitSet[I]) bool {
+	return r.SubsetOf(bs)
+}
+
+// Union will add all elements of the passed-in set to this set. This set
+// is then returned, allowing chaining.
+func (bs *BitSet[I]) Union(r *BitSet[I]) *BitSet[I] {
+	if len(r.words) > len(bs.words) {
+		bs.grow(len(r.words))
+	}
+	for i, w := range r.words {
+		bs.words[i] |= w
+	}
+	return bs
+}
+
+// Subtract removes all elements from this set that are in the passed-in
+// set.
+//
+// The set this is called on is returned, allowing for chaining.
+func (bs *BitSet[I]) Subtract(r *BitSet[I]) *BitSet[I] {
+	for i := range min(len(bs.words), len(r.words)) {
+		bs.words[i] &^= r.words[i]
+	}
+	return bs
+}
+
+// Intersect returns a new set containing only the elements that exist
+// in both sets.
+func (bs *BitSet[I]) Intersect(r *BitSet[I]) *BitSet[I] {
+	result := &BitSet[I]{make([]uint64, min(len(bs.words), len(r.words)))}
+	for i := range result.words {
+		result.words[i] = bs.words[i] & r.words[i]
+	}
+	result.words = result.trimmed()
+	return result
+}
+
+// XOR returns a new set containing the values in either the set this
+// method is called on, or the set passed in, but not both.
+func (bs *BitSet[I]) XOR(r *BitSet[I]) *BitSet[I] {
+	l, s := bs.words, r.words
+	if len(l) < len(s) {
+		l, s = s, l
+	}
+	result := &BitSet[I]{make([]uint64, len(l))}
+	copy(result.words, l)
+	for i, w := range s {
+		result.words[i] ^= w
+	}
+	result.words = result.trimmed()
+	return result
+}
+
+// All returns an iterator over the values in the set, in ascending
+// order.
+func (bs *BitSet[I]) All() iter.Seq[I] {
+	return func(yield func(I) bool) {
+		for i, w := range bs.words {
+			for w != 0 {
+				bit := bits.TrailingZeros64(w)
+				if !yield(I(i*64 + bit)) {
+					return
+				}
+				w &= w - 1
+			}
+		}
+	}
+}
+
+// AsSlice returns the values in the set as a slice, in ascending order.
+func (bs *BitSet[I]) AsSlice() []I {
+	vals := make([]I, 0, bs.Len())
+	for val := range bs.All() {
+		vals = append(vals, val)
+	}
+	return vals
+}
+
+// AsSet returns the values in the set as a Set.
+func (bs *BitSet[I]) AsSet() Set[I] {
+	s := make(Set[I], bs.Len())
+	for val := range bs.All() {
+		s[val] = void
+	}
+	return s
+}
+
+func allZero(words []uint64) bool {
+	for _, w := range words {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
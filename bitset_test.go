@@ -0,0 +1,118 @@
+package cm
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestBitSet(t *testing.T) {
+	var bs BitSet[int]
+	bs.Add(3)
+	bs.Add(64)
+	bs.Add(1000)
+	bs.Add(3)
+	bs.Remove(1000)
+	bs.Remove(5000)
+	bs.Remove(-1)
+
+	if bs.Len() != 2 || !bs.Contains(64) || bs.Contains(1000) ||
+		bs.Contains(5000) || bs.Contains(-1) {
+		t.Fatal("incorrect contents")
+	}
+	if !slices.Equal(bs.AsSlice(), []int{3, 64}) {
+		t.Fatal("incorrect AsSlice")
+	}
+	for range bs.All() {
+		break
+	}
+
+	clone := bs.Clone()
+	if !clone.Equal(&bs) || !bs.Equal(clone) || len(clone.words) != 2 {
+		t.Fatal("incorrect Clone")
+	}
+	clone.Remove(3)
+	if clone.Equal(&bs) {
+		t.Fatal("clone isn't independent")
+	}
+	if (&BitSet[int]{}).Clone().words != nil {
+		t.Fatal("clone of an empty set isn't empty")
+	}
+
+	s := bs.AsSet()
+	if !s.Equal(SetFromSlice([]int{3, 64})) {
+		t.Fatal("incorrect AsSet")
+	}
+	if !BitSetFromSet(s).Equal(&bs) ||
+		!BitSetFromSlice([]int{64, 3}).Equal(&bs) ||
+		!BitSetFromIter(bs.All()).Equal(&bs) {
+		t.Fatal("incorrect construction")
+	}
+
+	panics(t, "negative value added to BitSet", func() { bs.Add(-1) })
+
+	var big BitSet[int64]
+	panics(t, "value greater than BitSetMaxValue added to BitSet", func() {
+		big.Add(math.MaxInt64)
+	})
+	panics(t, "value greater than BitSetMaxValue added to BitSet", func() {
+		big.Add(BitSetMaxValue + 1)
+	})
+	if big.Contains(math.MaxInt64) || big.Contains(BitSetMaxValue+64) {
+		t.Fatal("BitSet contains a value beyond BitSetMaxValue")
+	}
+	big.Remove(math.MaxInt64)
+}
+
+func TestBitSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomSet := func() Set[uint16] {
+		s := Set[uint16]{}
+		limit := rng.Intn(1000) + 1
+		for range rng.Intn(200) {
+			s.Add(uint16(rng.Intn(limit)))
+		}
+		return s
+	}
+
+	for range 200 {
+		ls, rs := randomSet(), randomSet()
+		l, r := BitSetFromSet(ls), BitSetFromSet(rs)
+
+		if !l.Clone().Union(r).AsSet().Equal(ls.Clone().Union(rs)) {
+			t.Fatal("incorrect Union")
+		}
+		if !l.Clone().Subtract(r).AsSet().Equal(ls.Clone().Subtract(rs)) {
+			t.Fatal("incorrect Subtract")
+		}
+		if !l.Intersect(r).AsSet().Equal(ls.Intersect(rs)) {
+			t.Fatal("incorrect Intersect")
+		}
+		if !l.XOR(r).AsSet().Equal(ls.XOR(rs)) {
+			t.Fatal("incorrect XOR")
+		}
+		if l.SubsetOf(r) != ls.SubsetOf(rs) || l.SupersetOf(r) != ls.SupersetOf(rs) {
+			t.Fatal("incorrect SubsetOf")
+		}
+		if l.Equal(r) != ls.Equal(rs) {
+			t.Fatal("incorrect Equal")
+		}
+		if l.Len() != len(ls) {
+			t.Fatal("incorrect Len")
+		}
+
+		// A subset in a shorter bitmap, and the same set with trailing
+		// empty words.
+		sub := l.Intersect(r)
+		if !sub.SubsetOf(l) || !sub.SubsetOf(r) {
+			t.Fatal("intersection isn't a subset")
+		}
+		padded := l.Clone()
+		padded.Add(5000)
+		padded.Remove(5000)
+		if !padded.Equal(l) || !l.Equal(padded) || !padded.SubsetOf(l) {
+			t.Fatal("trailing zero words are significant")
+		}
+	}
+}
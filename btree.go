@@ -0,0 +1,388 @@
+package cm
+
+import "slices"
+
+// This file implements the B-tree underlying the Sorted types.
+//
+// It is a conventional B-tree storing the items in every node, not just
+// the leaves. Every node other than the root holds between
+// btreeDegree-1 and 2*btreeDegree-1 items; inserts split full nodes on
+// the way down and deletes top up minimal nodes on the way down, so
+// neither ever has to walk back up the tree.
+
+const (
+	btreeDegree   = 16
+	btreeMaxItems = 2*btreeDegree - 1
+	btreeMinItems = btreeDegree - 1
+)
+
+type btreeItem[K, V any] struct {
+	key K
+	val V
+}
+
+type btreeNode[K, V any] struct {
+	items []btreeItem[K, V]
+	// children is nil for leaves, otherwise it has one more element
+	// than items.
+	children []*btreeNode[K, V]
+}
+
+type btree[K, V any] struct {
+	cmp  func(K, K) int
+	root *btreeNode[K, V]
+	size int
+}
+
+func newBtree[K, V any](cmp func(K, K) int) *btree[K, V] {
+	return &btree[K, V]{cmp: cmp}
+}
+
+func (n *btreeNode[K, V]) leaf() bool {
+	return n.children == nil
+}
+
+// search returns the index of the first item in the node whose key is
+// not less than the given key, and whether that item's key is equal.
+func (t *btree[K, V]) search(n *btreeNode[K, V], key K) (int, bool) {
+	return slices.BinarySearchFunc(n.items, key,
+		func(item btreeItem[K, V], key K) int { return t.cmp(item.key, key) })
+}
+
+func (t *btree[K, V]) get(key K) (val V, exists bool) {
+	for n := t.root; n != nil; {
+		i, found := t.search(n, key)
+		if found {
+			return n.items[i].val, true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return val, false
+}
+
+// set sets the value for the key, returning true if the key was added
+// rather than replaced.
+func (t *btree[K, V]) set(key K, val V) bool {
+	if t.root == nil {
+		t.root = &btreeNode[K, V]{}
+	}
+	if len(t.root.items) == btreeMaxItems {
+		t.root = &btreeNode[K, V]{children: []*btreeNode[K, V]{t.root}}
+		t.root.split(0)
+	}
+
+	n := t.root
+	for {
+		i, found := t.search(n, key)
+		if found {
+			n.items[i].val = val
+			return false
+		}
+		if n.leaf() {
+			n.items = slices.Insert(n.items, i, btreeItem[K, V]{key, val})
+			t.size++
+			return true
+		}
+		if len(n.children[i].items) == btreeMaxItems {
+			n.split(i)
+			switch c := t.cmp(key, n.items[i].key); {
+			case c == 0:
+				n.items[i].val = val
+				return false
+			case c > 0:
+				i++
+			}
+		}
+		n = n.children[i]
+	}
+}
+
+// split splits the full child i of n in two, moving its median item up
+// into n.
+func (n *btreeNode[K, V]) split(i int) {
+	child := n.children[i]
+	median := child.items[btreeDegree-1]
+
+	right := &btreeNode[K, V]{
+		items: slices.Clone(child.items[btreeDegree:]),
+	}
+	clear(child.items[btreeDegree-1:])
+	child.items = child.items[:btreeDegree-1]
+	if !child.leaf() {
+		right.children = slices.Clone(child.children[btreeDegree:])
+		clear(child.children[btreeDegree:])
+		child.children = child.children[:btreeDegree]
+	}
+
+	n.items = slices.Insert(n.items, i, median)
+	n.children = slices.Insert(n.children, i+1, right)
+}
+
+// delete deletes the key, returning true if it was present.
+func (t *btree[K, V]) delete(key K) bool {
+	if t.root == nil {
+		return false
+	}
+	deleted := t.deleteFrom(t.root, key)
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+	if deleted {
+		t.size--
+	}
+	return deleted
+}
+
+// deleteFrom deletes the key from the subtree rooted at n, which must
+// have more than the minimum number of items unless it is the root.
+func (t *btree[K, V]) deleteFrom(n *btreeNode[K, V], key K) bool {
+	for {
+		i, found := t.search(n, key)
+
+		if n.leaf() {
+			if found {
+				n.items = slices.Delete(n.items, i, i+1)
+			}
+			return found
+		}
+
+		if found {
+			left, right := n.children[i], n.children[i+1]
+			switch {
+			case len(left.items) > btreeMinItems:
+				pred := left.max()
+				n.items[i] = pred
+				n, key = left, pred.key
+			case len(right.items) > btreeMinItems:
+				succ := right.min()
+				n.items[i] = succ
+				n, key = right, succ.key
+			default:
+				n.merge(i)
+				n = left
+			}
+			continue
+		}
+
+		if len(n.children[i].items) == btreeMinItems {
+			i = n.fill(i)
+		}
+		n = n.children[i]
+	}
+}
+
+// fill gives the minimal child i of n an extra item, by borrowing one
+// from a sibling or by merging it with a sibling. It returns the index
+// of the child that now holds the keys child i held.
+func (n *btreeNode[K, V]) fill(i int) int {
+	child := n.children[i]
+
+	if i > 0 && len(n.children[i-1].items) > btreeMinItems {
+		left := n.children[i-1]
+		child.items = slices.Insert(child.items, 0, n.items[i-1])
+		n.items[i-1] = left.items[len(left.items)-1]
+		left.items = slices.Delete(left.items, len(left.items)-1, len(left.items))
+		if !left.leaf() {
+			last := left.children[len(left.children)-1]
+			left.children = slices.Delete(left.children,
+				len(left.children)-1, len(left.children))
+			child.children = slices.Insert(child.children, 0, last)
+		}
+		return i
+	}
+
+	if i < len(n.items) && len(n.children[i+1].items) > btreeMinItems {
+		right := n.children[i+1]
+		child.items = append(child.items, n.items[i])
+		n.items[i] = right.items[0]
+		right.items = slices.Delete(right.items, 0, 1)
+		if !right.leaf() {
+			child.children = append(child.children, right.children[0])
+			right.children = slices.Delete(right.children, 0, 1)
+		}
+		return i
+	}
+
+	if i == len(n.items) {
+		i--
+	}
+	n.merge(i)
+	return i
+}
+
+// merge merges child i+1 of n and the item between them into child i.
+func (n *btreeNode[K, V]) merge(i int) {
+	left, right := n.children[i], n.children[i+1]
+	left.items = append(left.items, n.items[i])
+	left.items = append(left.items, right.items...)
+	if !left.leaf() {
+		left.children = append(left.children, right.children...)
+	}
+	n.items = slices.Delete(n.items, i, i+1)
+	n.children = slices.Delete(n.children, i+1, i+2)
+}
+
+func (n *btreeNode[K, V]) min() btreeItem[K, V] {
+	for !n.leaf() {
+		n = n.children[0]
+	}
+	return n.items[0]
+}
+
+func (n *btreeNode[K, V]) max() btreeItem[K, V] {
+	for !n.leaf() {
+		n = n.children[len(n.children)-1]
+	}
+	return n.items[len(n.items)-1]
+}
+
+// first returns the item with the smallest key.
+func (t *btree[K, V]) first() (item btreeItem[K, V], exists bool) {
+	if t.root == nil {
+		return item, false
+	}
+	return t.root.min(), true
+}
+
+// last returns the item with the largest key.
+func (t *btree[K, V]) last() (item btreeItem[K, V], exists bool) {
+	if t.root == nil {
+		return item, false
+	}
+	return t.root.max(), true
+}
+
+// floor returns the item with the largest key less than or equal to the
+// given key.
+func (t *btree[K, V]) floor(key K) (item btreeItem[K, V], exists bool) {
+	for n := t.root; n != nil; {
+		i, found := t.search(n, key)
+		if found {
+			return n.items[i], true
+		}
+		if i > 0 {
+			item, exists = n.items[i-1], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return item, exists
+}
+
+// ceiling returns the item with the smallest key greater than or equal
+// to the given key.
+func (t *btree[K, V]) ceiling(key K) (item btreeItem[K, V], exists bool) {
+	for n := t.root; n != nil; {
+		i, found := t.search(n, key)
+		if found {
+			return n.items[i], true
+		}
+		if i < len(n.items) {
+			item, exists = n.items[i], true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	return item, exists
+}
+
+// all calls yield on all the items in ascending order, until it returns
+// false. As a method value, it is an iter.Seq2.
+func (t *btree[K, V]) all(yield func(K, V) bool) {
+	t.ascend(nil, nil, yield)
+}
+
+// ascend calls yield on the items with keys in [lo, hi) in ascending
+// order, until it returns false. A nil bound is unbounded.
+func (t *btree[K, V]) ascend(lo, hi *K, yield func(K, V) bool) {
+	if t.root != nil {
+		t.ascendFrom(t.root, lo, hi, yield)
+	}
+}
+
+func (t *btree[K, V]) ascendFrom(
+	n *btreeNode[K, V],
+	lo, hi *K,
+	yield func(K, V) bool,
+) bool {
+	start := 0
+	if lo != nil {
+		start, _ = t.search(n, *lo)
+	}
+	for i := start; i <= len(n.items); i++ {
+		if !n.leaf() && !t.ascendFrom(n.children[i], lo, hi, yield) {
+			return false
+		}
+		if i == len(n.items) {
+			break
+		}
+		item := n.items[i]
+		if hi != nil && t.cmp(item.key, *hi) >= 0 {
+			return false
+		}
+		if !yield(item.key, item.val) {
+			return false
+		}
+	}
+	return true
+}
+
+// descend calls yield on all the items in descending order, until it
+// returns false.
+func (t *btree[K, V]) descend(yield func(K, V) bool) {
+	if t.root != nil {
+		t.root.descend(yield)
+	}
+}
+
+func (n *btreeNode[K, V]) descend(yield func(K, V) bool) bool {
+	for i := len(n.items); i >= 0; i-- {
+		if !n.leaf() && !n.children[i].descend(yield) {
+			return false
+		}
+		if i == 0 {
+			break
+		}
+		if !yield(n.items[i-1].key, n.items[i-1].val) {
+			return false
+		}
+	}
+	return true
+}
+
+// clone returns a copy of the tree, using the passed-in function to copy
+// the values.
+func (t *btree[K, V]) clone(cloneVal func(V) V) *btree[K, V] {
+	r := &btree[K, V]{cmp: t.cmp, size: t.size}
+	if t.root != nil {
+		r.root = t.root.clone(cloneVal)
+	}
+	return r
+}
+
+func (n *btreeNode[K, V]) clone(cloneVal func(V) V) *btreeNode[K, V] {
+	r := &btreeNode[K, V]{items: slices.Clone(n.items)}
+	if cloneVal != nil {
+		for i := range r.items {
+			r.items[i].val = cloneVal(r.items[i].val)
+		}
+	}
+	if !n.leaf() {
+		r.children = make([]*btreeNode[K, V], len(n.children))
+		for i, child := range n.children {
+			r.children[i] = child.clone(cloneVal)
+		}
+	}
+	return r
+}
@@ -0,0 +1,245 @@
+package cm
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// check verifies the B-tree invariants, returning the number of items.
+func (t *btree[K, V]) check(tb testing.TB) int {
+	tb.Helper()
+	if t.root == nil {
+		return 0
+	}
+	count, _ := t.checkNode(tb, t.root, true)
+	return count
+}
+
+func (t *btree[K, V]) checkNode(tb testing.TB, n *btreeNode[K, V], root bool) (int, int) {
+	tb.Helper()
+	if len(n.items) > btreeMaxItems || (!root && len(n.items) < btreeMinItems) {
+		tb.Fatalf("node has %d items", len(n.items))
+	}
+	if !slices.IsSortedFunc(n.items, func(a, b btreeItem[K, V]) int {
+		return t.cmp(a.key, b.key)
+	}) {
+		tb.Fatal("node isn't sorted")
+	}
+	count := len(n.items)
+	if n.leaf() {
+		return count, 1
+	}
+	if len(n.children) != len(n.items)+1 {
+		tb.Fatal("node has the wrong number of children")
+	}
+	depth := -1
+	for i, child := range n.children {
+		if i > 0 && t.cmp(child.min().key, n.items[i-1].key) <= 0 {
+			tb.Fatal("child is out of order")
+		}
+		if i < len(n.items) && t.cmp(child.max().key, n.items[i].key) >= 0 {
+			tb.Fatal("child is out of order")
+		}
+		childCount, childDepth := t.checkNode(tb, child, false)
+		if depth != -1 && childDepth != depth {
+			tb.Fatal("tree is unbalanced")
+		}
+		depth = childDepth
+		count += childCount
+	}
+	return count, depth + 1
+}
+
+func TestBtreeRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tree := newBtree[int, int](cmp.Compare[int])
+	model := map[int]int{}
+
+	for i := range 20000 {
+		key := rng.Intn(3000)
+		// bias towards inserting early and deleting late, to grow the
+		// tree several levels deep and then shrink it again.
+		if rng.Intn(20000) < i {
+			if tree.delete(key) != (model[key] != 0) {
+				t.Fatal("delete returned the wrong result")
+			}
+			delete(model, key)
+		} else {
+			if tree.set(key, i+1) != (model[key] == 0) {
+				t.Fatal("set returned the wrong result")
+			}
+			model[key] = i + 1
+		}
+
+		if i%1000 == 0 {
+			if tree.check(t) != len(model) || tree.size != len(model) {
+				t.Fatal("incorrect size")
+			}
+		}
+	}
+
+	keys := make([]int, 0, len(model))
+	for key := range model {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var ascended []int
+	for key, val := range tree.all {
+		if model[key] != val {
+			t.Fatal("incorrect value")
+		}
+		ascended = append(ascended, key)
+	}
+	if !slices.Equal(ascended, keys) {
+		t.Fatal("incorrect ascend")
+	}
+
+	var descended []int
+	tree.descend(func(key, _ int) bool {
+		descended = append(descended, key)
+		return true
+	})
+	slices.Reverse(descended)
+	if !slices.Equal(descended, keys) {
+		t.Fatal("incorrect descend")
+	}
+
+	for key := -1; key <= 3001; key++ {
+		val, exists := tree.get(key)
+		if val != model[key] || exists != (model[key] != 0) {
+			t.Fatal("incorrect get")
+		}
+
+		idx, found := slices.BinarySearch(keys, key)
+		item, exists := tree.floor(key)
+		switch {
+		case found:
+			if !exists || item.key != key {
+				t.Fatal("incorrect floor of a present key")
+			}
+		case idx == 0:
+			if exists {
+				t.Fatal("floor below the minimum exists")
+			}
+		default:
+			if !exists || item.key != keys[idx-1] {
+				t.Fatal("incorrect floor")
+			}
+		}
+		item, exists = tree.ceiling(key)
+		switch {
+		case found:
+			if !exists || item.key != key {
+				t.Fatal("incorrect ceiling of a present key")
+			}
+		case idx == len(keys):
+			if exists {
+				t.Fatal("ceiling above the maximum exists")
+			}
+		default:
+			if !exists || item.key != keys[idx] {
+				t.Fatal("incorrect ceiling")
+			}
+		}
+	}
+
+	for range 100 {
+		lo, hi := rng.Intn(3000), rng.Intn(3000)
+		var ranged []int
+		tree.ascend(&lo, &hi, func(key, _ int) bool {
+			ranged = append(ranged, key)
+			return true
+		})
+		var expected []int
+		for _, key := range keys {
+			if key >= lo && key < hi {
+				expected = append(expected, key)
+			}
+		}
+		if !slices.Equal(ranged, expected) {
+			t.Fatalf("incorrect range [%d, %d)", lo, hi)
+		}
+	}
+
+	first, _ := tree.first()
+	last, _ := tree.last()
+	if first.key != keys[0] || last.key != keys[len(keys)-1] {
+		t.Fatal("incorrect first or last")
+	}
+
+	clone := tree.clone(func(v int) int { return -v })
+	clone.set(-5, 5)
+	if _, exists := tree.get(-5); exists {
+		t.Fatal("clone isn't independent")
+	}
+	if val, _ := clone.get(keys[0]); val != -model[keys[0]] {
+		t.Fatal("clone didn't copy the values")
+	}
+
+	for _, key := range keys {
+		tree.delete(key)
+	}
+	if tree.root != nil || tree.size != 0 || tree.delete(1) {
+		t.Fatal("deleting everything didn't empty the tree")
+	}
+}
+
+func TestBtreeEmpty(t *testing.T) {
+	tree := newBtree[int, int](cmp.Compare[int])
+
+	if _, exists := tree.get(1); exists {
+		t.Fatal("empty tree has a value")
+	}
+	if _, exists := tree.first(); exists {
+		t.Fatal("empty tree has a first")
+	}
+	if _, exists := tree.last(); exists {
+		t.Fatal("empty tree has a last")
+	}
+	if _, exists := tree.floor(1); exists {
+		t.Fatal("empty tree has a floor")
+	}
+	if _, exists := tree.ceiling(1); exists {
+		t.Fatal("empty tree has a ceiling")
+	}
+	tree.ascend(nil, nil, func(int, int) bool {
+		t.Fatal("empty tree ascended")
+		return false
+	})
+	tree.descend(func(int, int) bool {
+		t.Fatal("empty tree descended")
+		return false
+	})
+	if tree.clone(nil).root != nil {
+		t.Fatal("clone of an empty tree isn't empty")
+	}
+
+	// early termination at every level
+	for i := range 1000 {
+		tree.set(i, i)
+	}
+	for _, stop := range []int{0, 5, 500, 999} {
+		count := 0
+		tree.ascend(nil, nil, func(key, _ int) bool {
+			count++
+			return key != stop
+		})
+		if count != stop+1 {
+			t.Fatal("ascend didn't stop")
+		}
+		count = 0
+		tree.descend(func(key, _ int) bool {
+			count++
+			return key != stop
+		})
+		if count != 1000-stop {
+			t.Fatal("descend didn't stop")
+		}
+	}
+	if tree.clone(nil).check(t) != 1000 {
+		t.Fatal("incorrect clone")
+	}
+}
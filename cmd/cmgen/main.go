@@ -0,0 +1,565 @@
+/*
+Command cmgen generates fixed-depth multi-level maps of depth four and
+beyond, following the same API and behavior as the MapMap and MapMapMap
+types in the cm package.
+
+Go generics can not be variadic, so there is no way to write a single
+generic type that covers every depth. PathMap covers the case where all
+the keys are the same type, but when you need a map[A]map[B]map[C]map[D]E
+with distinct key types, this generator will produce a MapMapMapMap and
+MapMapMapMapAny, the corresponding Tuple4, and so on up to the requested
+depth. Every depth between four and the requested depth is generated, as
+each level is built on the one beneath it; depth three is taken from the
+cm package itself.
+
+Usage:
+
+	cmgen -max 5 -package perms -o mapmaps_gen.go
+
+or, in a go:generate line:
+
+	//go:generate go run github.com/thejerf/cm/cmd/cmgen -max 5 -package perms -o mapmaps_gen.go
+
+If the package is anything other than "cm", the generated code will
+import github.com/thejerf/cm for the lower levels and KeyTree.
+*/
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const cmImport = "github.com/thejerf/cm"
+
+func main() {
+	maxDepth := flag.Int("max", 4, "the maximum depth of map to generate")
+	pkg := flag.String("package", "cm", "the package name of the generated code")
+	out := flag.String("o", "", "the file to write to; defaults to stdout")
+	flag.Parse()
+
+	src, err := generate(*maxDepth, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = write(*out, src)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// write writes the source to the named file, or to stdout if the name is
+// empty. The file is closed before returning, so that any error flushing
+// it is reported.
+func write(out string, src []byte) error {
+	if out == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(src)
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// generate returns the formatted source for all depths from 4 through
+// maxDepth.
+func generate(maxDepth int, pkg string) ([]byte, error) {
+	if maxDepth < 4 {
+		return nil, fmt.Errorf(
+			"depth must be at least 4; depths 2 and 3 are in the cm package")
+	}
+
+	qualifier := ""
+	if pkg != "cm" {
+		qualifier = "cm."
+	}
+
+	levels := []level{}
+	for depth := 4; depth <= maxDepth; depth++ {
+		levels = append(levels, newLevel(depth, qualifier))
+	}
+
+	var buf bytes.Buffer
+	err := fileTemplate.Execute(&buf, file{
+		Package:   pkg,
+		Import:    qualifier != "",
+		CMImport:  cmImport,
+		Levels:    levels,
+		Qualifier: qualifier,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+type file struct {
+	Package   string
+	Import    bool
+	CMImport  string
+	Levels    []level
+	Qualifier string
+}
+
+// level contains all the precomputed strings the template needs for a
+// given depth.
+type level struct {
+	Depth int
+
+	Name    string // MapMapMapMap
+	AnyName string // MapMapMapMapAny
+	Recv    string // mmmm
+	AnyRecv string // mmmma
+	Tuple   string // Tuple4
+
+	SubName    string // MapMapMap, qualified as necessary
+	SubAnyName string // MapMapMapAny, qualified as necessary
+
+	TypeParams string // K1, K2, K3, K4
+	SubParams  string // K2, K3, K4
+	KeyArgs    string // key1 K1, key2 K2, key3 K3, key4 K4
+	Keys       string // key1, key2, key3, key4
+	SubKeys    string // key2, key3, key4
+	SubKeyArgs string // key2 K2, key3 K3, key4 K4
+	TupleKeys  string // key.Key1, key.Key2, key.Key3, key.Key4
+	KeyIndex   string // [key.Key1][key.Key2][key.Key3][key.Key4]
+	KeyTree    string // KeyTree[K1, KeyTree[K2, KeyTree[K3, K4]]]
+
+	// SubTupleKeys is the tuple fields of a sub-tuple named k, which
+	// combined with key1 makes up the full tuple.
+	SubTupleKeys string // k.Key1, k.Key2, k.Key3
+
+	// TupleFields is the field list for the tuple struct.
+	TupleFields []string
+}
+
+func newLevel(depth int, qualifier string) level {
+	l := level{
+		Depth:   depth,
+		Name:    strings.Repeat("Map", depth),
+		AnyName: strings.Repeat("Map", depth) + "Any",
+		Recv:    strings.Repeat("m", depth),
+		AnyRecv: strings.Repeat("m", depth) + "a",
+		Tuple:   fmt.Sprintf("Tuple%d", depth),
+	}
+
+	sub := ""
+	if depth-1 <= 3 {
+		sub = qualifier
+	}
+	l.SubName = sub + strings.Repeat("Map", depth-1)
+	l.SubAnyName = sub + strings.Repeat("Map", depth-1) + "Any"
+
+	typeParams := []string{}
+	keyArgs := []string{}
+	keys := []string{}
+	tupleKeys := []string{}
+	subTupleKeys := []string{}
+	keyIndex := ""
+	for i := 1; i <= depth; i++ {
+		typeParams = append(typeParams, fmt.Sprintf("K%d", i))
+		keyArgs = append(keyArgs, fmt.Sprintf("key%d K%d", i, i))
+		keys = append(keys, fmt.Sprintf("key%d", i))
+		tupleKeys = append(tupleKeys, fmt.Sprintf("key.Key%d", i))
+		keyIndex += fmt.Sprintf("[key.Key%d]", i)
+		l.TupleFields = append(l.TupleFields, fmt.Sprintf("Key%d K%d", i, i))
+		if i < depth {
+			subTupleKeys = append(subTupleKeys, fmt.Sprintf("k.Key%d", i))
+		}
+	}
+	l.TypeParams = strings.Join(typeParams, ", ")
+	l.SubParams = strings.Join(typeParams[1:], ", ")
+	l.KeyArgs = strings.Join(keyArgs, ", ")
+	l.SubKeyArgs = strings.Join(keyArgs[1:], ", ")
+	l.Keys = strings.Join(keys, ", ")
+	l.SubKeys = strings.Join(keys[1:], ", ")
+	l.TupleKeys = strings.Join(tupleKeys, ", ")
+	l.SubTupleKeys = strings.Join(subTupleKeys, ", ")
+	l.KeyIndex = keyIndex
+
+	keyTree := typeParams[depth-1]
+	for i := depth - 2; i >= 0; i-- {
+		keyTree = fmt.Sprintf("%sKeyTree[%s, %s]", qualifier, typeParams[i], keyTree)
+	}
+	l.KeyTree = keyTree
+
+	return l
+}
+
+var fileTemplate = template.Must(template.New("file").Parse(`// Code generated by cmgen; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"iter"
+{{- if .Import}}
+
+	"{{.CMImport}}"
+{{- end}}
+)
+{{range .Levels}}
+// {{.Name}} is a {{.Depth}}-level map that has a comparable Value type,
+// which allows for the .Equal method.
+//
+// {{.AnyName}} lacks this restriction, and has all the methods {{.Name}}
+// has except .Equal.
+type {{.Name}}[{{.TypeParams}}, V comparable] {{.AnyName}}[{{.TypeParams}}, V]
+
+// Equal returns if this {{.Name}} is equal to the passed-in {{.Name}}.
+//
+// Two zero-sized maps are considered equal to each other, even if one is
+// nil and the other is not.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Equal(r {{.Name}}[{{.TypeParams}}, V]) bool {
+	if {{.Recv}} == nil && r == nil {
+		return true
+	}
+	if len({{.Recv}}) != len(r) {
+		return false
+	}
+	if len({{.Recv}}) == 0 {
+		return true
+	}
+
+	for key, submap := range {{.Recv}} {
+		rightSubmap, exists := r[key]
+		if !exists {
+			return false
+		}
+		if !{{.SubName}}[{{.SubParams}}, V](submap).Equal(
+			{{.SubName}}[{{.SubParams}}, V](rightSubmap)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Clone yields a shallow copy of the {{.Name}}, with the values simply
+// copied across.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Clone() {{.Name}}[{{.TypeParams}}, V] {
+	return {{.Name}}[{{.TypeParams}}, V]({{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).Clone())
+}
+
+// Set will set the given value with the given keys.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Set({{.KeyArgs}}, value V) {
+	{{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).Set({{.Keys}}, value)
+}
+
+// SetByTuple sets by the key tuple.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) SetByTuple(key {{.Tuple}}[{{.TypeParams}}], value V) {
+	{{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).SetByTuple(key, value)
+}
+
+// Delete deletes the value from the map.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Delete({{.KeyArgs}}) {
+	{{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).Delete({{.Keys}})
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) DeleteByTuple(key {{.Tuple}}[{{.TypeParams}}]) {
+	{{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).DeleteByTuple(key)
+}
+
+// GetByTuple retrieves by the given tuple. The second value is true if
+// the key exists, false otherwise.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) GetByTuple(key {{.Tuple}}[{{.TypeParams}}]) (val V, exists bool) {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).GetByTuple(key)
+}
+
+// EqualFunc reimplements maps.EqualFunc on the {{.Name}}.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) EqualFunc(
+	r {{.Name}}[{{.TypeParams}}, V],
+	eq func(v1, v2 V) bool,
+) bool {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).EqualFunc(
+		{{.AnyName}}[{{.TypeParams}}, V](r),
+		eq,
+	)
+}
+
+// DeleteFunc deletes from the map the values for which the function
+// returns true. If all values from a submap are deleted, the submap will
+// be deleted from the {{.Name}}.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) DeleteFunc(f func({{.TypeParams}}, V) bool) {
+	{{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).DeleteFunc(f)
+}
+
+// Keys returns the keys of the {{.Name}} as an iterator of {{.Tuple}}s.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Keys() iter.Seq[{{.Tuple}}[{{.TypeParams}}]] {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).Keys()
+}
+
+// KeySlice returns the keys of the {{.Name}} as a slice of {{.Tuple}}
+// values.
+//
+// A nil map will return a nil slice.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) KeySlice() []{{.Tuple}}[{{.TypeParams}}] {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).KeySlice()
+}
+
+// All returns an iterator over the map yielding the keys as a
+// {{.Tuple}}, and the value in the value slot.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) All() iter.Seq2[{{.Tuple}}[{{.TypeParams}}], V] {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).All()
+}
+
+// KeyTree returns the keys of the {{.Name}} as a {{.Depth}}-level tree of
+// the various keys.
+//
+// A nil map will return a nil slice.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) KeyTree() []{{.KeyTree}} {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).KeyTree()
+}
+
+// Values returns an iterator for the values in nondeterministic order.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Values() iter.Seq[V] {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).Values()
+}
+
+// ValueSlice returns a slice containing all the values for this
+// {{.Name}} in a nondeterministic order.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) ValueSlice() []V {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).ValueSlice()
+}
+
+// Len returns the number of values in the {{.Name}}.
+func ({{.Recv}} {{.Name}}[{{.TypeParams}}, V]) Len() int {
+	return {{.AnyName}}[{{.TypeParams}}, V]({{.Recv}}).Len()
+}
+
+// {{.AnyName}} is a {{.Depth}}-level map that can contain any value.
+type {{.AnyName}}[{{.TypeParams}} comparable, V any] map[K1]{{.SubAnyName}}[{{.SubParams}}, V]
+
+// {{.Tuple}} is a {{.Depth}}-element tuple struct with a slot for each of
+// the keys.
+type {{.Tuple}}[{{.TypeParams}} comparable] struct {
+{{- range .TupleFields}}
+	{{.}}
+{{- end}}
+}
+
+// Set will set the given value with the given keys.
+//
+// This will panic if called on a nil map.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) Set(
+	{{.KeyArgs}},
+	value V,
+) {
+	if {{.AnyRecv}} == nil {
+		panic("Set called on a nil {{.Name}}")
+	}
+	l1 := {{.AnyRecv}}[key1]
+	if l1 == nil {
+		l1 = {{.SubAnyName}}[{{.SubParams}}, V]{}
+		{{.AnyRecv}}[key1] = l1
+	}
+	l1.Set({{.SubKeys}}, value)
+}
+
+// SetByTuple sets by the key tuple.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) SetByTuple(
+	key {{.Tuple}}[{{.TypeParams}}],
+	value V,
+) {
+	{{.AnyRecv}}.Set({{.TupleKeys}}, value)
+}
+
+// Delete deletes the value from the map.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) Delete(
+	{{.KeyArgs}},
+) {
+	l1 := {{.AnyRecv}}[key1]
+	if l1 == nil {
+		return
+	}
+	l1.Delete({{.SubKeys}})
+	if len(l1) == 0 {
+		delete({{.AnyRecv}}, key1)
+	}
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) DeleteByTuple(key {{.Tuple}}[{{.TypeParams}}]) {
+	{{.AnyRecv}}.Delete({{.TupleKeys}})
+}
+
+// GetByTuple retrieves by the given tuple. The second value is true if
+// the key exists, false otherwise.
+//
+// GetByTuple called on a nil {{.Name}} will not panic, and return that
+// the value was not found.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) GetByTuple(key {{.Tuple}}[{{.TypeParams}}]) (val V, exists bool) {
+	val, exists = {{.AnyRecv}}{{.KeyIndex}}
+	return val, exists
+}
+
+// EqualFunc returns if this {{.Name}} is equal to the passed-in
+// {{.Name}}, using the passed-in function to compare the equality of
+// values.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) EqualFunc(
+	r {{.AnyName}}[{{.TypeParams}}, V],
+	eq func(v1, v2 V) bool,
+) bool {
+	if {{.AnyRecv}} == nil && r == nil {
+		return true
+	}
+	if len({{.AnyRecv}}) != len(r) {
+		return false
+	}
+	if len({{.AnyRecv}}) == 0 {
+		return true
+	}
+
+	for key, submap := range {{.AnyRecv}} {
+		rightSubmap, exists := r[key]
+		if !exists {
+			return false
+		}
+		if !submap.EqualFunc(rightSubmap, eq) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DeleteFunc deletes from the map the values for which the function
+// returns true. If all values from a submap are deleted, the submap will
+// be deleted from the {{.Name}}.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) DeleteFunc(f func({{.TypeParams}}, V) bool) {
+	if {{.AnyRecv}} == nil {
+		return
+	}
+
+	for key1, submap := range {{.AnyRecv}} {
+		delF := func({{.SubKeyArgs}}, val V) bool {
+			return f({{.Keys}}, val)
+		}
+		submap.DeleteFunc(delF)
+		if len(submap) == 0 {
+			delete({{.AnyRecv}}, key1)
+		}
+	}
+}
+
+// Clone yields a shallow copy of the {{.AnyName}}, with the values simply
+// copied across.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) Clone() {{.AnyName}}[{{.TypeParams}}, V] {
+	newMap := make({{.AnyName}}[{{.TypeParams}}, V], len({{.AnyRecv}}))
+
+	for key1, submap := range {{.AnyRecv}} {
+		newMap[key1] = submap.Clone()
+	}
+
+	return newMap
+}
+
+// Keys returns the keys of the {{.AnyName}} as an iterator of
+// {{.Tuple}}s.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) Keys() iter.Seq[{{.Tuple}}[{{.TypeParams}}]] {
+	return func(yield func({{.Tuple}}[{{.TypeParams}}]) bool) {
+		for key1, submap := range {{.AnyRecv}} {
+			for k := range submap.Keys() {
+				if !yield({{.Tuple}}[{{.TypeParams}}]{key1, {{.SubTupleKeys}}}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// KeySlice returns the keys of the {{.AnyName}} as a slice of {{.Tuple}}
+// values.
+//
+// A nil map will return a nil slice.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) KeySlice() []{{.Tuple}}[{{.TypeParams}}] {
+	if {{.AnyRecv}} == nil {
+		return nil
+	}
+
+	r := []{{.Tuple}}[{{.TypeParams}}]{}
+	for key := range {{.AnyRecv}}.Keys() {
+		r = append(r, key)
+	}
+	return r
+}
+
+// All returns an iterator over the map yielding the keys as a
+// {{.Tuple}}, and the value in the value slot.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) All() iter.Seq2[{{.Tuple}}[{{.TypeParams}}], V] {
+	return func(yield func({{.Tuple}}[{{.TypeParams}}], V) bool) {
+		for key1, submap := range {{.AnyRecv}} {
+			for k, val := range submap.All() {
+				if !yield({{.Tuple}}[{{.TypeParams}}]{key1, {{.SubTupleKeys}}}, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// KeyTree returns the keys of the {{.AnyName}} as a {{.Depth}}-level tree
+// of the various keys.
+//
+// A nil map will return a nil slice.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) KeyTree() []{{.KeyTree}} {
+	if {{.AnyRecv}} == nil {
+		return nil
+	}
+
+	r := make([]{{.KeyTree}}, 0, len({{.AnyRecv}}))
+	for key1, submap := range {{.AnyRecv}} {
+		r = append(r, {{.KeyTree}}{Key: key1, Vals: submap.KeyTree()})
+	}
+	return r
+}
+
+// Values returns an iterator for the values in nondeterministic order.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, submap := range {{.AnyRecv}} {
+			for val := range submap.Values() {
+				if !yield(val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ValueSlice returns a slice containing all the values for this
+// {{.AnyName}} in a nondeterministic order.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) ValueSlice() []V {
+	result := make([]V, 0, {{.AnyRecv}}.Len())
+	for val := range {{.AnyRecv}}.Values() {
+		result = append(result, val)
+	}
+	return result
+}
+
+// Len returns the number of values in the {{.AnyName}}.
+func ({{.AnyRecv}} {{.AnyName}}[{{.TypeParams}}, V]) Len() int {
+	l := 0
+	for _, submap := range {{.AnyRecv}} {
+		l += submap.Len()
+	}
+	return l
+}
+{{end}}`))
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, pkg := range []string{"cm", "perms"} {
+		src, err := generate(6, pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := parser.ParseFile(token.NewFileSet(), "gen.go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name.Name != pkg {
+			t.Fatal("incorrect package name")
+		}
+
+		code := string(src)
+		for _, name := range []string{
+			"MapMapMapMap[", "MapMapMapMapAny[", "Tuple4[",
+			"MapMapMapMapMapMap[", "MapMapMapMapMapMapAny[", "Tuple6[",
+		} {
+			if !strings.Contains(code, "type "+name) {
+				t.Fatalf("%s not generated", name)
+			}
+		}
+		if strings.Contains(code, "type MapMapMap[") ||
+			strings.Contains(code, "type Tuple3[") {
+			t.Fatal("generated types that the cm package has")
+		}
+
+		imported := strings.Contains(code, `"github.com/thejerf/cm"`)
+		if imported != (pkg != "cm") {
+			t.Fatal("incorrect import of cm")
+		}
+		if pkg != "cm" && !strings.Contains(code, "map[K1]cm.MapMapMapAny[K2, K3, K4, V]") {
+			t.Fatal("depth 4 doesn't build on the cm package's MapMapMapAny")
+		}
+		if !strings.Contains(code, "map[K1]MapMapMapMapAny[K2, K3, K4, K5, V]") {
+			t.Fatal("depth 5 doesn't build on depth 4")
+		}
+
+		buildGenerated(t, pkg, src)
+	}
+}
+
+// buildGenerated compiles the generated source with the go tool, in a
+// temporary module.
+func buildGenerated(t *testing.T, pkg string, src []byte) {
+	t.Helper()
+	runGo(t, generatedModule(t, pkg, src), "build", "./...")
+}
+
+// generatedModule writes the generated source into a temporary module
+// and returns its directory. For the cm package, the source is placed
+// along with a copy of the cm package; otherwise it is a package of its
+// own that imports this checkout of cm.
+func generatedModule(t *testing.T, pkg string, src []byte) string {
+	t.Helper()
+
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	copyFile := func(name string) {
+		t.Helper()
+		contents, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(dir, name), contents, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	copyFile("go.sum")
+	if pkg == "cm" {
+		copyFile("go.mod")
+		names, err := filepath.Glob(filepath.Join(root, "*.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if !strings.HasSuffix(name, "_test.go") {
+				copyFile(filepath.Base(name))
+			}
+		}
+	} else {
+		goMod := "module example.com/" + pkg + "\n\n" +
+			"go 1.24\n\n" +
+			"require " + cmImport + " v0.0.0\n\n" +
+			"replace " + cmImport + " => " + root + "\n"
+		err = os.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = os.WriteFile(filepath.Join(dir, "gen.go"), src, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// runGo runs the go tool with the given arguments in the given
+// directory, failing the test if it fails.
+func runGo(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not available to build the generated code")
+	}
+	cmd := exec.Command(goTool, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go %s of generated code failed: %v\n%s",
+			strings.Join(args, " "), err, out)
+	}
+}
+
+// generatedTest exercises the generated types, to check the behavior of
+// the templates and not just that they compile.
+const generatedTest = `package perms
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thejerf/cm"
+)
+
+func TestMapMapMapMap(t *testing.T) {
+	m := MapMapMapMap[int, string, int, string, int]{}
+	m.Set(1, "a", 2, "x", 10)
+	m.Set(1, "a", 2, "y", 11)
+	m.Set(1, "b", 3, "x", 12)
+	m.SetByTuple(Tuple4[int, string, int, string]{2, "a", 2, "x"}, 13)
+
+	if m.Len() != 4 {
+		t.Fatalf("incorrect Len: %d", m.Len())
+	}
+	if m[1]["a"][2]["y"] != 11 {
+		t.Fatal("Set didn't store the value at the keys")
+	}
+	val, exists := m.GetByTuple(Tuple4[int, string, int, string]{2, "a", 2, "x"})
+	if val != 13 || !exists {
+		t.Fatal("couldn't get a value set by tuple")
+	}
+	_, exists = m.GetByTuple(Tuple4[int, string, int, string]{1, "a", 2, "z"})
+	if exists {
+		t.Fatal("got a value that was never set")
+	}
+	var nilMap MapMapMapMap[int, string, int, string, int]
+	if _, exists := nilMap.GetByTuple(Tuple4[int, string, int, string]{}); exists {
+		t.Fatal("got a value from a nil map")
+	}
+
+	single := MapMapMapMap[int, string, int, string, int]{}
+	single.Set(1, "a", 2, "x", 10)
+	expected := []cm.KeyTree[int, cm.KeyTree[string, cm.KeyTree[int, string]]]{
+		{1, []cm.KeyTree[string, cm.KeyTree[int, string]]{
+			{"a", []cm.KeyTree[int, string]{{2, []string{"x"}}}},
+		}},
+	}
+	if !reflect.DeepEqual(single.KeyTree(), expected) {
+		t.Fatalf("incorrect KeyTree: %v", single.KeyTree())
+	}
+	if len(m.KeyTree()) != 2 {
+		t.Fatal("incorrect KeyTree length")
+	}
+
+	m.Delete(1, "a", 2, "x")
+	if m.Len() != 3 || len(m[1]["a"][2]) != 1 {
+		t.Fatal("Delete didn't remove just the one value")
+	}
+	m.Delete(1, "a", 2, "y")
+	if _, exists := m[1]["a"]; exists {
+		t.Fatal("Delete left empty submaps behind")
+	}
+	m.DeleteByTuple(Tuple4[int, string, int, string]{1, "b", 3, "x"})
+	if _, exists := m[1]; exists {
+		t.Fatal("DeleteByTuple left an empty top-level submap behind")
+	}
+	m.Delete(9, "z", 9, "z")
+	if m.Len() != 1 || len(m) != 1 {
+		t.Fatal("deleting a missing value changed the map")
+	}
+
+	m5 := MapMapMapMapMapAny[int, int, int, int, int, int]{}
+	m5.Set(1, 2, 3, 4, 5, 6)
+	m5.Delete(1, 2, 3, 4, 5)
+	if len(m5) != 0 || m5.Len() != 0 {
+		t.Fatal("depth 5 Delete left empty submaps behind")
+	}
+}
+`
+
+// TestGeneratedBehavior runs generatedTest against code generated into a
+// temporary module.
+func TestGeneratedBehavior(t *testing.T) {
+	src, err := generate(5, "perms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := generatedModule(t, "perms", src)
+	err = os.WriteFile(filepath.Join(dir, "gen_test.go"), []byte(generatedTest), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGo(t, dir, "test", "./...")
+}
+
+func TestWrite(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "gen.go")
+	err := write(name, []byte("package cm\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := os.ReadFile(name)
+	if err != nil || string(contents) != "package cm\n" {
+		t.Fatal("write didn't write the file")
+	}
+
+	err = write(filepath.Join(name, "not", "a", "dir"), []byte("package cm\n"))
+	if err == nil {
+		t.Fatal("write didn't report an error creating the file")
+	}
+}
+
+func TestGenerateTooShallow(t *testing.T) {
+	_, err := generate(3, "cm")
+	if err == nil {
+		t.Fatal("can generate maps the cm package already has")
+	}
+}
+
+func TestKeyTreeType(t *testing.T) {
+	l := newLevel(4, "cm.")
+	if l.KeyTree != "cm.KeyTree[K1, cm.KeyTree[K2, cm.KeyTree[K3, K4]]]" {
+		t.Fatalf("incorrect KeyTree type: %s", l.KeyTree)
+	}
+	if l.SubAnyName != "cm.MapMapMapAny" {
+		t.Fatal("incorrect qualification of the cm package")
+	}
+
+	l = newLevel(5, "cm.")
+	if l.SubAnyName != "MapMapMapMapAny" {
+		t.Fatal("incorrect qualification of generated types")
+	}
+}
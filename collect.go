@@ -0,0 +1,209 @@
+package cm
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// MapMapFromIter loads a MapMap in from an iterator of keys and values,
+// such as the one returned by All. If a key appears more than once, the
+// last value wins.
+func MapMapFromIter[K1, K2, V comparable](i iter.Seq2[Tuple2[K1, K2], V]) MapMap[K1, K2, V] {
+	return MapMap[K1, K2, V](MapMapAnyFromIter(i))
+}
+
+// MapMapAnyFromIter loads a MapMapAny in from an iterator of keys and
+// values, such as the one returned by All. If a key appears more than
+// once, the last value wins.
+func MapMapAnyFromIter[K1, K2 comparable, V any](
+	i iter.Seq2[Tuple2[K1, K2], V],
+) MapMapAny[K1, K2, V] {
+	mma := MapMapAny[K1, K2, V]{}
+	for key, val := range i {
+		mma.SetByTuple(key, val)
+	}
+	return mma
+}
+
+// MapMapMapFromIter loads a MapMapMap in from an iterator of keys and
+// values, such as the one returned by All. If a key appears more than
+// once, the last value wins.
+func MapMapMapFromIter[K1, K2, K3, V comparable](
+	i iter.Seq2[Tuple3[K1, K2, K3], V],
+) MapMapMap[K1, K2, K3, V] {
+	return MapMapMap[K1, K2, K3, V](MapMapMapAnyFromIter(i))
+}
+
+// MapMapMapAnyFromIter loads a MapMapMapAny in from an iterator of keys
+// and values, such as the one returned by All. If a key appears more than
+// once, the last value wins.
+func MapMapMapAnyFromIter[K1, K2, K3 comparable, V any](
+	i iter.Seq2[Tuple3[K1, K2, K3], V],
+) MapMapMapAny[K1, K2, K3, V] {
+	mmma := MapMapMapAny[K1, K2, K3, V]{}
+	for key, val := range i {
+		mmma.SetByTuple(key, val)
+	}
+	return mmma
+}
+
+// MapSetFromIter loads a MapSet in from an iterator of key and value
+// pairs.
+func MapSetFromIter[K, V comparable](i iter.Seq[Tuple2[K, V]]) MapSet[K, V] {
+	ms := MapSet[K, V]{}
+	for pair := range i {
+		ms.AddByTuple(pair)
+	}
+	return ms
+}
+
+// DualMapFromIter loads a DualMap in from an iterator of keys and values,
+// such as the one returned by All. If a key appears more than once, the
+// last value wins.
+func DualMapFromIter[P, S comparable, V any](i iter.Seq2[Tuple2[P, S], V]) *DualMap[P, S, V] {
+	dm := &DualMap[P, S, V]{}
+	for key, val := range i {
+		dm.SetByTuple(key, val)
+	}
+	return dm
+}
+
+// DuplicatePolicy selects what the GroupBy functions do when two records
+// have the same keys.
+type DuplicatePolicy int
+
+const (
+	// OnDuplicateError stops grouping and returns a *DuplicateKey
+	// describing the duplicate.
+	OnDuplicateError DuplicatePolicy = iota
+
+	// OnDuplicateKeepFirst keeps the first record with the keys, ignoring
+	// the later ones.
+	OnDuplicateKeepFirst
+
+	// OnDuplicateKeepLast keeps the last record with the keys, replacing
+	// the earlier ones.
+	OnDuplicateKeepLast
+
+	// OnDuplicatePanic panics with a *DuplicateKey describing the
+	// duplicate.
+	OnDuplicatePanic
+)
+
+// ErrDuplicateKey is the error wrapped by all DuplicateKey errors, so that
+// duplicates can be detected with errors.Is without knowing the types.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// DuplicateKey describes two records given the same keys by a GroupBy
+// function. Key is the Tuple2 or Tuple3 of the keys.
+type DuplicateKey[K comparable, R any] struct {
+	Key      K
+	Existing R
+	Record   R
+}
+
+// Error implements the error interface.
+func (dk *DuplicateKey[K, R]) Error() string {
+	return fmt.Sprintf("duplicate key %v: %v and %v", dk.Key, dk.Existing, dk.Record)
+}
+
+// Unwrap returns ErrDuplicateKey.
+func (dk *DuplicateKey[K, R]) Unwrap() error {
+	return ErrDuplicateKey
+}
+
+// onDuplicate applies the policy to a record whose keys are already in
+// use, returning whether to replace the existing record with it.
+func onDuplicate[K comparable, R any](
+	policy DuplicatePolicy,
+	key K,
+	existing R,
+	record R,
+) (bool, error) {
+	switch policy {
+	case OnDuplicateKeepFirst:
+		return false, nil
+	case OnDuplicateKeepLast:
+		return true, nil
+	case OnDuplicatePanic:
+		panic(&DuplicateKey[K, R]{key, existing, record})
+	default:
+		return false, &DuplicateKey[K, R]{key, existing, record}
+	}
+}
+
+// GroupByMapMap builds a MapMapAny of records, keyed by the results of the
+// key functions on each record. To group a slice, pass
+// slices.Values(records).
+//
+// If two records have the same keys, the policy determines the result.
+// Under OnDuplicateError the returned map is nil and the error is a
+// *DuplicateKey[Tuple2[K1, K2], R]; otherwise the error is always nil.
+func GroupByMapMap[R any, K1, K2 comparable](
+	records iter.Seq[R],
+	key1 func(R) K1,
+	key2 func(R) K2,
+	policy DuplicatePolicy,
+) (MapMapAny[K1, K2, R], error) {
+	mma := MapMapAny[K1, K2, R]{}
+	for record := range records {
+		key := Tuple2[K1, K2]{key1(record), key2(record)}
+		if existing, exists := mma.GetByTuple(key); exists {
+			replace, err := onDuplicate(policy, key, existing, record)
+			if err != nil {
+				return nil, err
+			}
+			if !replace {
+				continue
+			}
+		}
+		mma.SetByTuple(key, record)
+	}
+	return mma, nil
+}
+
+// GroupByMapMapMap builds a MapMapMapAny of records, keyed by the results
+// of the key functions on each record. To group a slice, pass
+// slices.Values(records).
+//
+// If two records have the same keys, the policy determines the result.
+// Under OnDuplicateError the returned map is nil and the error is a
+// *DuplicateKey[Tuple3[K1, K2, K3], R]; otherwise the error is always nil.
+func GroupByMapMapMap[R any, K1, K2, K3 comparable](
+	records iter.Seq[R],
+	key1 func(R) K1,
+	key2 func(R) K2,
+	key3 func(R) K3,
+	policy DuplicatePolicy,
+) (MapMapMapAny[K1, K2, K3, R], error) {
+	mmma := MapMapMapAny[K1, K2, K3, R]{}
+	for record := range records {
+		key := Tuple3[K1, K2, K3]{key1(record), key2(record), key3(record)}
+		if existing, exists := mmma.GetByTuple(key); exists {
+			replace, err := onDuplicate(policy, key, existing, record)
+			if err != nil {
+				return nil, err
+			}
+			if !replace {
+				continue
+			}
+		}
+		mmma.SetByTuple(key, record)
+	}
+	return mmma, nil
+}
+
+// GroupByMapSet builds a MapSet of records, keyed by the result of the key
+// function on each record. To group a slice, pass slices.Values(records).
+//
+// As the records are collected into sets, there are no duplicate keys to
+// resolve; a record that appears more than once is simply in the set
+// once.
+func GroupByMapSet[R, K comparable](records iter.Seq[R], key func(R) K) MapSet[K, R] {
+	ms := MapSet[K, R]{}
+	for record := range records {
+		ms.Add(key(record), record)
+	}
+	return ms
+}
@@ -0,0 +1,107 @@
+package cm
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestFromIter(t *testing.T) {
+	mm := MapMap[int, int, string]{}
+	mm.Set(1, 2, "a")
+	mm.Set(2, 3, "b")
+	if !MapMapFromIter(mm.All()).Equal(mm) {
+		t.Fatal("MapMapFromIter didn't work")
+	}
+
+	mmm := MapMapMap[int, int, int, string]{}
+	mmm.Set(1, 2, 3, "a")
+	mmm.Set(2, 3, 4, "b")
+	if !MapMapMapFromIter(mmm.All()).Equal(mmm) {
+		t.Fatal("MapMapMapFromIter didn't work")
+	}
+
+	ms := MapSet[int, int]{}
+	ms.Add(1, 2)
+	ms.Add(1, 3)
+	ms.Add(2, 2)
+	fromIter := MapSetFromIter(slices.Values([]Tuple2[int, int]{{1, 2}, {1, 3}, {2, 2}}))
+	if !EqualMapSets[int, int](fromIter, ms) {
+		t.Fatal("MapSetFromIter didn't work")
+	}
+
+	dm := DualMapFromIter(mm.All())
+	if !EqualMapMaps[int, int, string](dm, mm) {
+		t.Fatal("DualMapFromIter didn't work")
+	}
+	if dm.Reverse[3][2] != "b" {
+		t.Fatal("DualMapFromIter didn't populate the reverse map")
+	}
+}
+
+type groupTestRecord struct {
+	Region  string
+	Service string
+	Host    int
+	Load    int
+}
+
+func TestGroupBy(t *testing.T) {
+	records := []groupTestRecord{
+		{"us", "web", 1, 10},
+		{"us", "web", 2, 20},
+		{"us", "db", 1, 30},
+		{"eu", "web", 1, 40},
+	}
+	region := func(r groupTestRecord) string { return r.Region }
+	service := func(r groupTestRecord) string { return r.Service }
+	host := func(r groupTestRecord) int { return r.Host }
+
+	mmm, err := GroupByMapMapMap(slices.Values(records), region, service, host, OnDuplicateError)
+	if err != nil || mmm.Len() != 4 || mmm["us"]["web"][2].Load != 20 {
+		t.Fatal("GroupByMapMapMap didn't work")
+	}
+
+	ms := GroupByMapSet(slices.Values(records), region)
+	if len(ms) != 2 || len(ms["us"]) != 3 || !ms["eu"].Contains(records[3]) {
+		t.Fatal("GroupByMapSet didn't work")
+	}
+
+	mm, err := GroupByMapMap(slices.Values(records), region, service, OnDuplicateKeepFirst)
+	if err != nil || mm.Len() != 3 || mm["us"]["web"].Load != 10 {
+		t.Fatal("OnDuplicateKeepFirst didn't work")
+	}
+	mm, err = GroupByMapMap(slices.Values(records), region, service, OnDuplicateKeepLast)
+	if err != nil || mm.Len() != 3 || mm["us"]["web"].Load != 20 {
+		t.Fatal("OnDuplicateKeepLast didn't work")
+	}
+
+	mm, err = GroupByMapMap(slices.Values(records), region, service, OnDuplicateError)
+	if mm != nil || !errors.Is(err, ErrDuplicateKey) {
+		t.Fatal("OnDuplicateError didn't work")
+	}
+	var dk *DuplicateKey[Tuple2[string, string], groupTestRecord]
+	if !errors.As(err, &dk) ||
+		dk.Key != (Tuple2[string, string]{"us", "web"}) ||
+		dk.Existing != records[0] ||
+		dk.Record != records[1] {
+		t.Fatal("incorrect DuplicateKey:", err)
+	}
+	if err.Error() != "duplicate key {us web}: {us web 1 10} and {us web 2 20}" {
+		t.Fatal("incorrect error message:", err)
+	}
+
+	panics(t, "OnDuplicatePanic", func() {
+		_, _ = GroupByMapMap(slices.Values(records), region, service, OnDuplicatePanic)
+	})
+
+	duplicated := append(slices.Clone(records), records[0])
+	_, err = GroupByMapMapMap(slices.Values(duplicated), region, service, host, OnDuplicateError)
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatal("GroupByMapMapMap didn't detect the duplicate")
+	}
+	mmm, err = GroupByMapMapMap(slices.Values(duplicated), region, service, host, OnDuplicateKeepFirst)
+	if err != nil || mmm.Len() != 4 {
+		t.Fatal("GroupByMapMapMap didn't keep the first record")
+	}
+}
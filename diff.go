@@ -0,0 +1,260 @@
+package cm
+
+// MapDiff describes the differences between two maps, from the map the
+// Diff method was called on (the "old" map) to the map passed in to it
+// (the "new" map). The keys are the Tuple2 or Tuple3 of the nested map
+// the diff was taken from.
+//
+// Added contains the entries only in the new map, Removed the entries
+// only in the old map, and Changed the entries in both whose values
+// differ. Each of these maps is always non-nil, even when empty.
+type MapDiff[K comparable, V any] struct {
+	Added   map[K]V
+	Removed map[K]V
+	Changed map[K]Change[V]
+}
+
+// Change contains the old and new value of an entry in a MapDiff.
+type Change[V any] struct {
+	Old V
+	New V
+}
+
+// SetDiff describes the differences between two sets, from the set the
+// Diff method was called on to the set passed in to it. Added contains
+// the values only in the new set, Removed the values only in the old
+// set. Both are always non-nil, even when empty.
+type SetDiff[M comparable] struct {
+	Added   Set[M]
+	Removed Set[M]
+}
+
+func newMapDiff[K comparable, V any]() MapDiff[K, V] {
+	return MapDiff[K, V]{
+		Added:   map[K]V{},
+		Removed: map[K]V{},
+		Changed: map[K]Change[V]{},
+	}
+}
+
+// Empty returns true if the diff describes no changes.
+func (md MapDiff[K, V]) Empty() bool {
+	return len(md.Added) == 0 && len(md.Removed) == 0 && len(md.Changed) == 0
+}
+
+// Empty returns true if the diff describes no changes.
+func (sd SetDiff[M]) Empty() bool {
+	return len(sd.Added) == 0 && len(sd.Removed) == 0
+}
+
+// DiffFunc returns the differences between this MapMap and the
+// passed-in MapMap, using the passed-in function to compare the
+// equality of values.
+func (mma MapMapAny[K1, K2, V]) DiffFunc(
+	r MapMapAny[K1, K2, V],
+	eq func(v1, v2 V) bool,
+) MapDiff[Tuple2[K1, K2], V] {
+	diff := newMapDiff[Tuple2[K1, K2], V]()
+
+	for key, val := range mma.All() {
+		rVal, exists := r.GetByTuple(key)
+		switch {
+		case !exists:
+			diff.Removed[key] = val
+		case !eq(val, rVal):
+			diff.Changed[key] = Change[V]{val, rVal}
+		}
+	}
+	for key, val := range r.All() {
+		if _, exists := mma.GetByTuple(key); !exists {
+			diff.Added[key] = val
+		}
+	}
+
+	return diff
+}
+
+// Apply replays the diff onto this MapMap. Removed entries are deleted,
+// cleaning up emptied submaps just as Delete does, and added and changed
+// entries are set to their new values.
+//
+// Applying a diff taken from a to b onto a will result in a being equal
+// to b. Applying it to a different map applies the same changes, without
+// checking that the old values match.
+//
+// This will panic if called on a nil map with any values to set.
+func (mma MapMapAny[K1, K2, V]) Apply(diff MapDiff[Tuple2[K1, K2], V]) {
+	for key := range diff.Removed {
+		mma.DeleteByTuple(key)
+	}
+	for key, change := range diff.Changed {
+		mma.SetByTuple(key, change.New)
+	}
+	for key, val := range diff.Added {
+		mma.SetByTuple(key, val)
+	}
+}
+
+// Diff returns the differences between this MapMap and the passed-in
+// MapMap.
+func (mm MapMap[K1, K2, V]) Diff(r MapMap[K1, K2, V]) MapDiff[Tuple2[K1, K2], V] {
+	return MapMapAny[K1, K2, V](mm).DiffFunc(
+		MapMapAny[K1, K2, V](r),
+		func(v1, v2 V) bool { return v1 == v2 },
+	)
+}
+
+// DiffFunc returns the differences between this MapMap and the
+// passed-in MapMap, using the passed-in function to compare the
+// equality of values.
+func (mm MapMap[K1, K2, V]) DiffFunc(
+	r MapMap[K1, K2, V],
+	eq func(v1, v2 V) bool,
+) MapDiff[Tuple2[K1, K2], V] {
+	return MapMapAny[K1, K2, V](mm).DiffFunc(MapMapAny[K1, K2, V](r), eq)
+}
+
+// Apply replays the diff onto this MapMap. See MapMapAny.Apply.
+func (mm MapMap[K1, K2, V]) Apply(diff MapDiff[Tuple2[K1, K2], V]) {
+	MapMapAny[K1, K2, V](mm).Apply(diff)
+}
+
+// DiffFunc returns the differences between this MapMapMap and the
+// passed-in MapMapMap, using the passed-in function to compare the
+// equality of values.
+func (mmma MapMapMapAny[K1, K2, K3, V]) DiffFunc(
+	r MapMapMapAny[K1, K2, K3, V],
+	eq func(v1, v2 V) bool,
+) MapDiff[Tuple3[K1, K2, K3], V] {
+	diff := newMapDiff[Tuple3[K1, K2, K3], V]()
+
+	for key, val := range mmma.All() {
+		rVal, exists := r.GetByTuple(key)
+		switch {
+		case !exists:
+			diff.Removed[key] = val
+		case !eq(val, rVal):
+			diff.Changed[key] = Change[V]{val, rVal}
+		}
+	}
+	for key, val := range r.All() {
+		if _, exists := mmma.GetByTuple(key); !exists {
+			diff.Added[key] = val
+		}
+	}
+
+	return diff
+}
+
+// Apply replays the diff onto this MapMapMap. Removed entries are
+// deleted, cleaning up emptied submaps just as Delete does, and added and
+// changed entries are set to their new values.
+//
+// This will panic if called on a nil map with any values to set.
+func (mmma MapMapMapAny[K1, K2, K3, V]) Apply(diff MapDiff[Tuple3[K1, K2, K3], V]) {
+	for key := range diff.Removed {
+		mmma.DeleteByTuple(key)
+	}
+	for key, change := range diff.Changed {
+		mmma.SetByTuple(key, change.New)
+	}
+	for key, val := range diff.Added {
+		mmma.SetByTuple(key, val)
+	}
+}
+
+// Diff returns the differences between this MapMapMap and the passed-in
+// MapMapMap.
+func (mmm MapMapMap[K1, K2, K3, V]) Diff(
+	r MapMapMap[K1, K2, K3, V],
+) MapDiff[Tuple3[K1, K2, K3], V] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).DiffFunc(
+		MapMapMapAny[K1, K2, K3, V](r),
+		func(v1, v2 V) bool { return v1 == v2 },
+	)
+}
+
+// DiffFunc returns the differences between this MapMapMap and the
+// passed-in MapMapMap, using the passed-in function to compare the
+// equality of values.
+func (mmm MapMapMap[K1, K2, K3, V]) DiffFunc(
+	r MapMapMap[K1, K2, K3, V],
+	eq func(v1, v2 V) bool,
+) MapDiff[Tuple3[K1, K2, K3], V] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).DiffFunc(
+		MapMapMapAny[K1, K2, K3, V](r), eq)
+}
+
+// Apply replays the diff onto this MapMapMap. See MapMapMapAny.Apply.
+func (mmm MapMapMap[K1, K2, K3, V]) Apply(diff MapDiff[Tuple3[K1, K2, K3], V]) {
+	MapMapMapAny[K1, K2, K3, V](mmm).Apply(diff)
+}
+
+// Diff returns the differences between this set and the passed-in set.
+func (s Set[M]) Diff(r Set[M]) SetDiff[M] {
+	diff := SetDiff[M]{Added: Set[M]{}, Removed: Set[M]{}}
+	for val := range s {
+		if !r.Contains(val) {
+			diff.Removed.Add(val)
+		}
+	}
+	for val := range r {
+		if !s.Contains(val) {
+			diff.Added.Add(val)
+		}
+	}
+	return diff
+}
+
+// Apply replays the diff onto this set, removing the removed values and
+// adding the added ones.
+//
+// This will panic if called on a nil set with any values to add.
+func (s Set[M]) Apply(diff SetDiff[M]) {
+	s.Subtract(diff.Removed)
+	if len(diff.Added) != 0 {
+		s.Union(diff.Added)
+	}
+}
+
+// Diff returns the differences between this MapSet and the passed-in
+// MapSet, as a SetDiff of the key/value pairs.
+func (ms MapSet[K, V]) Diff(r MapSet[K, V]) SetDiff[Tuple2[K, V]] {
+	diff := SetDiff[Tuple2[K, V]]{
+		Added:   Set[Tuple2[K, V]]{},
+		Removed: Set[Tuple2[K, V]]{},
+	}
+
+	for key, set := range ms {
+		rSet := r[key]
+		for val := range set {
+			if !rSet.Contains(val) {
+				diff.Removed.Add(Tuple2[K, V]{key, val})
+			}
+		}
+	}
+	for key, rSet := range r {
+		set := ms[key]
+		for val := range rSet {
+			if !set.Contains(val) {
+				diff.Added.Add(Tuple2[K, V]{key, val})
+			}
+		}
+	}
+
+	return diff
+}
+
+// Apply replays the diff onto this MapSet. Removed pairs are deleted,
+// cleaning up emptied sets just as Delete does, and added pairs are
+// added.
+//
+// This will panic if called on a nil MapSet with any values to add.
+func (ms MapSet[K, V]) Apply(diff SetDiff[Tuple2[K, V]]) {
+	for key := range diff.Removed {
+		ms.Delete(key.Key1, key.Key2)
+	}
+	for key := range diff.Added {
+		ms.AddByTuple(key)
+	}
+}
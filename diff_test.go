@@ -0,0 +1,159 @@
+package cm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapMapDiff(t *testing.T) {
+	old := MapMap[int, int, string]{}
+	old.Set(0, 1, "same")
+	old.Set(0, 2, "changing")
+	old.Set(1, 1, "removed")
+
+	updated := MapMap[int, int, string]{}
+	updated.Set(0, 1, "same")
+	updated.Set(0, 2, "changed")
+	updated.Set(2, 2, "added")
+
+	diff := old.Diff(updated)
+	if !reflect.DeepEqual(diff, MapDiff[Tuple2[int, int], string]{
+		Added:   map[Tuple2[int, int]]string{{2, 2}: "added"},
+		Removed: map[Tuple2[int, int]]string{{1, 1}: "removed"},
+		Changed: map[Tuple2[int, int]]Change[string]{
+			{0, 2}: {"changing", "changed"},
+		},
+	}) {
+		t.Fatal("incorrect diff")
+	}
+	if diff.Empty() {
+		t.Fatal("diff is empty")
+	}
+
+	patched := old.Clone()
+	patched.Apply(diff)
+	if !patched.Equal(updated) {
+		t.Fatal("applying the diff didn't produce the new map")
+	}
+	if _, exists := patched[1]; exists {
+		t.Fatal("applying the diff didn't clean up the empty submap")
+	}
+
+	if !updated.Diff(patched).Empty() {
+		t.Fatal("diff between equal maps isn't empty")
+	}
+
+	strLenEq := func(a, b string) bool { return len(a) == len(b) }
+	diff = old.DiffFunc(updated, strLenEq)
+	if len(diff.Changed) != 1 {
+		t.Fatal("DiffFunc didn't use the equality function")
+	}
+	diff = old.DiffFunc(old.Clone(), strLenEq)
+	if !diff.Empty() {
+		t.Fatal("DiffFunc found differences in equal maps")
+	}
+
+	// nil maps are just empty
+	var nilMM MapMap[int, int, string]
+	diff = nilMM.Diff(updated)
+	if len(diff.Added) != 3 || len(diff.Removed) != 0 {
+		t.Fatal("incorrect diff from a nil map")
+	}
+	diff = updated.Diff(nilMM)
+	if len(diff.Removed) != 3 || len(diff.Added) != 0 {
+		t.Fatal("incorrect diff to a nil map")
+	}
+	nilMM.Apply(diff)
+	panics(t, "could apply additions to a nil map",
+		func() { nilMM.Apply(nilMM.Diff(updated)) })
+}
+
+func TestMapMapMapDiff(t *testing.T) {
+	old := MapMapMap[int, int, int, int]{}
+	old.Set(0, 0, 0, 0)
+	old.Set(0, 0, 1, 1)
+	old.Set(1, 1, 1, 1)
+
+	updated := MapMapMap[int, int, int, int]{}
+	updated.Set(0, 0, 0, 0)
+	updated.Set(0, 0, 1, 2)
+	updated.Set(2, 2, 2, 2)
+
+	diff := old.Diff(updated)
+	if !reflect.DeepEqual(diff, MapDiff[Tuple3[int, int, int], int]{
+		Added:   map[Tuple3[int, int, int]]int{{2, 2, 2}: 2},
+		Removed: map[Tuple3[int, int, int]]int{{1, 1, 1}: 1},
+		Changed: map[Tuple3[int, int, int]]Change[int]{{0, 0, 1}: {1, 2}},
+	}) {
+		t.Fatal("incorrect diff")
+	}
+
+	patched := old.Clone()
+	patched.Apply(diff)
+	if !patched.Equal(updated) {
+		t.Fatal("applying the diff didn't produce the new map")
+	}
+	if _, exists := patched[1]; exists {
+		t.Fatal("applying the diff didn't clean up the empty submap")
+	}
+
+	diff = old.DiffFunc(updated, func(a, b int) bool { return true })
+	if len(diff.Changed) != 0 || len(diff.Added) != 1 {
+		t.Fatal("DiffFunc didn't use the equality function")
+	}
+}
+
+func TestSetDiff(t *testing.T) {
+	old := SetFromSlice([]int{1, 2, 3})
+	updated := SetFromSlice([]int{2, 3, 4})
+
+	diff := old.Diff(updated)
+	if !diff.Added.Equal(SetFromSlice([]int{4})) ||
+		!diff.Removed.Equal(SetFromSlice([]int{1})) {
+		t.Fatal("incorrect set diff")
+	}
+
+	old.Apply(diff)
+	if !old.Equal(updated) {
+		t.Fatal("applying the diff didn't produce the new set")
+	}
+	if !old.Diff(updated).Empty() {
+		t.Fatal("diff between equal sets isn't empty")
+	}
+
+	var nilSet Set[int]
+	diff = nilSet.Diff(nilSet)
+	if diff.Added == nil || diff.Removed == nil || !diff.Empty() {
+		t.Fatal("incorrect diff between nil sets")
+	}
+	nilSet.Apply(updated.Diff(nilSet))
+	panics(t, "could apply additions to a nil set",
+		func() { nilSet.Apply(nilSet.Diff(updated)) })
+}
+
+func TestMapSetDiff(t *testing.T) {
+	old := MapSet[string, int]{}
+	old.Add("a", 1)
+	old.Add("a", 2)
+	old.Add("b", 1)
+
+	updated := MapSet[string, int]{}
+	updated.Add("a", 1)
+	updated.Add("c", 3)
+
+	diff := old.Diff(updated)
+	if !diff.Added.Equal(SetFromSlice([]Tuple2[string, int]{{"c", 3}})) ||
+		!diff.Removed.Equal(SetFromSlice([]Tuple2[string, int]{
+			{"a", 2}, {"b", 1},
+		})) {
+		t.Fatal("incorrect MapSet diff")
+	}
+
+	old.Apply(diff)
+	if !reflect.DeepEqual(old, updated) {
+		t.Fatal("applying the diff didn't produce the new MapSet")
+	}
+	if !old.Diff(updated).Empty() {
+		t.Fatal("diff between equal MapSets isn't empty")
+	}
+}
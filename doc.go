@@ -3,8 +3,10 @@
 Package cm contains generic "complicated maps": multi-level maps, dual-key
 maps, and maps containing sets.
 
-This package provides no locking in the datastructures. All locking is
-the responsibility of code using these maps.
+The core datastructures in this package provide no locking. All locking
+is the responsibility of code using these maps, with the exception of the
+Sync types, which are concurrency-safe wrappers around them. See
+SyncMapMapAny.
 
 This code panics analogously to normal map behaviors. When there is no
 existing map behavior to guide, it tries to match the same logic Go
@@ -84,6 +86,45 @@ be implemented for manipulating such values.
 As a consequence of offering this functionality, this package also provides
 a Set implementation.
 
+Persistent Types
+
+The Persistent types are immutable versions of Set, MapMap, and MapSet.
+Rather than modifying the value they are called on, their With and
+Without methods return a new version with the change applied, leaving
+the original untouched. The new version shares all of its unchanged
+structure with the old one, so these operations take O(log n) time and
+space rather than copying the whole thing.
+
+Since a version can never change, they are safe to share between
+goroutines without locking, and holding on to an old version is a
+cheap snapshot.
+
+For making many changes at once, each Persistent type has a Builder,
+which is a mutable, transient version of it. A Builder starts from a
+persistent version, and changes to it are made in place wherever that
+is safe, which is substantially faster than a series of With calls.
+Calling Persistent on the Builder returns the result as a persistent
+version again. A Builder is not safe for concurrent use, just like the
+other mutable types in this package.
+
+The zero value of all the Persistent types and their Builders is
+empty and ready to use. They are implemented as hash array mapped
+tries, so like maps, iteration order is unspecified.
+
+Sorted Types
+
+SortedSet, SortedMapMap and SortedMapSet are B-tree backed versions of
+Set, MapMapAny and MapSet that keep every level of their keys in
+order, as determined by comparison functions in the style of
+slices.SortFunc. On top of the usual operations, they offer Range
+queries over a half-open interval of keys, Floor, Ceiling, Min and Max,
+and Backward iteration. The levels below the first are SortedMaps and
+SortedSets, which offer the same on their own keys.
+
+Lookups and updates take O(log n) time rather than a map's O(1), so
+these are for when the ordering is actually needed. For the occasional
+sorted iteration over an ordinary map, see the SortedAllFunc methods.
+
 Key Trees And Key Slices
 
 Each of these structures implements the ability to get data structures
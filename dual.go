@@ -1,5 +1,7 @@
 package cm
 
+import "iter"
+
 // A DualMap is a map that will store values in a way that allows you to
 // access them by either key. For any key tuple (K1, K2), you can get the
 // set of all values by either K1 or K2. This contrasts with a standard
@@ -69,3 +71,91 @@ func (dm *DualMap[P, S, V]) Delete(l P, r S) {
 func (dm *DualMap[P, S, V]) DeleteByTuple(key Tuple2[P, S]) {
 	dm.Delete(key.Key1, key.Key2)
 }
+
+// DeleteAllPrimary deletes every value with the given primary key,
+// removing them from both the Primary and the Reverse maps.
+func (dm *DualMap[P, S, V]) DeleteAllPrimary(p P) {
+	for s := range dm.Primary[p] {
+		dm.Reverse.Delete(s, p)
+	}
+	delete(dm.Primary, p)
+}
+
+// DeleteAllSecondary deletes every value with the given secondary key,
+// removing them from both the Primary and the Reverse maps.
+func (dm *DualMap[P, S, V]) DeleteAllSecondary(s S) {
+	for p := range dm.Reverse[s] {
+		dm.Primary.Delete(p, s)
+	}
+	delete(dm.Reverse, s)
+}
+
+// DeleteFunc deletes the values for which the function returns true from
+// both the Primary and the Reverse maps. The function is called with the
+// keys in primary/secondary order.
+func (dm *DualMap[P, S, V]) DeleteFunc(f func(P, S, V) bool) {
+	dm.Primary.DeleteFunc(func(p P, s S, v V) bool {
+		if f(p, s, v) {
+			dm.Reverse.Delete(s, p)
+			return true
+		}
+		return false
+	})
+}
+
+// Len returns the total number of values in the DualMap.
+func (dm *DualMap[P, S, V]) Len() int {
+	return dm.Primary.Len()
+}
+
+// All returns an iterator over the DualMap that yields the keys as a
+// Tuple2 in primary/secondary order, and the value in the value slot.
+func (dm *DualMap[P, S, V]) All() iter.Seq2[Tuple2[P, S], V] {
+	return dm.Primary.All()
+}
+
+// Keys returns an iterator on the keys as a Tuple2 in primary/secondary
+// order.
+func (dm *DualMap[P, S, V]) Keys() iter.Seq[Tuple2[P, S]] {
+	return dm.Primary.Keys()
+}
+
+// Values returns an iterator for all values in this DualMap in a
+// nondeterministic order.
+func (dm *DualMap[P, S, V]) Values() iter.Seq[V] {
+	return dm.Primary.Values()
+}
+
+// Clone returns a shallow copy of the DualMap.
+//
+// The clone of a DualMap with nothing set is also a DualMap with nothing
+// set.
+func (dm *DualMap[P, S, V]) Clone() *DualMap[P, S, V] {
+	if dm.Primary == nil {
+		return &DualMap[P, S, V]{}
+	}
+	return &DualMap[P, S, V]{
+		Primary: dm.Primary.Clone(),
+		Reverse: dm.Reverse.Clone(),
+	}
+}
+
+// EqualFunc returns if this DualMap contains the same keys and values as
+// the passed-in DualMap, using the passed-in function to compare the
+// equality of values.
+//
+// Only the Primary maps are compared, as the Reverse maps are
+// determined by them.
+func (dm *DualMap[P, S, V]) EqualFunc(
+	r *DualMap[P, S, V],
+	eq func(v1, v2 V) bool,
+) bool {
+	return dm.Primary.EqualFunc(r.Primary, eq)
+}
+
+// DualMapEqual returns if the two DualMaps contain the same keys and
+// values. This is a function rather than a method because DualMap does
+// not require a comparable value type.
+func DualMapEqual[P, S, V comparable](l, r *DualMap[P, S, V]) bool {
+	return l.EqualFunc(r, func(v1, v2 V) bool { return v1 == v2 })
+}
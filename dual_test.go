@@ -58,3 +58,80 @@ func TestDualMaps(t *testing.T) {
 		t.Fatal("couldn't set into zero-value DualMap")
 	}
 }
+
+func TestDualMapMethods(t *testing.T) {
+	dm := &DualMap[int, string, int]{}
+	dm.Set(1, "a", 10)
+	dm.Set(1, "b", 20)
+	dm.Set(2, "a", 30)
+	dm.Set(2, "b", 40)
+	dm.Set(3, "c", 50)
+
+	if dm.Len() != 5 {
+		t.Fatal("incorrect Len")
+	}
+	count := 0
+	for key, val := range dm.All() {
+		if dm.Reverse[key.Key2][key.Key1] != val {
+			t.Fatal("incorrect All")
+		}
+		count++
+	}
+	for range dm.Keys() {
+		count++
+	}
+	for range dm.Values() {
+		count++
+	}
+	if count != 15 {
+		t.Fatal("incorrect iteration")
+	}
+
+	clone := dm.Clone()
+	if !DualMapEqual(dm, clone) {
+		t.Fatal("clone isn't equal")
+	}
+	clone.Set(3, "c", 51)
+	if DualMapEqual(dm, clone) {
+		t.Fatal("clone isn't independent")
+	}
+	if !dm.EqualFunc(clone, func(v1, v2 int) bool { return v1/10 == v2/10 }) {
+		t.Fatal("incorrect EqualFunc")
+	}
+
+	dm.DeleteAllPrimary(1)
+	dm.DeleteAllPrimary(9)
+	expected := &DualMap[int, string, int]{}
+	expected.Set(2, "a", 30)
+	expected.Set(2, "b", 40)
+	expected.Set(3, "c", 50)
+	if !reflect.DeepEqual(dm, expected) {
+		t.Fatal("incorrect DeleteAllPrimary")
+	}
+
+	dm.DeleteAllSecondary("a")
+	dm.DeleteAllSecondary("z")
+	expected.Delete(2, "a")
+	if !reflect.DeepEqual(dm, expected) {
+		t.Fatal("incorrect DeleteAllSecondary")
+	}
+
+	dm.DeleteFunc(func(p int, s string, v int) bool {
+		return p == 2 && s == "b" && v == 40
+	})
+	expected.Delete(2, "b")
+	if !reflect.DeepEqual(dm, expected) {
+		t.Fatal("incorrect DeleteFunc")
+	}
+
+	empty := &DualMap[int, string, int]{}
+	if !reflect.DeepEqual(empty.Clone(), empty) || !DualMapEqual(empty, empty.Clone()) {
+		t.Fatal("incorrect Clone of an empty DualMap")
+	}
+	empty.DeleteAllPrimary(1)
+	empty.DeleteAllSecondary("a")
+	empty.DeleteFunc(func(int, string, int) bool { return true })
+	if empty.Len() != 0 {
+		t.Fatal("empty DualMap isn't empty")
+	}
+}
@@ -0,0 +1,168 @@
+package cm
+
+import "iter"
+
+// A FlatMapMap is a map with two keys, like MapMapAny, stored in a single
+// map keyed by Tuple2 rather than a map per first key.
+//
+// A MapMapAny allocates a Go map for every first key, which is wasteful
+// when most first keys have only one or two second keys. A FlatMapMap
+// has no such overhead, but can not find the entries for a given first
+// key without scanning the whole map. If that is needed, an indexed
+// FlatMapMap, as returned by NewIndexedFlatMapMap, also maintains a set
+// of second keys for each first key, at the cost of a Set per first key
+// and some extra work on every Set and Delete. See the benchmarks in the
+// tests to compare the layouts.
+//
+// The zero-value of this struct is an empty, unindexed FlatMapMap, ready
+// to use.
+type FlatMapMap[K1, K2 comparable, V any] struct {
+	m map[Tuple2[K1, K2]]V
+
+	// index holds the second keys of each first key. It is nil if the
+	// FlatMapMap is not indexed.
+	index MapSet[K1, K2]
+}
+
+// NewIndexedFlatMapMap returns an empty FlatMapMap that maintains an
+// index of its first keys.
+func NewIndexedFlatMapMap[K1, K2 comparable, V any]() *FlatMapMap[K1, K2, V] {
+	return &FlatMapMap[K1, K2, V]{index: MapSet[K1, K2]{}}
+}
+
+// Set will set the given value with the given keys.
+func (fmm *FlatMapMap[K1, K2, V]) Set(key1 K1, key2 K2, value V) {
+	fmm.SetByTuple(Tuple2[K1, K2]{key1, key2}, value)
+}
+
+// SetByTuple sets by the key tuple.
+func (fmm *FlatMapMap[K1, K2, V]) SetByTuple(key Tuple2[K1, K2], value V) {
+	if fmm.m == nil {
+		fmm.m = map[Tuple2[K1, K2]]V{}
+	}
+	fmm.m[key] = value
+	if fmm.index != nil {
+		fmm.index.AddByTuple(key)
+	}
+}
+
+// Delete deletes the value from the map.
+func (fmm *FlatMapMap[K1, K2, V]) Delete(key1 K1, key2 K2) {
+	fmm.DeleteByTuple(Tuple2[K1, K2]{key1, key2})
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func (fmm *FlatMapMap[K1, K2, V]) DeleteByTuple(key Tuple2[K1, K2]) {
+	delete(fmm.m, key)
+	if fmm.index != nil {
+		fmm.index.Delete(key.Key1, key.Key2)
+	}
+}
+
+// Get returns the value for the given keys. The second value is true if
+// the keys exist, false otherwise.
+func (fmm *FlatMapMap[K1, K2, V]) Get(key1 K1, key2 K2) (val V, exists bool) {
+	return fmm.GetByTuple(Tuple2[K1, K2]{key1, key2})
+}
+
+// GetByTuple retrieves by the given tuple. The second value is true if
+// the key exists, false otherwise.
+func (fmm *FlatMapMap[K1, K2, V]) GetByTuple(key Tuple2[K1, K2]) (val V, exists bool) {
+	val, exists = fmm.m[key]
+	return val, exists
+}
+
+// Len returns the number of values in the map.
+func (fmm *FlatMapMap[K1, K2, V]) Len() int {
+	return len(fmm.m)
+}
+
+// All returns an iterator over the map that yields the keys as a Tuple2,
+// and the value in the value slot.
+func (fmm *FlatMapMap[K1, K2, V]) All() iter.Seq2[Tuple2[K1, K2], V] {
+	return func(yield func(Tuple2[K1, K2], V) bool) {
+		for key, val := range fmm.m {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator on the keys as a Tuple2.
+func (fmm *FlatMapMap[K1, K2, V]) Keys() iter.Seq[Tuple2[K1, K2]] {
+	return func(yield func(Tuple2[K1, K2]) bool) {
+		for key := range fmm.m {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator for all values in the map in a
+// nondeterministic order.
+func (fmm *FlatMapMap[K1, K2, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range fmm.m {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// Submap returns an iterator over the second keys and values under the
+// given first key. This uses the index if there is one, and otherwise
+// scans the whole map.
+func (fmm *FlatMapMap[K1, K2, V]) Submap(key1 K1) iter.Seq2[K2, V] {
+	return func(yield func(K2, V) bool) {
+		if fmm.index != nil {
+			for key2 := range fmm.index[key1] {
+				if !yield(key2, fmm.m[Tuple2[K1, K2]{key1, key2}]) {
+					return
+				}
+			}
+			return
+		}
+		for key, val := range fmm.m {
+			if key.Key1 == key1 && !yield(key.Key2, val) {
+				return
+			}
+		}
+	}
+}
+
+// KeyTree returns the keys of the map as a 2-level tree of the various
+// keys. This uses the index if there is one, and otherwise has to group
+// the keys itself.
+//
+// An empty map will return a nil slice.
+func (fmm *FlatMapMap[K1, K2, V]) KeyTree() []KeyTree[K1, K2] {
+	if len(fmm.m) == 0 {
+		return nil
+	}
+
+	index := fmm.index
+	if index == nil {
+		index = MapSet[K1, K2]{}
+		for key := range fmm.m {
+			index.AddByTuple(key)
+		}
+	}
+
+	r := make([]KeyTree[K1, K2], 0, len(index))
+	for key1, key2s := range index {
+		r = append(r, KeyTree[K1, K2]{key1, key2s.AsSlice()})
+	}
+	return r
+}
+
+// MapMapAny returns the contents of the map as a MapMapAny.
+func (fmm *FlatMapMap[K1, K2, V]) MapMapAny() MapMapAny[K1, K2, V] {
+	mma := MapMapAny[K1, K2, V]{}
+	for key, val := range fmm.m {
+		mma.SetByTuple(key, val)
+	}
+	return mma
+}
@@ -0,0 +1,199 @@
+package cm
+
+import (
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestFlatMapMap(t *testing.T) {
+	for _, fmm := range []*FlatMapMap[int, int, string]{
+		{},
+		NewIndexedFlatMapMap[int, int, string](),
+	} {
+		if fmm.KeyTree() != nil {
+			t.Fatal("KeyTree of an empty map isn't nil")
+		}
+
+		mma := MapMapAny[int, int, string]{}
+		for _, key := range []Tuple2[int, int]{{1, 1}, {1, 2}, {2, 1}, {3, 3}} {
+			val := fmt.Sprint(key.Key1, key.Key2)
+			fmm.SetByTuple(key, val)
+			mma.SetByTuple(key, val)
+		}
+		fmm.Set(3, 4, "removed")
+		fmm.Delete(3, 4)
+
+		if !EqualMapMaps[int, int, string](fmm, mma) || fmm.Len() != 4 {
+			t.Fatal("FlatMapMap doesn't contain the values")
+		}
+		if !MapMap[int, int, string](fmm.MapMapAny()).Equal(MapMap[int, int, string](mma)) {
+			t.Fatal("MapMapAny didn't work")
+		}
+		if val, exists := fmm.Get(1, 2); !exists || val != "1 2" {
+			t.Fatal("Get didn't work")
+		}
+
+		keys := Set[Tuple2[int, int]]{}
+		for key := range fmm.Keys() {
+			keys.Add(key)
+		}
+		if !keys.Equal(SetFromIter(mma.Keys())) {
+			t.Fatal("Keys didn't work")
+		}
+		if !SetFromIter(fmm.Values()).Equal(SetFromIter(mma.Values())) {
+			t.Fatal("Values didn't work")
+		}
+
+		submap := map[int]string{}
+		for key2, val := range fmm.Submap(1) {
+			submap[key2] = val
+		}
+		if !(MapMap[int, int, string]{1: submap}).Equal(MapMap[int, int, string]{1: mma[1]}) {
+			t.Fatal("Submap didn't work")
+		}
+
+		keyTree := fmm.KeyTree()
+		sort.Slice(keyTree, func(i, j int) bool { return keyTree[i].Key < keyTree[j].Key })
+		for _, kt := range keyTree {
+			slices.Sort(kt.Vals)
+		}
+		if !slices.EqualFunc(keyTree, []KeyTree[int, int]{
+			{1, []int{1, 2}}, {2, []int{1}}, {3, []int{3}},
+		}, func(l, r KeyTree[int, int]) bool {
+			return l.Key == r.Key && slices.Equal(l.Vals, r.Vals)
+		}) {
+			t.Fatal("KeyTree didn't work:", keyTree)
+		}
+
+		for range fmm.All() {
+			break
+		}
+		for range fmm.Keys() {
+			break
+		}
+		for range fmm.Values() {
+			break
+		}
+		for range fmm.Submap(1) {
+			break
+		}
+	}
+}
+
+// The benchmarks compare MapMapAny with an unindexed and an indexed
+// FlatMapMap. "sparse" maps have one second key per first key, where
+// FlatMapMap should win, and "dense" maps have 100, where MapMapAny
+// should.
+
+type benchMapMap interface {
+	MutableMapMap[int, int, int]
+	Submap(key1 int) iter.Seq2[int, int]
+}
+
+type benchMapMapAny struct {
+	MapMapAny[int, int, int]
+}
+
+func (mma benchMapMapAny) Submap(key1 int) iter.Seq2[int, int] {
+	return maps.All(mma.MapMapAny[key1])
+}
+
+const benchMapMapSize = 10000
+
+var benchMapMapLayouts = []struct {
+	name string
+	make func() benchMapMap
+}{
+	{"MapMapAny", func() benchMapMap {
+		return benchMapMapAny{MapMapAny[int, int, int]{}}
+	}},
+	{"FlatMapMap", func() benchMapMap { return &FlatMapMap[int, int, int]{} }},
+	{"IndexedFlatMapMap", func() benchMapMap {
+		return NewIndexedFlatMapMap[int, int, int]()
+	}},
+}
+
+var benchMapMapShapes = []struct {
+	name    string
+	perKey1 int
+}{
+	{"sparse", 1},
+	{"dense", 100},
+}
+
+func benchMapMapKey(i, perKey1 int) Tuple2[int, int] {
+	return Tuple2[int, int]{i / perKey1, i % perKey1}
+}
+
+func benchMapMaps(b *testing.B, f func(b *testing.B, mm benchMapMap, perKey1 int)) {
+	for _, layout := range benchMapMapLayouts {
+		for _, shape := range benchMapMapShapes {
+			b.Run(layout.name+"/"+shape.name, func(b *testing.B) {
+				mm := layout.make()
+				for i := range benchMapMapSize {
+					mm.SetByTuple(benchMapMapKey(i, shape.perKey1), i)
+				}
+				b.ReportAllocs()
+				b.ResetTimer()
+				f(b, mm, shape.perKey1)
+			})
+		}
+	}
+}
+
+func BenchmarkMapMapBuild(b *testing.B) {
+	for _, layout := range benchMapMapLayouts {
+		for _, shape := range benchMapMapShapes {
+			b.Run(layout.name+"/"+shape.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for range b.N {
+					mm := layout.make()
+					for i := range benchMapMapSize {
+						mm.SetByTuple(benchMapMapKey(i, shape.perKey1), i)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkMapMapGet(b *testing.B) {
+	benchMapMaps(b, func(b *testing.B, mm benchMapMap, perKey1 int) {
+		for n := range b.N {
+			mm.GetByTuple(benchMapMapKey(n%benchMapMapSize, perKey1))
+		}
+	})
+}
+
+func BenchmarkMapMapAll(b *testing.B) {
+	benchMapMaps(b, func(b *testing.B, mm benchMapMap, _ int) {
+		for range b.N {
+			for range mm.All() {
+			}
+		}
+	})
+}
+
+func BenchmarkMapMapSubmap(b *testing.B) {
+	benchMapMaps(b, func(b *testing.B, mm benchMapMap, perKey1 int) {
+		key1s := benchMapMapSize / perKey1
+		for n := range b.N {
+			for range mm.Submap(n % key1s) {
+			}
+		}
+	})
+}
+
+func BenchmarkMapMapDeleteAndSet(b *testing.B) {
+	benchMapMaps(b, func(b *testing.B, mm benchMapMap, perKey1 int) {
+		for n := range b.N {
+			key := benchMapMapKey(n%benchMapMapSize, perKey1)
+			mm.DeleteByTuple(key)
+			mm.SetByTuple(key, n)
+		}
+	})
+}
@@ -1,6 +1,6 @@
 module github.com/thejerf/cm
 
-go 1.23
+go 1.24
 
 require golang.org/x/exp v0.0.0-20220309201404-e19e41d68951
 
@@ -0,0 +1,266 @@
+/*
+Package graph implements graph algorithms directly on the maps of the cm
+package.
+
+A cm.MapSet[N, N] is an adjacency-set representation of a directed
+graph: the set stored under a node is the set of nodes it has edges to.
+A cm.MapMapAny[N, N, W] is the weighted equivalent, with the weight of
+the edge from a to b stored at [a][b].
+
+A node exists in the graph if it is a key of the map, or if any node has
+an edge to it. There is no need to add nodes with no outgoing edges as
+keys, though it is harmless to do so, and is the only way to include a
+node with no edges at all.
+
+The functions in this package never modify the graphs passed to them.
+As with Go maps, the order that nodes are visited in among the
+successors of a single node is unspecified.
+*/
+package graph
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+
+	"github.com/thejerf/cm"
+)
+
+// CycleError is returned by the functions that require an acyclic graph
+// when they find a cycle. Cycle lists the nodes in the cycle, beginning
+// and ending with the same node.
+type CycleError[N comparable] struct {
+	Cycle []N
+}
+
+// Error implements the error interface.
+func (ce *CycleError[N]) Error() string {
+	nodes := make([]string, len(ce.Cycle))
+	for i, node := range ce.Cycle {
+		nodes[i] = fmt.Sprint(node)
+	}
+	return "graph contains a cycle: " + strings.Join(nodes, " -> ")
+}
+
+// BFS returns an iterator over the nodes reachable from the start node,
+// including the start node itself, in breadth-first order.
+func BFS[N comparable](g cm.MapSet[N, N], start N) iter.Seq[N] {
+	return func(yield func(N) bool) {
+		seen := cm.Set[N]{start: {}}
+		queue := []N{start}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			if !yield(node) {
+				return
+			}
+			for next := range g[node] {
+				if !seen.Contains(next) {
+					seen.Add(next)
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+}
+
+// DFS returns an iterator over the nodes reachable from the start node,
+// including the start node itself, in depth-first preorder.
+func DFS[N comparable](g cm.MapSet[N, N], start N) iter.Seq[N] {
+	return func(yield func(N) bool) {
+		seen := cm.Set[N]{}
+		stack := []N{start}
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen.Contains(node) {
+				continue
+			}
+			seen.Add(node)
+			if !yield(node) {
+				return
+			}
+			for next := range g[node] {
+				if !seen.Contains(next) {
+					stack = append(stack, next)
+				}
+			}
+		}
+	}
+}
+
+// TopoSort returns the nodes of the graph in a topological order, so
+// that every node comes before all the nodes it has edges to.
+//
+// If the graph has a cycle, a *CycleError containing one of the cycles
+// is returned instead.
+func TopoSort[N comparable](g cm.MapSet[N, N]) ([]N, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[N]int{}
+	order := []N{}
+	var path []N
+
+	var visit func(N) error
+	visit = func(node N) error {
+		state[node] = visiting
+		path = append(path, node)
+		for next := range g[node] {
+			switch state[next] {
+			case visiting:
+				cycle := slices.Clone(path[slices.Index(path, next):])
+				return &CycleError[N]{append(cycle, next)}
+			case unvisited:
+				if err := visit(next); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = visited
+		order = append(order, node)
+		return nil
+	}
+
+	for node := range g {
+		if state[node] == unvisited {
+			if err := visit(node); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	slices.Reverse(order)
+	return order, nil
+}
+
+// StronglyConnectedComponents returns the strongly connected components
+// of the graph: the maximal sets of nodes where every node in the set can
+// reach every other. Every node is in exactly one component, so a node
+// that is not part of any cycle is a component by itself.
+//
+// The components are returned in reverse topological order of the graph
+// formed by collapsing each component into a single node, so no
+// component has an edge to a component after it.
+func StronglyConnectedComponents[N comparable](g cm.MapSet[N, N]) [][]N {
+	// This is Tarjan's algorithm.
+	type nodeInfo struct {
+		index   int
+		lowLink int
+		onStack bool
+	}
+	info := map[N]*nodeInfo{}
+	var stack []N
+	var components [][]N
+
+	var connect func(N)
+	connect = func(node N) {
+		ni := &nodeInfo{len(info), len(info), true}
+		info[node] = ni
+		stack = append(stack, node)
+
+		for next := range g[node] {
+			nextInfo := info[next]
+			switch {
+			case nextInfo == nil:
+				connect(next)
+				ni.lowLink = min(ni.lowLink, info[next].lowLink)
+			case nextInfo.onStack:
+				ni.lowLink = min(ni.lowLink, nextInfo.index)
+			}
+		}
+
+		if ni.lowLink == ni.index {
+			var component []N
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				info[top].onStack = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for node := range g {
+		if info[node] == nil {
+			connect(node)
+		}
+	}
+	return components
+}
+
+// TransitiveClosure returns a new graph with an edge from a to b for
+// every pair where b can be reached from a by following one or more
+// edges. A node only has an edge to itself if it is part of a cycle.
+func TransitiveClosure[N comparable](g cm.MapSet[N, N]) cm.MapSet[N, N] {
+	closure := cm.MapSet[N, N]{}
+	for node := range g {
+		reachable := reachableFrom(g, node)
+		if len(reachable) > 0 {
+			closure[node] = reachable
+		}
+	}
+	return closure
+}
+
+// TransitiveReduction returns a new graph with the fewest edges that has
+// the same transitive closure as the passed-in graph, by removing every
+// edge from a to b where b can also be reached from a by a longer path.
+//
+// The transitive reduction is only unique for acyclic graphs, so if the
+// graph has a cycle, a *CycleError is returned instead.
+func TransitiveReduction[N comparable](
+	g cm.MapSet[N, N],
+) (cm.MapSet[N, N], error) {
+	if _, err := TopoSort(g); err != nil {
+		return nil, err
+	}
+
+	closure := TransitiveClosure(g)
+	reduction := cm.MapSet[N, N]{}
+	for node, successors := range g {
+		for next := range successors {
+			redundant := false
+			for other := range successors {
+				if other != next && closure[other].Contains(next) {
+					redundant = true
+					break
+				}
+			}
+			if !redundant {
+				reduction.Add(node, next)
+			}
+		}
+	}
+	return reduction, nil
+}
+
+// reachableFrom returns the set of nodes reachable from the start node
+// by following one or more edges. This only includes the start node if
+// it is part of a cycle.
+func reachableFrom[N comparable](g cm.MapSet[N, N], start N) cm.Set[N] {
+	reachable := cm.Set[N]{}
+	stack := g[start].AsSlice()
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable.Contains(node) {
+			continue
+		}
+		reachable.Add(node)
+		for next := range g[node] {
+			if !reachable.Contains(next) {
+				stack = append(stack, next)
+			}
+		}
+	}
+	return reachable
+}
@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/thejerf/cm"
+)
+
+// edge is a directed edge, from the first node to the second.
+type edge [2]string
+
+// graphOf builds a graph from a list of edges.
+func graphOf(edges ...edge) cm.MapSet[string, string] {
+	g := cm.MapSet[string, string]{}
+	for _, edge := range edges {
+		g.Add(edge[0], edge[1])
+	}
+	return g
+}
+
+func TestTraversal(t *testing.T) {
+	// a -> b -> d
+	//  \-> c -/
+	// with a cycle d -> a, and an unreachable e.
+	g := graphOf(
+		edge{"a", "b"}, edge{"a", "c"}, edge{"b", "d"}, edge{"c", "d"},
+		edge{"d", "a"}, edge{"e", "a"},
+	)
+
+	bfs := slices.Collect(BFS(g, "a"))
+	if len(bfs) != 4 || bfs[0] != "a" || bfs[3] != "d" {
+		t.Fatalf("incorrect BFS: %v", bfs)
+	}
+
+	dfs := slices.Collect(DFS(g, "a"))
+	if len(dfs) != 4 || dfs[0] != "a" {
+		t.Fatalf("incorrect DFS: %v", dfs)
+	}
+	// in preorder, d immediately follows whichever of b or c is first.
+	if dfs[2] != "d" {
+		t.Fatalf("DFS isn't depth first: %v", dfs)
+	}
+
+	// b and c are both pushed twice, whichever is visited first.
+	twice := graphOf(edge{"a", "b"}, edge{"a", "c"}, edge{"b", "c"}, edge{"c", "b"})
+	if len(slices.Collect(DFS(twice, "a"))) != 3 {
+		t.Fatal("DFS visited a node twice")
+	}
+
+	for range BFS(g, "a") {
+		break
+	}
+	for range DFS(g, "a") {
+		break
+	}
+
+	if !reflect.DeepEqual(slices.Collect(BFS(g, "z")), []string{"z"}) ||
+		!reflect.DeepEqual(slices.Collect(DFS(g, "z")), []string{"z"}) {
+		t.Fatal("isolated start node isn't yielded")
+	}
+}
+
+func TestTopoSort(t *testing.T) {
+	g := graphOf(
+		edge{"shirt", "tie"}, edge{"tie", "jacket"}, edge{"pants", "shoes"},
+		edge{"pants", "belt"}, edge{"belt", "jacket"}, edge{"shirt", "belt"},
+		edge{"socks", "shoes"},
+	)
+	g["watch"] = cm.Set[string]{}
+
+	order, err := TopoSort(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 8 {
+		t.Fatalf("incorrect node count: %v", order)
+	}
+	for from, successors := range g {
+		for to := range successors {
+			if slices.Index(order, from) > slices.Index(order, to) {
+				t.Fatalf("%s is after %s in %v", from, to, order)
+			}
+		}
+	}
+
+	g.Add("jacket", "shirt")
+	_, err = TopoSort(g)
+	var cycleErr *CycleError[string]
+	if !errors.As(err, &cycleErr) {
+		t.Fatal("cycle wasn't reported")
+	}
+	cycle := cycleErr.Cycle
+	if cycle[0] != cycle[len(cycle)-1] || len(cycle) < 4 {
+		t.Fatalf("incorrect cycle: %v", cycle)
+	}
+	for i := range len(cycle) - 1 {
+		if !g[cycle[i]].Contains(cycle[i+1]) {
+			t.Fatalf("cycle isn't a path in the graph: %v", cycle)
+		}
+	}
+
+	self := graphOf(edge{"a", "a"})
+	_, err = TopoSort(self)
+	if err == nil || err.Error() != "graph contains a cycle: a -> a" {
+		t.Fatalf("incorrect self-cycle error: %v", err)
+	}
+}
+
+func TestStronglyConnectedComponents(t *testing.T) {
+	g := graphOf(
+		edge{"a", "b"}, edge{"b", "c"}, edge{"c", "a"},
+		edge{"c", "d"}, edge{"d", "e"}, edge{"e", "d"},
+		edge{"e", "f"},
+	)
+
+	components := StronglyConnectedComponents(g)
+	for _, component := range components {
+		slices.Sort(component)
+	}
+	// reverse topological order is fully determined for this graph.
+	if !reflect.DeepEqual(components, [][]string{
+		{"f"}, {"d", "e"}, {"a", "b", "c"},
+	}) {
+		t.Fatalf("incorrect components: %v", components)
+	}
+
+	if StronglyConnectedComponents(cm.MapSet[int, int]{}) != nil {
+		t.Fatal("empty graph has components")
+	}
+}
+
+func TestTransitive(t *testing.T) {
+	g := graphOf(
+		edge{"a", "b"}, edge{"b", "c"}, edge{"a", "c"}, edge{"c", "d"},
+		edge{"a", "d"},
+	)
+	g["e"] = cm.Set[string]{}
+
+	closure := TransitiveClosure(g)
+	expected := graphOf(
+		edge{"a", "b"}, edge{"a", "c"}, edge{"a", "d"},
+		edge{"b", "c"}, edge{"b", "d"}, edge{"c", "d"},
+	)
+	if !reflect.DeepEqual(closure, expected) {
+		t.Fatalf("incorrect closure: %v", closure)
+	}
+
+	reduction, err := TransitiveReduction(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = graphOf(edge{"a", "b"}, edge{"b", "c"}, edge{"c", "d"})
+	if !reflect.DeepEqual(reduction, expected) {
+		t.Fatalf("incorrect reduction: %v", reduction)
+	}
+
+	cyclic := graphOf(edge{"a", "b"}, edge{"b", "a"})
+	if !reflect.DeepEqual(TransitiveClosure(cyclic), graphOf(
+		edge{"a", "a"}, edge{"a", "b"}, edge{"b", "a"}, edge{"b", "b"},
+	)) {
+		t.Fatal("incorrect closure of a cycle")
+	}
+	_, err = TransitiveReduction(cyclic)
+	if err == nil {
+		t.Fatal("could reduce a cyclic graph")
+	}
+}
@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"container/heap"
+	"errors"
+	"slices"
+
+	"github.com/thejerf/cm"
+)
+
+// Weight is the constraint on the edge weights of a weighted graph.
+type Weight interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// ErrNegativeWeight is returned by ShortestPaths if it encounters an
+// edge with a negative weight, which Dijkstra's algorithm can not
+// handle.
+var ErrNegativeWeight = errors.New("graph has an edge with a negative weight")
+
+// Paths holds the shortest paths from a single start node to every node
+// reachable from it, as computed by ShortestPaths.
+type Paths[N comparable, W Weight] struct {
+	Start N
+
+	dist map[N]W
+	prev map[N]N
+}
+
+// ShortestPaths computes the shortest paths from the start node to every
+// node reachable from it in the weighted graph, using Dijkstra's
+// algorithm.
+//
+// Only the edges reachable from the start node are examined. If any of
+// them has a negative weight, ErrNegativeWeight is returned.
+func ShortestPaths[N comparable, W Weight](
+	g cm.MapMapAny[N, N, W],
+	start N,
+) (*Paths[N, W], error) {
+	paths := &Paths[N, W]{
+		Start: start,
+		dist:  map[N]W{start: 0},
+		prev:  map[N]N{},
+	}
+	done := cm.Set[N]{}
+	queue := &distQueue[N, W]{{start, 0}}
+
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(distItem[N, W])
+		if done.Contains(item.node) {
+			continue
+		}
+		done.Add(item.node)
+
+		for next, weight := range g[item.node] {
+			if weight < 0 {
+				return nil, ErrNegativeWeight
+			}
+			dist := item.dist + weight
+			if oldDist, seen := paths.dist[next]; !seen || dist < oldDist {
+				paths.dist[next] = dist
+				paths.prev[next] = item.node
+				heap.Push(queue, distItem[N, W]{next, dist})
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// Distance returns the total weight of the shortest path from the start
+// node to the given node. The second value is false if the node can not
+// be reached from the start node.
+func (p *Paths[N, W]) Distance(to N) (W, bool) {
+	dist, exists := p.dist[to]
+	return dist, exists
+}
+
+// PathTo returns the nodes on the shortest path from the start node to
+// the given node, including both ends. If the node can not be reached
+// from the start node, it returns nil.
+func (p *Paths[N, W]) PathTo(to N) []N {
+	if _, exists := p.dist[to]; !exists {
+		return nil
+	}
+
+	path := []N{to}
+	for to != p.Start {
+		to = p.prev[to]
+		path = append(path, to)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+type distItem[N comparable, W Weight] struct {
+	node N
+	dist W
+}
+
+// distQueue implements heap.Interface as a min-heap on the distance.
+type distQueue[N comparable, W Weight] []distItem[N, W]
+
+func (dq distQueue[N, W]) Len() int           { return len(dq) }
+func (dq distQueue[N, W]) Less(i, j int) bool { return dq[i].dist < dq[j].dist }
+func (dq distQueue[N, W]) Swap(i, j int)      { dq[i], dq[j] = dq[j], dq[i] }
+
+func (dq *distQueue[N, W]) Push(x any) {
+	*dq = append(*dq, x.(distItem[N, W]))
+}
+
+func (dq *distQueue[N, W]) Pop() any {
+	old := *dq
+	item := old[len(old)-1]
+	*dq = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/thejerf/cm"
+)
+
+func TestShortestPaths(t *testing.T) {
+	g := cm.MapMapAny[string, string, float64]{}
+	g.Set("a", "b", 7)
+	g.Set("a", "c", 9)
+	g.Set("a", "f", 14)
+	g.Set("b", "c", 10)
+	g.Set("b", "d", 15)
+	g.Set("c", "d", 11)
+	g.Set("c", "f", 2)
+	g.Set("d", "e", 6)
+	g.Set("e", "f", 9)
+	g.Set("f", "e", 9)
+	g.Set("f", "a", 0)
+	g.Set("z", "a", 1)
+
+	paths, err := ShortestPaths(g, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for node, expected := range map[string]float64{
+		"a": 0, "b": 7, "c": 9, "d": 20, "e": 20, "f": 11,
+	} {
+		dist, reachable := paths.Distance(node)
+		if dist != expected || !reachable {
+			t.Fatalf("incorrect distance to %s: %v", node, dist)
+		}
+	}
+	if _, reachable := paths.Distance("z"); reachable {
+		t.Fatal("unreachable node is reachable")
+	}
+
+	if !reflect.DeepEqual(paths.PathTo("e"), []string{"a", "c", "f", "e"}) {
+		t.Fatalf("incorrect path: %v", paths.PathTo("e"))
+	}
+	if !reflect.DeepEqual(paths.PathTo("a"), []string{"a"}) {
+		t.Fatal("incorrect path to the start")
+	}
+	if paths.PathTo("z") != nil {
+		t.Fatal("path to unreachable node isn't nil")
+	}
+
+	g.Set("d", "z", -1)
+	_, err = ShortestPaths(g, "a")
+	if !errors.Is(err, ErrNegativeWeight) {
+		t.Fatal("negative weight wasn't reported")
+	}
+	// but it isn't found if it isn't reachable
+	g.Set("y", "x", 1)
+	_, err = ShortestPaths(g, "y")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
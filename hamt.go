@@ -0,0 +1,283 @@
+package cm
+
+import (
+	"hash/maphash"
+	"iter"
+	"math/bits"
+	"slices"
+)
+
+// This file implements the hash array mapped trie underlying the
+// Persistent types.
+//
+// Each node of the trie consumes hamtBits bits of the key's hash to
+// select one of up to 32 slots, and stores only its populated slots,
+// with a bitmap recording which those are. A slot holds either a child
+// node or a bucket of the entries whose full hash is that slot's hash.
+// The bucket will almost always contain one entry; it only contains more
+// when the full 64-bit hashes of distinct keys collide. Since two keys
+// with different hashes will differ in some group of hamtBits bits, a
+// bucket is only ever split into a child node when a key with a
+// different hash lands in its slot, and never needs to run out of hash.
+//
+// All updates copy the nodes along the path to the changed entry,
+// sharing everything else with the previous version. Transient updates
+// carry a *hamtOwner; nodes created under that owner may be modified in
+// place by later updates with the same owner, since no persistent version
+// can be referring to them yet. Once a persistent version is taken from
+// the transient, the transient must switch to a new owner.
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+var hamtSeed = maphash.MakeSeed()
+
+// hamtOwner identifies a transient. It must not be zero-sized, so that
+// distinct owners have distinct addresses.
+type hamtOwner struct {
+	_ byte
+}
+
+type hamtLeaf[K comparable, V any] struct {
+	key K
+	val V
+}
+
+// hamtEntry is a slot of a hamtNode. If child is nil, it is a bucket of
+// leaves that all have the given hash.
+type hamtEntry[K comparable, V any] struct {
+	child  *hamtNode[K, V]
+	hash   uint64
+	leaves []hamtLeaf[K, V]
+}
+
+type hamtNode[K comparable, V any] struct {
+	owner   *hamtOwner
+	bitmap  uint32
+	entries []hamtEntry[K, V]
+}
+
+// hamt is a persistent map. The zero value is an empty map. As a value
+// type, a hamt is itself immutable; the update methods return a new one.
+type hamt[K comparable, V any] struct {
+	root *hamtNode[K, V]
+	size int
+}
+
+func hamtSlot(hash uint64, shift uint, bitmap uint32) (bit uint32, pos int) {
+	bit = 1 << ((hash >> shift) & hamtMask)
+	return bit, bits.OnesCount32(bitmap & (bit - 1))
+}
+
+// editable returns a node that the given owner may modify: the node
+// itself if the owner already owns it, or a copy otherwise.
+func (n *hamtNode[K, V]) editable(owner *hamtOwner) *hamtNode[K, V] {
+	if owner != nil && n.owner == owner {
+		return n
+	}
+	return &hamtNode[K, V]{
+		owner:   owner,
+		bitmap:  n.bitmap,
+		entries: slices.Clone(n.entries),
+	}
+}
+
+func (h hamt[K, V]) get(key K) (val V, exists bool) {
+	return h.root.get(0, hashOf(hamtSeed, key), key)
+}
+
+// with returns a hamt with the key set to the value. A nil owner makes a
+// persistent update.
+func (h hamt[K, V]) with(key K, val V, owner *hamtOwner) hamt[K, V] {
+	root := h.root
+	if root == nil {
+		root = &hamtNode[K, V]{owner: owner}
+	}
+	root, added := root.with(owner, 0, hashOf(hamtSeed, key), key, val)
+	if added {
+		h.size++
+	}
+	h.root = root
+	return h
+}
+
+// without returns a hamt without the key. A nil owner makes a persistent
+// update.
+func (h hamt[K, V]) without(key K, owner *hamtOwner) hamt[K, V] {
+	if h.root == nil {
+		return h
+	}
+	root, removed := h.root.without(owner, 0, hashOf(hamtSeed, key), key)
+	if !removed {
+		return h
+	}
+	h.size--
+	if h.size == 0 {
+		root = nil
+	}
+	h.root = root
+	return h
+}
+
+func (h hamt[K, V]) all() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if h.root != nil {
+			h.root.all(yield)
+		}
+	}
+}
+
+func (n *hamtNode[K, V]) get(shift uint, hash uint64, key K) (val V, exists bool) {
+	for ; n != nil; shift += hamtBits {
+		bit, pos := hamtSlot(hash, shift, n.bitmap)
+		if n.bitmap&bit == 0 {
+			break
+		}
+		e := &n.entries[pos]
+		if e.child != nil {
+			n = e.child
+			continue
+		}
+		if e.hash == hash {
+			for _, leaf := range e.leaves {
+				if leaf.key == key {
+					return leaf.val, true
+				}
+			}
+		}
+		break
+	}
+	return val, false
+}
+
+func (n *hamtNode[K, V]) with(
+	owner *hamtOwner,
+	shift uint,
+	hash uint64,
+	key K,
+	val V,
+) (*hamtNode[K, V], bool) {
+	bit, pos := hamtSlot(hash, shift, n.bitmap)
+
+	if n.bitmap&bit == 0 {
+		m := n.editable(owner)
+		m.bitmap |= bit
+		m.entries = slices.Insert(m.entries, pos, hamtEntry[K, V]{
+			hash:   hash,
+			leaves: []hamtLeaf[K, V]{{key, val}},
+		})
+		return m, true
+	}
+
+	e := n.entries[pos]
+	switch {
+	case e.child != nil:
+		child, added := e.child.with(owner, shift+hamtBits, hash, key, val)
+		m := n.editable(owner)
+		m.entries[pos].child = child
+		return m, added
+
+	case e.hash == hash:
+		leaves := slices.Clone(e.leaves)
+		added := true
+		for i := range leaves {
+			if leaves[i].key == key {
+				leaves[i].val = val
+				added = false
+				break
+			}
+		}
+		if added {
+			leaves = append(leaves, hamtLeaf[K, V]{key, val})
+		}
+		m := n.editable(owner)
+		m.entries[pos].leaves = leaves
+		return m, added
+
+	default:
+		// Push the existing bucket down into a new child, then add the
+		// new key into that child.
+		childBit, _ := hamtSlot(e.hash, shift+hamtBits, 0)
+		child := &hamtNode[K, V]{
+			owner:   owner,
+			bitmap:  childBit,
+			entries: []hamtEntry[K, V]{e},
+		}
+		child, _ = child.with(owner, shift+hamtBits, hash, key, val)
+		m := n.editable(owner)
+		m.entries[pos] = hamtEntry[K, V]{child: child}
+		return m, true
+	}
+}
+
+func (n *hamtNode[K, V]) without(
+	owner *hamtOwner,
+	shift uint,
+	hash uint64,
+	key K,
+) (*hamtNode[K, V], bool) {
+	bit, pos := hamtSlot(hash, shift, n.bitmap)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+
+	e := n.entries[pos]
+	if e.child != nil {
+		child, removed := e.child.without(owner, shift+hamtBits, hash, key)
+		if !removed {
+			return n, false
+		}
+		// Children always hold at least two keys, so the child can not
+		// be empty now. If it is down to a single bucket, it collapses
+		// back into this node, keeping the trie as shallow as possible.
+		m := n.editable(owner)
+		if len(child.entries) == 1 && child.entries[0].child == nil {
+			m.entries[pos] = child.entries[0]
+		} else {
+			m.entries[pos].child = child
+		}
+		return m, true
+	}
+
+	if e.hash != hash {
+		return n, false
+	}
+	idx := slices.IndexFunc(e.leaves, func(leaf hamtLeaf[K, V]) bool {
+		return leaf.key == key
+	})
+	if idx == -1 {
+		return n, false
+	}
+
+	m := n.editable(owner)
+	if len(e.leaves) == 1 {
+		m.removeSlot(bit, pos)
+	} else {
+		m.entries[pos].leaves = slices.Delete(slices.Clone(e.leaves), idx, idx+1)
+	}
+	return m, true
+}
+
+func (n *hamtNode[K, V]) removeSlot(bit uint32, pos int) {
+	n.bitmap &^= bit
+	n.entries = slices.Delete(n.entries, pos, pos+1)
+}
+
+func (n *hamtNode[K, V]) all(yield func(K, V) bool) bool {
+	for _, e := range n.entries {
+		if e.child != nil {
+			if !e.child.all(yield) {
+				return false
+			}
+			continue
+		}
+		for _, leaf := range e.leaves {
+			if !yield(leaf.key, leaf.val) {
+				return false
+			}
+		}
+	}
+	return true
+}
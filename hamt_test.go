@@ -0,0 +1,191 @@
+package cm
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// depth returns the maximum depth of the trie under the node.
+func (n *hamtNode[K, V]) depth() int {
+	d := 0
+	for _, e := range n.entries {
+		if e.child != nil {
+			d = max(d, e.child.depth())
+		}
+	}
+	return d + 1
+}
+
+func TestHAMTCollisions(t *testing.T) {
+	// Exercise the trie with hand-picked hashes: a and b collide
+	// completely, c differs from them only in the last bits of the hash,
+	// forcing a chain of children all the way down.
+	const (
+		hashAB = uint64(0x0123456789abcdef)
+		hashC  = hashAB ^ (1 << 63)
+	)
+
+	var owner *hamtOwner
+	root := &hamtNode[string, int]{}
+	root, added := root.with(owner, 0, hashAB, "a", 1)
+	if !added {
+		t.Fatal("a not added")
+	}
+	root, added = root.with(owner, 0, hashAB, "b", 2)
+	if !added || root.depth() != 1 || len(root.entries[0].leaves) != 2 {
+		t.Fatal("b not added to a's bucket")
+	}
+	root, added = root.with(owner, 0, hashAB, "b", 3)
+	if added {
+		t.Fatal("replacing b added it")
+	}
+	withoutC := root
+	root, added = root.with(owner, 0, hashC, "c", 4)
+	if !added || root.depth() != 13 {
+		t.Fatalf("c not added to the bottom of the trie: %d", root.depth())
+	}
+	if withoutC.depth() != 1 {
+		t.Fatal("adding c modified the previous version")
+	}
+
+	for _, test := range []struct {
+		hash uint64
+		key  string
+		val  int
+	}{{hashAB, "a", 1}, {hashAB, "b", 3}, {hashC, "c", 4}} {
+		val, exists := root.get(0, test.hash, test.key)
+		if val != test.val || !exists {
+			t.Fatalf("couldn't get %s", test.key)
+		}
+	}
+	if _, exists := root.get(0, hashAB, "c"); exists {
+		t.Fatal("found c in the wrong bucket")
+	}
+	if _, exists := root.get(0, hashAB^(1<<62), "c"); exists {
+		t.Fatal("found c with the wrong hash")
+	}
+
+	// removing things that aren't there changes nothing
+	for _, test := range []struct {
+		hash uint64
+		key  string
+	}{{hashAB, "z"}, {hashAB ^ (1 << 62), "a"}, {hashAB ^ 1, "a"}} {
+		same, removed := root.without(owner, 0, test.hash, test.key)
+		if removed || same != root {
+			t.Fatal("removed something that isn't there")
+		}
+	}
+
+	withC := root
+	root, _ = root.without(owner, 0, hashC, "c")
+	if root.depth() != 1 {
+		t.Fatal("removing c didn't collapse the chain")
+	}
+	if withC.depth() != 13 {
+		t.Fatal("removing c modified the previous version")
+	}
+	root, _ = root.without(owner, 0, hashAB, "a")
+	if len(root.entries[0].leaves) != 1 || root.entries[0].leaves[0].key != "b" {
+		t.Fatal("removing a from the bucket didn't work")
+	}
+	root, _ = root.without(owner, 0, hashAB, "b")
+	if len(root.entries) != 0 {
+		t.Fatal("removing b didn't empty the trie")
+	}
+}
+
+func TestHAMTTransient(t *testing.T) {
+	owner := &hamtOwner{}
+	var h hamt[int, int]
+	h = h.with(1, 1, owner)
+	root := h.root
+	h = h.with(2, 2, owner)
+	if h.root != root {
+		t.Fatal("owned node wasn't modified in place")
+	}
+
+	persistent := h
+	h = h.with(3, 3, nil)
+	if h.root == root || persistent.size != 2 {
+		t.Fatal("persistent update modified the node")
+	}
+
+	other := h.with(4, 4, &hamtOwner{})
+	if other.root == h.root {
+		t.Fatal("a different owner modified the node in place")
+	}
+}
+
+func TestHAMTRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var h hamt[int, int]
+	model := map[int]int{}
+
+	type snapshot struct {
+		h     hamt[int, int]
+		model map[int]int
+	}
+	var snapshots []snapshot
+
+	owner := &hamtOwner{}
+	for i := range 5000 {
+		key := rng.Intn(1000)
+		if i%3 == 0 {
+			owner = &hamtOwner{}
+		}
+		if rng.Intn(3) == 0 {
+			h = h.without(key, owner)
+			delete(model, key)
+		} else {
+			h = h.with(key, i, owner)
+			model[key] = i
+		}
+
+		if i%500 == 0 {
+			snap := snapshot{h, map[int]int{}}
+			for k, v := range model {
+				snap.model[k] = v
+			}
+			snapshots = append(snapshots, snap)
+			// the snapshot must not be modified in place
+			owner = &hamtOwner{}
+		}
+	}
+	snapshots = append(snapshots, snapshot{h, model})
+
+	for _, snap := range snapshots {
+		if snap.h.size != len(snap.model) {
+			t.Fatal("incorrect size")
+		}
+		count := 0
+		for key, val := range snap.h.all() {
+			if snap.model[key] != val {
+				t.Fatal("incorrect value")
+			}
+			count++
+		}
+		if count != len(snap.model) {
+			t.Fatal("incorrect iteration")
+		}
+		for key := range 1000 {
+			val, exists := snap.h.get(key)
+			modelVal, modelExists := snap.model[key]
+			if val != modelVal || exists != modelExists {
+				t.Fatal("incorrect get")
+			}
+		}
+	}
+
+	for range h.all() {
+		break
+	}
+	for key := range model {
+		h = h.without(key, nil)
+	}
+	if h.root != nil || h.size != 0 {
+		t.Fatal("removing everything didn't empty the hamt")
+	}
+	if h.without(1, nil) != h {
+		t.Fatal("removing from an empty hamt changed it")
+	}
+}
@@ -0,0 +1,9 @@
+package cm
+
+import "hash/maphash"
+
+// hashOf hashes any comparable value, such that values that are == hash
+// the same.
+func hashOf[K comparable](seed maphash.Seed, k K) uint64 {
+	return maphash.Comparable(seed, k)
+}
@@ -0,0 +1,27 @@
+package cm
+
+import (
+	"hash/maphash"
+	"math"
+	"testing"
+)
+
+func TestHashOfEqualKeys(t *testing.T) {
+	negZero := math.Copysign(0, -1)
+	seed := maphash.MakeSeed()
+	if hashOf(seed, 0.0) != hashOf(seed, negZero) {
+		t.Fatal("positive and negative zero hash differently")
+	}
+
+	smm := NewSyncMapMap[float64, int, int](64)
+	smm.Set(0.0, 1, 1)
+	smm.Set(negZero, 1, 2)
+	if smm.Len() != 1 {
+		t.Fatal("equal keys became duplicate entries in a SyncMapMap")
+	}
+
+	ps := PersistentSet[float64]{}.With(0.0).With(negZero)
+	if ps.Len() != 1 {
+		t.Fatal("equal keys became duplicate entries in a PersistentSet")
+	}
+}
@@ -0,0 +1,251 @@
+package cm
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// A Relation is a set of rows that can be looked up by any number of
+// indexes, for when a DualMap's two keys aren't enough. Rows are
+// typically structs with several fields, any of which may be indexed.
+//
+// Indexes are declared with NewIndex and NewUniqueIndex, with a function
+// that extracts the index's key from a row. Every Insert and Delete on the
+// Relation updates all of its indexes, so they are always consistent with
+// each other and with the rows. A unique index allows at most one row per
+// key, and Insert will refuse a row that would violate one.
+//
+// As with DualMap, emptied buckets are cleaned up when rows are deleted,
+// so an index only contains keys that have at least one row.
+//
+// The extractor functions must be deterministic, and a row must not be
+// changed while it is in the Relation, just as a key must not be
+// changed while it is in a map.
+//
+// The zero-value of this struct is safe to use.
+type Relation[R comparable] struct {
+	rows    Set[R]
+	indexes []relationIndex[R]
+}
+
+// relationIndex is the interface the Relation uses to maintain its
+// indexes without knowing their key types.
+type relationIndex[R comparable] interface {
+	check(r R) error
+	insert(r R)
+	delete(r R)
+}
+
+// ErrUniqueConflict is the error wrapped by all UniqueConflict errors, so
+// that conflicts can be detected with errors.Is without knowing the row
+// type.
+var ErrUniqueConflict = errors.New("row conflicts with a unique index")
+
+// UniqueConflict is returned when inserting a row into a Relation would
+// give a unique index two rows with the same key. Existing is the row
+// already in the Relation with that key.
+type UniqueConflict[R comparable] struct {
+	Row      R
+	Existing R
+}
+
+// Error implements the error interface.
+func (uc *UniqueConflict[R]) Error() string {
+	return fmt.Sprintf("can not insert %v: unique index key is already used by %v",
+		uc.Row, uc.Existing)
+}
+
+// Unwrap returns ErrUniqueConflict.
+func (uc *UniqueConflict[R]) Unwrap() error {
+	return ErrUniqueConflict
+}
+
+// Insert adds the row to the Relation and all of its indexes. If the
+// row would violate a unique index, the Relation is left unchanged and a
+// *UniqueConflict is returned. Inserting a row that is already in the
+// Relation does nothing.
+func (rel *Relation[R]) Insert(r R) error {
+	if rel.rows.Contains(r) {
+		return nil
+	}
+	for _, index := range rel.indexes {
+		if err := index.check(r); err != nil {
+			return err
+		}
+	}
+
+	if rel.rows == nil {
+		rel.rows = Set[R]{}
+	}
+	rel.rows.Add(r)
+	for _, index := range rel.indexes {
+		index.insert(r)
+	}
+	return nil
+}
+
+// Delete removes the row from the Relation and all of its indexes. It
+// returns true if the row was in the Relation.
+func (rel *Relation[R]) Delete(r R) bool {
+	if !rel.rows.Contains(r) {
+		return false
+	}
+	rel.rows.Remove(r)
+	for _, index := range rel.indexes {
+		index.delete(r)
+	}
+	return true
+}
+
+// DeleteFunc deletes the rows for which the function returns true.
+func (rel *Relation[R]) DeleteFunc(f func(R) bool) {
+	for r := range rel.rows {
+		if f(r) {
+			rel.Delete(r)
+		}
+	}
+}
+
+// Contains returns true if the row is in the Relation.
+func (rel *Relation[R]) Contains(r R) bool {
+	return rel.rows.Contains(r)
+}
+
+// Len returns the number of rows in the Relation.
+func (rel *Relation[R]) Len() int {
+	return len(rel.rows)
+}
+
+// All returns an iterator over the rows in the Relation.
+//
+// As with maps, rows may be deleted from the Relation during iteration.
+func (rel *Relation[R]) All() iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for r := range rel.rows {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// An Index is an index on a Relation, created by NewIndex or
+// NewUniqueIndex. It is kept up to date by the Relation.
+type Index[R, K comparable] struct {
+	rel     *Relation[R]
+	extract func(R) K
+	unique  bool
+	rows    MapSet[K, R]
+}
+
+// NewIndex adds a non-unique index to the Relation, using the passed-in
+// function to extract the key of each row, and returns it. Any rows
+// already in the Relation are added to the index.
+func NewIndex[R, K comparable](rel *Relation[R], extract func(R) K) *Index[R, K] {
+	index := &Index[R, K]{
+		rel:     rel,
+		extract: extract,
+		rows:    MapSet[K, R]{},
+	}
+	for r := range rel.rows {
+		index.insert(r)
+	}
+	rel.indexes = append(rel.indexes, index)
+	return index
+}
+
+// NewUniqueIndex adds a unique index to the Relation, using the
+// passed-in function to extract the key of each row, and returns it. Any
+// rows already in the Relation are added to the index.
+//
+// If two rows already in the Relation have the same key, the index is
+// not added, and a *UniqueConflict is returned.
+func NewUniqueIndex[R, K comparable](
+	rel *Relation[R],
+	extract func(R) K,
+) (*Index[R, K], error) {
+	index := &Index[R, K]{
+		rel:     rel,
+		extract: extract,
+		unique:  true,
+		rows:    MapSet[K, R]{},
+	}
+	for r := range rel.rows {
+		if err := index.check(r); err != nil {
+			return nil, err
+		}
+		index.insert(r)
+	}
+	rel.indexes = append(rel.indexes, index)
+	return index, nil
+}
+
+func (index *Index[R, K]) check(r R) error {
+	if !index.unique {
+		return nil
+	}
+	for existing := range index.rows[index.extract(r)] {
+		return &UniqueConflict[R]{r, existing}
+	}
+	return nil
+}
+
+func (index *Index[R, K]) insert(r R) {
+	index.rows.Add(index.extract(r), r)
+}
+
+func (index *Index[R, K]) delete(r R) {
+	index.rows.Delete(index.extract(r), r)
+}
+
+// Lookup returns an iterator over the rows with the given key.
+//
+// As with maps, rows may be deleted from the Relation during iteration.
+func (index *Index[R, K]) Lookup(key K) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for r := range index.rows[key] {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
+// Get returns a row with the given key. This is intended for unique
+// indexes; on a non-unique index, which of the rows is returned is
+// unspecified. The second value is false if there are no rows with the
+// key.
+func (index *Index[R, K]) Get(key K) (r R, exists bool) {
+	for r = range index.rows[key] {
+		return r, true
+	}
+	return r, false
+}
+
+// Count returns the number of rows with the given key.
+func (index *Index[R, K]) Count(key K) int {
+	return len(index.rows[key])
+}
+
+// Keys returns an iterator over the keys in the index that have at least
+// one row.
+func (index *Index[R, K]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range index.rows {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Delete deletes every row with the given key from the Relation, and so
+// from all of its indexes. It returns the number of rows deleted.
+func (index *Index[R, K]) Delete(key K) int {
+	rows := index.rows[key].AsSlice()
+	for _, r := range rows {
+		index.rel.Delete(r)
+	}
+	return len(rows)
+}
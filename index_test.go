@@ -0,0 +1,121 @@
+package cm
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+type employee struct {
+	ID    int
+	Email string
+	Dept  string
+	Floor int
+}
+
+func TestRelation(t *testing.T) {
+	var rel Relation[employee]
+	alice := employee{1, "alice@example.com", "eng", 3}
+	bob := employee{2, "bob@example.com", "eng", 2}
+	carol := employee{3, "carol@example.com", "sales", 2}
+
+	if rel.Insert(alice) != nil || rel.Insert(bob) != nil {
+		t.Fatal("couldn't insert")
+	}
+
+	// indexes added after rows are inserted pick up the existing rows
+	byID, err := NewUniqueIndex(&rel, func(e employee) int { return e.ID })
+	if err != nil {
+		t.Fatal(err)
+	}
+	byEmail, _ := NewUniqueIndex(&rel, func(e employee) string { return e.Email })
+	byDept := NewIndex(&rel, func(e employee) string { return e.Dept })
+	byFloor := NewIndex(&rel, func(e employee) int { return e.Floor })
+
+	if rel.Insert(carol) != nil || rel.Insert(carol) != nil {
+		t.Fatal("couldn't insert")
+	}
+	if rel.Len() != 3 || !rel.Contains(carol) {
+		t.Fatal("incorrect rows")
+	}
+
+	if e, exists := byID.Get(2); e != bob || !exists {
+		t.Fatal("couldn't get by unique index")
+	}
+	if _, exists := byID.Get(9); exists {
+		t.Fatal("got a nonexistent row")
+	}
+	if e, _ := byEmail.Get("carol@example.com"); e != carol {
+		t.Fatal("couldn't get by a second unique index")
+	}
+	eng := slices.Collect(byDept.Lookup("eng"))
+	if len(eng) != 2 || !slices.Contains(eng, alice) || !slices.Contains(eng, bob) {
+		t.Fatal("incorrect Lookup")
+	}
+	if byFloor.Count(2) != 2 || byFloor.Count(9) != 0 {
+		t.Fatal("incorrect Count")
+	}
+	floors := slices.Collect(byFloor.Keys())
+	slices.Sort(floors)
+	if !slices.Equal(floors, []int{2, 3}) {
+		t.Fatal("incorrect Keys")
+	}
+
+	// a conflict on the second unique index leaves everything unchanged
+	dupe := employee{4, "bob@example.com", "ops", 1}
+	err = rel.Insert(dupe)
+	var conflict *UniqueConflict[employee]
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrUniqueConflict) ||
+		conflict.Existing != bob || conflict.Row != dupe {
+		t.Fatal("incorrect conflict")
+	}
+	if err.Error() == "" || rel.Len() != 3 || byID.Count(4) != 0 ||
+		byDept.Count("ops") != 0 {
+		t.Fatal("conflicting insert changed the relation")
+	}
+
+	// deleting through an index removes from every index
+	if byFloor.Delete(2) != 2 {
+		t.Fatal("incorrect count of deleted rows")
+	}
+	if rel.Len() != 1 || byID.Count(2) != 0 || byEmail.Count("carol@example.com") != 0 {
+		t.Fatal("Delete through an index didn't update everything")
+	}
+	if byDept.Count("sales") != 0 || len(byDept.rows) != 1 {
+		t.Fatal("emptied buckets weren't cleaned up")
+	}
+	if byFloor.Delete(2) != 0 || rel.Delete(carol) {
+		t.Fatal("deleted nonexistent rows")
+	}
+
+	rel.Insert(bob)
+	rel.Insert(carol)
+	rel.DeleteFunc(func(e employee) bool { return e.Dept == "eng" })
+	rows := slices.Collect(rel.All())
+	if !slices.Equal(rows, []employee{carol}) {
+		t.Fatal("incorrect DeleteFunc")
+	}
+	if slices.Collect(byDept.Lookup("eng")) != nil {
+		t.Fatal("DeleteFunc didn't update the indexes")
+	}
+
+	for range rel.All() {
+		break
+	}
+	rel.Insert(bob)
+	for range byFloor.Lookup(2) {
+		break
+	}
+	for range byFloor.Keys() {
+		break
+	}
+
+	// a unique index can't be added over conflicting rows
+	_, err = NewUniqueIndex(&rel, func(e employee) int { return e.Floor })
+	if !errors.Is(err, ErrUniqueConflict) {
+		t.Fatal("could add a unique index over conflicting rows")
+	}
+	if len(rel.indexes) != 4 {
+		t.Fatal("failed index was added anyhow")
+	}
+}
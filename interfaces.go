@@ -0,0 +1,299 @@
+package cm
+
+import "iter"
+
+// The interfaces in this file are satisfied by the types in this package
+// that share the corresponding methods, so that code can be written
+// against "any set" or "any 2-key map" and used with a Set or a
+// SortedSet, a MapMap or a SyncMapMap, and so on. The functions
+// following them are the generic algorithms written against them.
+//
+// The concrete types remain the better choice where they can be used;
+// a method like Set.Equal can use the representation directly, where
+// EqualSets can only use the interface.
+
+// A ReadSet is a set that can be queried. It is satisfied by Set,
+// PersistentSet, and the pointer types of SortedSet, BitSet and
+// RoaringSet.
+type ReadSet[M comparable] interface {
+	Contains(v M) bool
+	Len() int
+	All() iter.Seq[M]
+}
+
+// A MutableSet is a set that can be queried and modified. It is
+// satisfied by Set, and the pointer types of SortedSet, BitSet and
+// RoaringSet.
+type MutableSet[M comparable] interface {
+	ReadSet[M]
+	Add(v M)
+	Remove(v M)
+}
+
+// A MapMapView is a map with two keys that can be queried. It is
+// satisfied by MapMap, MapMapAny, PersistentMapMap, and the pointer types
+// of SortedMapMap, SyncMapMap, SyncMapMapAny, DualMap and FlatMapMap.
+type MapMapView[K1, K2 comparable, V any] interface {
+	GetByTuple(key Tuple2[K1, K2]) (V, bool)
+	Len() int
+	All() iter.Seq2[Tuple2[K1, K2], V]
+}
+
+// A MutableMapMap is a map with two keys that can be queried and
+// modified. It is satisfied by MapMap, MapMapAny, and the pointer types
+// of SortedMapMap, SyncMapMap, SyncMapMapAny, DualMap and FlatMapMap.
+type MutableMapMap[K1, K2 comparable, V any] interface {
+	MapMapView[K1, K2, V]
+	SetByTuple(key Tuple2[K1, K2], value V)
+	DeleteByTuple(key Tuple2[K1, K2])
+}
+
+// A MapSetView is a map of sets that can be queried, viewed as a set of
+// key and value pairs. It is satisfied by MapSet, RoaringMapSet,
+// PersistentMapSet, and the pointer types of SortedMapSet and
+// SyncMapSet.
+//
+// It does not include Len, as MapSet and RoaringMapSet are maps, for
+// which len counts keys rather than pairs.
+type MapSetView[K, V comparable] interface {
+	Contains(key K, val V) bool
+	All() iter.Seq2[K, V]
+}
+
+// A MutableMapSet is a map of sets that can be queried and modified. It
+// is satisfied by MapSet, RoaringMapSet, and the pointer type of
+// SortedMapSet.
+type MutableMapSet[K, V comparable] interface {
+	MapSetView[K, V]
+	Add(key K, val V)
+	Delete(key K, val V)
+}
+
+var (
+	_ MutableSet[int] = Set[int]{}
+	_ MutableSet[int] = (*SortedSet[int])(nil)
+	_ MutableSet[int] = (*BitSet[int])(nil)
+	_ MutableSet[int] = (*RoaringSet[int])(nil)
+	_ ReadSet[int]    = PersistentSet[int]{}
+
+	_ MutableMapMap[int, int, int] = MapMap[int, int, int]{}
+	_ MutableMapMap[int, int, int] = MapMapAny[int, int, int]{}
+	_ MutableMapMap[int, int, int] = (*SortedMapMap[int, int, int])(nil)
+	_ MutableMapMap[int, int, int] = (*SyncMapMap[int, int, int])(nil)
+	_ MutableMapMap[int, int, int] = (*SyncMapMapAny[int, int, int])(nil)
+	_ MutableMapMap[int, int, int] = (*DualMap[int, int, int])(nil)
+	_ MutableMapMap[int, int, int] = (*FlatMapMap[int, int, int])(nil)
+	_ MapMapView[int, int, int]    = PersistentMapMap[int, int, int]{}
+
+	_ MutableMapSet[int, int] = MapSet[int, int]{}
+	_ MutableMapSet[int, int] = RoaringMapSet[int, int]{}
+	_ MutableMapSet[int, int] = (*SortedMapSet[int, int])(nil)
+	_ MapSetView[int, int]    = PersistentMapSet[int, int]{}
+	_ MapSetView[int, int]    = (*SyncMapSet[int, int])(nil)
+)
+
+// EqualSets returns true if the two sets contain the same values.
+func EqualSets[M comparable](l, r ReadSet[M]) bool {
+	if l.Len() != r.Len() {
+		return false
+	}
+	for val := range l.All() {
+		if !r.Contains(val) {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffSets returns the differences between the old set and the new set,
+// as Set.Diff does.
+func DiffSets[M comparable](old, new ReadSet[M]) SetDiff[M] {
+	diff := SetDiff[M]{Added: Set[M]{}, Removed: Set[M]{}}
+	for val := range old.All() {
+		if !new.Contains(val) {
+			diff.Removed.Add(val)
+		}
+	}
+	for val := range new.All() {
+		if !old.Contains(val) {
+			diff.Added.Add(val)
+		}
+	}
+	return diff
+}
+
+// CopySet makes dst contain exactly the values in src, removing any
+// others it contains.
+func CopySet[M comparable](dst MutableSet[M], src ReadSet[M]) {
+	var remove []M
+	for val := range dst.All() {
+		if !src.Contains(val) {
+			remove = append(remove, val)
+		}
+	}
+	for _, val := range remove {
+		dst.Remove(val)
+	}
+	for val := range src.All() {
+		dst.Add(val)
+	}
+}
+
+// UnionSets adds all the values in each of the srcs to dst.
+func UnionSets[M comparable](dst MutableSet[M], srcs ...ReadSet[M]) {
+	for _, src := range srcs {
+		for val := range src.All() {
+			dst.Add(val)
+		}
+	}
+}
+
+// EqualMapMaps returns true if the two maps contain the same keys and
+// values.
+func EqualMapMaps[K1, K2, V comparable](l, r MapMapView[K1, K2, V]) bool {
+	return EqualMapMapsFunc(l, r, func(v1, v2 V) bool { return v1 == v2 })
+}
+
+// EqualMapMapsFunc returns true if the two maps contain the same keys,
+// using the passed-in function to compare the equality of values.
+func EqualMapMapsFunc[K1, K2 comparable, V any](
+	l, r MapMapView[K1, K2, V],
+	eq func(v1, v2 V) bool,
+) bool {
+	if l.Len() != r.Len() {
+		return false
+	}
+	for key, val := range l.All() {
+		rVal, exists := r.GetByTuple(key)
+		if !exists || !eq(val, rVal) {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffMapMaps returns the differences between the old map and the new
+// map, as MapMap.Diff does.
+func DiffMapMaps[K1, K2, V comparable](
+	old, new MapMapView[K1, K2, V],
+) MapDiff[Tuple2[K1, K2], V] {
+	return DiffMapMapsFunc(old, new, func(v1, v2 V) bool { return v1 == v2 })
+}
+
+// DiffMapMapsFunc returns the differences between the old map and the
+// new map, using the passed-in function to compare the equality of
+// values, as MapMapAny.DiffFunc does.
+func DiffMapMapsFunc[K1, K2 comparable, V any](
+	old, new MapMapView[K1, K2, V],
+	eq func(v1, v2 V) bool,
+) MapDiff[Tuple2[K1, K2], V] {
+	diff := newMapDiff[Tuple2[K1, K2], V]()
+	for key, val := range old.All() {
+		newVal, exists := new.GetByTuple(key)
+		switch {
+		case !exists:
+			diff.Removed[key] = val
+		case !eq(val, newVal):
+			diff.Changed[key] = Change[V]{val, newVal}
+		}
+	}
+	for key, val := range new.All() {
+		if _, exists := old.GetByTuple(key); !exists {
+			diff.Added[key] = val
+		}
+	}
+	return diff
+}
+
+// CopyMapMap makes dst contain exactly the keys and values in src,
+// deleting any other keys it contains.
+func CopyMapMap[K1, K2 comparable, V any](
+	dst MutableMapMap[K1, K2, V],
+	src MapMapView[K1, K2, V],
+) {
+	var remove []Tuple2[K1, K2]
+	for key := range dst.All() {
+		if _, exists := src.GetByTuple(key); !exists {
+			remove = append(remove, key)
+		}
+	}
+	for _, key := range remove {
+		dst.DeleteByTuple(key)
+	}
+	UnionMapMaps(dst, src)
+}
+
+// UnionMapMaps sets all the keys and values in each of the srcs into
+// dst. Where the srcs have the same key, the value from the last one
+// wins.
+func UnionMapMaps[K1, K2 comparable, V any](
+	dst MutableMapMap[K1, K2, V],
+	srcs ...MapMapView[K1, K2, V],
+) {
+	for _, src := range srcs {
+		for key, val := range src.All() {
+			dst.SetByTuple(key, val)
+		}
+	}
+}
+
+// EqualMapSets returns true if the two MapSets contain the same key and
+// value pairs.
+func EqualMapSets[K, V comparable](l, r MapSetView[K, V]) bool {
+	for key, val := range l.All() {
+		if !r.Contains(key, val) {
+			return false
+		}
+	}
+	for key, val := range r.All() {
+		if !l.Contains(key, val) {
+			return false
+		}
+	}
+	return true
+}
+
+// DiffMapSets returns the differences between the old MapSet and the new
+// one, as MapSet.Diff does.
+func DiffMapSets[K, V comparable](old, new MapSetView[K, V]) SetDiff[Tuple2[K, V]] {
+	diff := SetDiff[Tuple2[K, V]]{
+		Added:   Set[Tuple2[K, V]]{},
+		Removed: Set[Tuple2[K, V]]{},
+	}
+	for key, val := range old.All() {
+		if !new.Contains(key, val) {
+			diff.Removed.Add(Tuple2[K, V]{key, val})
+		}
+	}
+	for key, val := range new.All() {
+		if !old.Contains(key, val) {
+			diff.Added.Add(Tuple2[K, V]{key, val})
+		}
+	}
+	return diff
+}
+
+// CopyMapSet makes dst contain exactly the key and value pairs in src,
+// deleting any others it contains.
+func CopyMapSet[K, V comparable](dst MutableMapSet[K, V], src MapSetView[K, V]) {
+	var remove []Tuple2[K, V]
+	for key, val := range dst.All() {
+		if !src.Contains(key, val) {
+			remove = append(remove, Tuple2[K, V]{key, val})
+		}
+	}
+	for _, pair := range remove {
+		dst.Delete(pair.Key1, pair.Key2)
+	}
+	UnionMapSets(dst, src)
+}
+
+// UnionMapSets adds all the key and value pairs in each of the srcs to
+// dst.
+func UnionMapSets[K, V comparable](dst MutableMapSet[K, V], srcs ...MapSetView[K, V]) {
+	for _, src := range srcs {
+		for key, val := range src.All() {
+			dst.Add(key, val)
+		}
+	}
+}
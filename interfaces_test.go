@@ -0,0 +1,124 @@
+package cm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetAlgorithms(t *testing.T) {
+	s := SetFromSlice([]int{1, 2, 3})
+	sorted := NewSortedSet[int]()
+	bs := BitSetFromSlice([]int{1, 2, 3})
+	ps := PersistentSetFromSet(s)
+
+	if EqualSets[int](s, sorted) {
+		t.Fatal("sets of different lengths are equal")
+	}
+	UnionSets[int](sorted, bs, SetFromSlice([]int{2}))
+	if !EqualSets[int](s, sorted) || !EqualSets[int](sorted, ps) {
+		t.Fatal("UnionSets didn't work")
+	}
+	sorted.Remove(3)
+	sorted.Add(4)
+	if EqualSets[int](sorted, bs) {
+		t.Fatal("sets with different values are equal")
+	}
+
+	diff := DiffSets[int](bs, sorted)
+	if !diff.Added.Equal(SetFromSlice([]int{4})) ||
+		!diff.Removed.Equal(SetFromSlice([]int{3})) {
+		t.Fatal("DiffSets didn't work")
+	}
+
+	rs := RoaringSetFromSlice([]int{3, 10, 100000})
+	CopySet[int](rs, sorted)
+	if !EqualSets[int](rs, sorted) {
+		t.Fatal("CopySet didn't work")
+	}
+}
+
+func TestMapMapAlgorithms(t *testing.T) {
+	mm := MapMap[int, int, int]{}
+	mm.Set(1, 2, 3)
+	mm.Set(1, 3, 4)
+	mm.Set(2, 1, 5)
+
+	sorted := NewSortedMapMap[int, int, int]()
+	if EqualMapMaps[int, int, int](mm, sorted) {
+		t.Fatal("maps of different lengths are equal")
+	}
+	UnionMapMaps[int, int, int](sorted, PersistentMapMapFromMapMap(MapMapAny[int, int, int](mm)))
+	if !EqualMapMaps[int, int, int](mm, sorted) {
+		t.Fatal("UnionMapMaps didn't work")
+	}
+
+	sorted.Set(1, 2, 10)
+	sorted.Delete(2, 1)
+	sorted.Set(3, 3, 3)
+	if EqualMapMaps[int, int, int](mm, sorted) {
+		t.Fatal("maps with different values are equal")
+	}
+	diff := DiffMapMaps[int, int, int](mm, sorted)
+	expected := MapDiff[Tuple2[int, int], int]{
+		Added:   map[Tuple2[int, int]]int{{3, 3}: 3},
+		Removed: map[Tuple2[int, int]]int{{2, 1}: 5},
+		Changed: map[Tuple2[int, int]]Change[int]{{1, 2}: {3, 10}},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatal("DiffMapMaps didn't work")
+	}
+
+	dm := &DualMap[int, int, int]{}
+	dm.Set(9, 9, 9)
+	CopyMapMap[int, int, int](dm, mm)
+	if !EqualMapMaps[int, int, int](dm, mm) {
+		t.Fatal("CopyMapMap didn't work")
+	}
+
+	mma := MapMapAny[int, int, []int]{}
+	mma.Set(1, 1, []int{1})
+	sortedAny := NewSortedMapMap[int, int, []int]()
+	sortedAny.Set(1, 1, []int{2})
+	eq := func(l, r []int) bool { return l[0] == r[0] }
+	if EqualMapMapsFunc[int, int, []int](mma, sortedAny, eq) {
+		t.Fatal("EqualMapMapsFunc didn't work")
+	}
+	if len(DiffMapMapsFunc[int, int, []int](mma, sortedAny, eq).Changed) != 1 {
+		t.Fatal("DiffMapMapsFunc didn't work")
+	}
+}
+
+func TestMapSetAlgorithms(t *testing.T) {
+	ms := MapSet[int, int]{}
+	ms.Add(1, 2)
+	ms.Add(1, 3)
+	ms.Add(2, 4)
+
+	sorted := NewSortedMapSet[int, int]()
+	if EqualMapSets[int, int](ms, sorted) {
+		t.Fatal("different MapSets are equal")
+	}
+	UnionMapSets[int, int](sorted, PersistentMapSetFromMapSet(ms))
+	if !EqualMapSets[int, int](ms, sorted) {
+		t.Fatal("UnionMapSets didn't work")
+	}
+	sorted.Add(3, 3)
+	if EqualMapSets[int, int](ms, sorted) {
+		t.Fatal("different MapSets are equal")
+	}
+	sorted.Delete(1, 2)
+
+	diff := DiffMapSets[int, int](ms, sorted)
+	if !diff.Added.Equal(SetFromSlice([]Tuple2[int, int]{{3, 3}})) ||
+		!diff.Removed.Equal(SetFromSlice([]Tuple2[int, int]{{1, 2}})) {
+		t.Fatal("DiffMapSets didn't work")
+	}
+
+	rms := RoaringMapSet[int, int]{}
+	rms.Add(5, 5)
+	rms.Add(1, 3)
+	CopyMapSet[int, int](rms, sorted)
+	if !EqualMapSets[int, int](rms, sorted) {
+		t.Fatal("CopyMapSet didn't work")
+	}
+}
@@ -0,0 +1,501 @@
+package cm
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// JSONLayout selects how a container is serialized into JSON.
+//
+// The containers in this package implement json.Marshaler with
+// JSONAuto. To choose a specific layout, call MarshalJSONLayout on the
+// container. Unmarshaling accepts either layout, so it is not necessary
+// to know which layout was used to write the JSON.
+type JSONLayout int
+
+const (
+	// JSONAuto selects JSONNested if every key type can be a JSON object
+	// key, and JSONRows otherwise. A key type that implements only one of
+	// encoding.TextMarshaler and encoding.TextUnmarshaler is not treated
+	// as an object key, as it could not be read back in.
+	JSONAuto JSONLayout = iota
+
+	// JSONNested serializes the container as nested JSON objects, the
+	// way encoding/json serializes a map of maps. Empty submaps are
+	// omitted. This requires every key type to be a string, an integer,
+	// or an encoding.TextMarshaler, just as it does for Go maps, and to
+	// unmarshal it again, a TextMarshaler key type also needs to be an
+	// encoding.TextUnmarshaler.
+	JSONNested
+
+	// JSONRows serializes the container as a flat array of objects, one
+	// per value, with the keys in "k1", "k2", and "k3" and the value in
+	// "v". These correspond to the slots of a Tuple2 or Tuple3. This
+	// works for any key type that can itself be serialized.
+	JSONRows
+)
+
+// ErrJSONNotObjectOrArray is returned when unmarshaling a container
+// from JSON that is neither an object nor an array.
+var ErrJSONNotObjectOrArray = errors.New(
+	"can only unmarshal a JSON object or array into this container")
+
+// ErrJSONEmptyPath is returned when unmarshaling a PathMap from JSON
+// that has a value with an empty path, which a PathMap can not hold.
+var ErrJSONEmptyPath = errors.New("can not unmarshal an empty path into a PathMap")
+
+var (
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// isJSONKey returns whether the given type can be used as a JSON object
+// key by encoding/json, both when marshaling and unmarshaling.
+func isJSONKey[K any]() bool {
+	t := reflect.TypeFor[K]()
+	marshals := t.Implements(textMarshalerType)
+	unmarshals := reflect.PointerTo(t).Implements(textUnmarshalerType)
+	if marshals || unmarshals {
+		return marshals && unmarshals
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// jsonStart returns the first non-whitespace byte in the JSON, and
+// whether the JSON is the null value.
+func jsonStart(data []byte) (byte, bool) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return 0, false
+	}
+	return data[0], bytes.Equal(data, []byte("null"))
+}
+
+type row2[K1, K2 comparable, V any] struct {
+	Key1  K1 `json:"k1"`
+	Key2  K2 `json:"k2"`
+	Value V  `json:"v"`
+}
+
+type row3[K1, K2, K3 comparable, V any] struct {
+	Key1  K1 `json:"k1"`
+	Key2  K2 `json:"k2"`
+	Key3  K3 `json:"k3"`
+	Value V  `json:"v"`
+}
+
+type setRow[K, V comparable] struct {
+	Key1 K `json:"k1"`
+	Key2 V `json:"k2"`
+}
+
+type pathRow[K comparable, V any] struct {
+	Path  []K `json:"k"`
+	Value V   `json:"v"`
+}
+
+// MarshalJSON implements json.Marshaler, serializing the Set as a JSON
+// array in hash order.
+//
+// A nil Set is serialized as null.
+func (s Set[M]) MarshalJSON() ([]byte, error) {
+	if s == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(s.AsSlice())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, adding the values of a
+// JSON array into the Set. As with Go maps, existing values in the Set
+// are retained.
+func (s *Set[M]) UnmarshalJSON(data []byte) error {
+	if _, null := jsonStart(data); null {
+		return nil
+	}
+	var vals []M
+	err := json.Unmarshal(data, &vals)
+	if err != nil {
+		return err
+	}
+	if *s == nil {
+		*s = make(Set[M], len(vals))
+	}
+	for _, val := range vals {
+		(*s)[val] = void
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler with the JSONAuto layout.
+func (mma MapMapAny[K1, K2, V]) MarshalJSON() ([]byte, error) {
+	return mma.MarshalJSONLayout(JSONAuto)
+}
+
+// MarshalJSONLayout serializes the MapMap into JSON with the given
+// layout.
+//
+// A nil MapMap is serialized as null.
+func (mma MapMapAny[K1, K2, V]) MarshalJSONLayout(layout JSONLayout) ([]byte, error) {
+	if mma == nil {
+		return []byte("null"), nil
+	}
+	if layout == JSONAuto {
+		layout = JSONRows
+		if isJSONKey[K1]() && isJSONKey[K2]() {
+			layout = JSONNested
+		}
+	}
+
+	if layout == JSONNested {
+		nested := make(map[K1]map[K2]V, len(mma))
+		for key1, submap := range mma {
+			if len(submap) != 0 {
+				nested[key1] = submap
+			}
+		}
+		return json.Marshal(nested)
+	}
+
+	rows := make([]row2[K1, K2, V], 0, mma.Len())
+	for key, val := range mma.All() {
+		rows = append(rows, row2[K1, K2, V]{key.Key1, key.Key2, val})
+	}
+	return json.Marshal(rows)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either the
+// JSONNested or JSONRows layout. As with Go maps, existing values in the
+// MapMap are retained. Empty submaps in the JSON are discarded.
+func (mma *MapMapAny[K1, K2, V]) UnmarshalJSON(data []byte) error {
+	start, null := jsonStart(data)
+	if null {
+		return nil
+	}
+
+	switch start {
+	case '{':
+		var nested map[K1]map[K2]V
+		err := json.Unmarshal(data, &nested)
+		if err != nil {
+			return err
+		}
+		if *mma == nil {
+			*mma = make(MapMapAny[K1, K2, V], len(nested))
+		}
+		for key1, submap := range nested {
+			for key2, val := range submap {
+				mma.Set(key1, key2, val)
+			}
+		}
+		return nil
+
+	case '[':
+		var rows []row2[K1, K2, V]
+		err := json.Unmarshal(data, &rows)
+		if err != nil {
+			return err
+		}
+		if *mma == nil {
+			*mma = MapMapAny[K1, K2, V]{}
+		}
+		for _, row := range rows {
+			mma.Set(row.Key1, row.Key2, row.Value)
+		}
+		return nil
+	}
+
+	return ErrJSONNotObjectOrArray
+}
+
+// MarshalJSON implements json.Marshaler with the JSONAuto layout.
+func (mm MapMap[K1, K2, V]) MarshalJSON() ([]byte, error) {
+	return MapMapAny[K1, K2, V](mm).MarshalJSONLayout(JSONAuto)
+}
+
+// MarshalJSONLayout serializes the MapMap into JSON with the given
+// layout.
+func (mm MapMap[K1, K2, V]) MarshalJSONLayout(layout JSONLayout) ([]byte, error) {
+	return MapMapAny[K1, K2, V](mm).MarshalJSONLayout(layout)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either the
+// JSONNested or JSONRows layout.
+func (mm *MapMap[K1, K2, V]) UnmarshalJSON(data []byte) error {
+	return (*MapMapAny[K1, K2, V])(mm).UnmarshalJSON(data)
+}
+
+// MarshalJSON implements json.Marshaler with the JSONAuto layout.
+func (mmma MapMapMapAny[K1, K2, K3, V]) MarshalJSON() ([]byte, error) {
+	return mmma.MarshalJSONLayout(JSONAuto)
+}
+
+// MarshalJSONLayout serializes the MapMapMap into JSON with the given
+// layout.
+//
+// A nil MapMapMap is serialized as null.
+func (mmma MapMapMapAny[K1, K2, K3, V]) MarshalJSONLayout(layout JSONLayout) ([]byte, error) {
+	if mmma == nil {
+		return []byte("null"), nil
+	}
+	if layout == JSONAuto {
+		layout = JSONRows
+		if isJSONKey[K1]() && isJSONKey[K2]() && isJSONKey[K3]() {
+			layout = JSONNested
+		}
+	}
+
+	if layout == JSONNested {
+		nested := make(map[K1]map[K2]map[K3]V, len(mmma))
+		for key1, mapmap := range mmma {
+			for key2, submap := range mapmap {
+				if len(submap) == 0 {
+					continue
+				}
+				if nested[key1] == nil {
+					nested[key1] = map[K2]map[K3]V{}
+				}
+				nested[key1][key2] = submap
+			}
+		}
+		return json.Marshal(nested)
+	}
+
+	rows := make([]row3[K1, K2, K3, V], 0, mmma.Len())
+	for key, val := range mmma.All() {
+		rows = append(rows, row3[K1, K2, K3, V]{key.Key1, key.Key2, key.Key3, val})
+	}
+	return json.Marshal(rows)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either the
+// JSONNested or JSONRows layout. As with Go maps, existing values in the
+// MapMapMap are retained. Empty submaps in the JSON are discarded.
+func (mmma *MapMapMapAny[K1, K2, K3, V]) UnmarshalJSON(data []byte) error {
+	start, null := jsonStart(data)
+	if null {
+		return nil
+	}
+
+	switch start {
+	case '{':
+		var nested map[K1]map[K2]map[K3]V
+		err := json.Unmarshal(data, &nested)
+		if err != nil {
+			return err
+		}
+		if *mmma == nil {
+			*mmma = make(MapMapMapAny[K1, K2, K3, V], len(nested))
+		}
+		for key1, mapmap := range nested {
+			for key2, submap := range mapmap {
+				for key3, val := range submap {
+					mmma.Set(key1, key2, key3, val)
+				}
+			}
+		}
+		return nil
+
+	case '[':
+		var rows []row3[K1, K2, K3, V]
+		err := json.Unmarshal(data, &rows)
+		if err != nil {
+			return err
+		}
+		if *mmma == nil {
+			*mmma = MapMapMapAny[K1, K2, K3, V]{}
+		}
+		for _, row := range rows {
+			mmma.Set(row.Key1, row.Key2, row.Key3, row.Value)
+		}
+		return nil
+	}
+
+	return ErrJSONNotObjectOrArray
+}
+
+// MarshalJSON implements json.Marshaler with the JSONAuto layout.
+func (mmm MapMapMap[K1, K2, K3, V]) MarshalJSON() ([]byte, error) {
+	return MapMapMapAny[K1, K2, K3, V](mmm).MarshalJSONLayout(JSONAuto)
+}
+
+// MarshalJSONLayout serializes the MapMapMap into JSON with the given
+// layout.
+func (mmm MapMapMap[K1, K2, K3, V]) MarshalJSONLayout(layout JSONLayout) ([]byte, error) {
+	return MapMapMapAny[K1, K2, K3, V](mmm).MarshalJSONLayout(layout)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either the
+// JSONNested or JSONRows layout.
+func (mmm *MapMapMap[K1, K2, K3, V]) UnmarshalJSON(data []byte) error {
+	return (*MapMapMapAny[K1, K2, K3, V])(mmm).UnmarshalJSON(data)
+}
+
+// MarshalJSON implements json.Marshaler with the JSONAuto layout.
+func (ms MapSet[K, V]) MarshalJSON() ([]byte, error) {
+	return ms.MarshalJSONLayout(JSONAuto)
+}
+
+// MarshalJSONLayout serializes the MapSet into JSON with the given
+// layout. The JSONNested layout is an object of arrays. The JSONRows
+// layout has the key in "k1" and the value in "k2", corresponding to
+// the Tuple2 taken by AddByTuple; there is no "v".
+//
+// A nil MapSet is serialized as null.
+func (ms MapSet[K, V]) MarshalJSONLayout(layout JSONLayout) ([]byte, error) {
+	if ms == nil {
+		return []byte("null"), nil
+	}
+	if layout == JSONAuto {
+		layout = JSONRows
+		if isJSONKey[K]() {
+			layout = JSONNested
+		}
+	}
+
+	if layout == JSONNested {
+		nested := make(map[K]Set[V], len(ms))
+		for key, set := range ms {
+			if len(set) != 0 {
+				nested[key] = set
+			}
+		}
+		return json.Marshal(nested)
+	}
+
+	rows := []setRow[K, V]{}
+	for key, set := range ms {
+		for val := range set {
+			rows = append(rows, setRow[K, V]{key, val})
+		}
+	}
+	return json.Marshal(rows)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either the
+// JSONNested or JSONRows layout. As with Go maps, existing values in the
+// MapSet are retained. Empty sets in the JSON are discarded.
+func (ms *MapSet[K, V]) UnmarshalJSON(data []byte) error {
+	start, null := jsonStart(data)
+	if null {
+		return nil
+	}
+
+	switch start {
+	case '{':
+		var nested map[K][]V
+		err := json.Unmarshal(data, &nested)
+		if err != nil {
+			return err
+		}
+		if *ms == nil {
+			*ms = make(MapSet[K, V], len(nested))
+		}
+		for key, vals := range nested {
+			for _, val := range vals {
+				ms.Add(key, val)
+			}
+		}
+		return nil
+
+	case '[':
+		var rows []setRow[K, V]
+		err := json.Unmarshal(data, &rows)
+		if err != nil {
+			return err
+		}
+		if *ms == nil {
+			*ms = MapSet[K, V]{}
+		}
+		for _, row := range rows {
+			ms.Add(row.Key1, row.Key2)
+		}
+		return nil
+	}
+
+	return ErrJSONNotObjectOrArray
+}
+
+// MarshalJSON implements json.Marshaler with the JSONAuto layout.
+//
+// Only the Primary map is serialized, as the Reverse map can be
+// reconstructed from it. Unlike the rest of DualMap's methods, this has
+// a value receiver, so that DualMap values embedded in other structs
+// are serialized correctly.
+func (dm DualMap[P, S, V]) MarshalJSON() ([]byte, error) {
+	return dm.Primary.MarshalJSONLayout(JSONAuto)
+}
+
+// MarshalJSONLayout serializes the DualMap's Primary map into JSON with
+// the given layout.
+func (dm DualMap[P, S, V]) MarshalJSONLayout(layout JSONLayout) ([]byte, error) {
+	return dm.Primary.MarshalJSONLayout(layout)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either the
+// JSONNested or JSONRows layout of the Primary map, and setting both the
+// Primary and Reverse maps from it. Existing values in the DualMap are
+// retained.
+func (dm *DualMap[P, S, V]) UnmarshalJSON(data []byte) error {
+	var primary MapMapAny[P, S, V]
+	err := primary.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+	for key, val := range primary.All() {
+		dm.SetByTuple(key, val)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. As paths vary in length, a
+// PathMap is always serialized as a JSON array of objects, one per
+// value, with the path as an array in "k" and the value in "v". There is
+// no nested layout.
+//
+// A nil PathMap is serialized as null.
+func (pm PathMap[K, V]) MarshalJSON() ([]byte, error) {
+	if pm == nil {
+		return []byte("null"), nil
+	}
+	rows := make([]pathRow[K, V], 0, pm.Len())
+	for path, val := range pm.All() {
+		rows = append(rows, pathRow[K, V]{path, val})
+	}
+	return json.Marshal(rows)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, reading the layout written
+// by MarshalJSON. As with Go maps, existing values in the PathMap are
+// retained. A value with an empty path is an error.
+func (pm *PathMap[K, V]) UnmarshalJSON(data []byte) error {
+	if _, null := jsonStart(data); null {
+		return nil
+	}
+	var rows []pathRow[K, V]
+	err := json.Unmarshal(data, &rows)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if len(row.Path) == 0 {
+			return ErrJSONEmptyPath
+		}
+	}
+	if *pm == nil {
+		*pm = PathMap[K, V]{}
+	}
+	for _, row := range rows {
+		pm.Set(row.Path, row.Value)
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package cm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// textID is a non-string key type that can be an object key.
+type textID struct {
+	ID int
+}
+
+func (ti textID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("id-%d", ti.ID)), nil
+}
+
+func (ti *textID) UnmarshalText(b []byte) error {
+	if len(b) < 3 {
+		return errors.New("bad textID")
+	}
+	id, err := strconv.Atoi(string(b[3:]))
+	ti.ID = id
+	return err
+}
+
+// marshalOnlyID is a key type that can be marshaled as text, but not
+// unmarshaled again, so it can not be an object key.
+type marshalOnlyID int
+
+func (mo marshalOnlyID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("mo-%d", int(mo))), nil
+}
+
+// structID is a key type that can not be an object key.
+type structID struct {
+	A int
+	B string
+}
+
+func roundTrip[T any](t *testing.T, in T, out *T) []byte {
+	t.Helper()
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(b, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSetJSON(t *testing.T) {
+	s := SetFromSlice([]int{1, 2, 3})
+
+	var s2 Set[int]
+	b := roundTrip(t, s, &s2)
+	if b[0] != '[' {
+		t.Fatal("set didn't serialize as an array")
+	}
+	if !s.Equal(s2) {
+		t.Fatal("set didn't round trip")
+	}
+
+	// Existing values are retained, like maps.
+	s3 := SetFromSlice([]int{4})
+	roundTrip(t, s, &s3)
+	if !s3.Equal(SetFromSlice([]int{1, 2, 3, 4})) {
+		t.Fatal("set didn't unmarshal into an existing set")
+	}
+
+	var nilSet Set[int]
+	b = roundTrip(t, nilSet, &s2)
+	if string(b) != "null" {
+		t.Fatal("nil set didn't serialize to null")
+	}
+
+	if json.Unmarshal([]byte(`{}`), &s2) == nil {
+		t.Fatal("could unmarshal an object into a set")
+	}
+}
+
+func TestMapMapJSON(t *testing.T) {
+	{
+		mm := MapMapAny[string, int, string]{}
+		mm.Set("a", 1, "x")
+		mm.Set("a", 2, "y")
+		mm.Set("b", 1, "z")
+		// direct writes can leave empty submaps; they shouldn't show up.
+		mm["empty"] = map[int]string{}
+
+		var mm2 MapMapAny[string, int, string]
+		b := roundTrip(t, mm, &mm2)
+		if b[0] != '{' {
+			t.Fatal("string keys didn't serialize as nested objects")
+		}
+		delete(mm, "empty")
+		if !reflect.DeepEqual(mm, mm2) {
+			t.Fatal("MapMap didn't round trip")
+		}
+
+		b, err := mm.MarshalJSONLayout(JSONRows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mm3 MapMapAny[string, int, string]
+		err = json.Unmarshal(b, &mm3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(mm, mm3) {
+			t.Fatal("MapMap didn't round trip as rows")
+		}
+
+		var rows []map[string]any
+		err = json.Unmarshal(b, &rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 3 || len(rows[0]) != 3 || rows[0]["k1"] == nil ||
+			rows[0]["k2"] == nil || rows[0]["v"] == nil {
+			t.Fatal("incorrect row layout")
+		}
+	}
+
+	{
+		mm := MapMap[textID, structID, int]{}
+		mm.Set(textID{1}, structID{2, "b"}, 3)
+		mm.Set(textID{1}, structID{3, "c"}, 4)
+
+		var mm2 MapMap[textID, structID, int]
+		b := roundTrip(t, mm, &mm2)
+		if b[0] != '[' {
+			t.Fatal("struct keys didn't serialize as rows")
+		}
+		if !mm.Equal(mm2) {
+			t.Fatal("MapMap with struct keys didn't round trip")
+		}
+
+		_, err := mm.MarshalJSONLayout(JSONNested)
+		if err == nil {
+			t.Fatal("could serialize struct keys as nested")
+		}
+	}
+
+	{
+		mm := MapMap[textID, int, int]{}
+		mm.Set(textID{1}, 2, 3)
+		var mm2 MapMap[textID, int, int]
+		b := roundTrip(t, mm, &mm2)
+		if string(b) != `{"id-1":{"2":3}}` {
+			t.Fatalf("TextMarshaler keys didn't serialize as nested: %s", b)
+		}
+		if !mm.Equal(mm2) {
+			t.Fatal("MapMap with TextMarshaler keys didn't round trip")
+		}
+	}
+
+	var nilMM MapMapAny[int, int, int]
+	var mm MapMapAny[int, int, int]
+	b := roundTrip(t, nilMM, &mm)
+	if string(b) != "null" || mm != nil {
+		t.Fatal("nil MapMap didn't round trip")
+	}
+	for _, bad := range []string{`"hello"`, `{"a":{}}`, `[1]`} {
+		if json.Unmarshal([]byte(bad), &mm) == nil {
+			t.Fatalf("could unmarshal %s into a MapMap", bad)
+		}
+	}
+	if !errors.Is(mm.UnmarshalJSON(nil), ErrJSONNotObjectOrArray) {
+		t.Fatal("could unmarshal nothing into a MapMap")
+	}
+	if json.Unmarshal([]byte(`{"1":{}}`), &mm) != nil || len(mm) != 0 {
+		t.Fatal("empty submap isn't discarded on unmarshal")
+	}
+}
+
+func TestMapMapMapJSON(t *testing.T) {
+	{
+		mmm := MapMapMapAny[string, string, int, int]{}
+		mmm.Set("a", "b", 1, 2)
+		mmm.Set("a", "c", 1, 3)
+		mmm["a"]["empty"] = map[int]int{}
+		mmm["empty"] = MapMapAny[string, int, int]{"empty": {}}
+
+		var mmm2 MapMapMapAny[string, string, int, int]
+		b := roundTrip(t, mmm, &mmm2)
+		if string(b) != `{"a":{"b":{"1":2},"c":{"1":3}}}` {
+			t.Fatalf("incorrect nested MapMapMap JSON: %s", b)
+		}
+		delete(mmm, "empty")
+		delete(mmm["a"], "empty")
+		if !reflect.DeepEqual(mmm, mmm2) {
+			t.Fatal("MapMapMap didn't round trip")
+		}
+	}
+
+	{
+		mmm := MapMapMap[structID, int, int, int]{}
+		mmm.Set(structID{1, "a"}, 2, 3, 4)
+
+		var mmm2 MapMapMap[structID, int, int, int]
+		b := roundTrip(t, mmm, &mmm2)
+		if string(b) != `[{"k1":{"A":1,"B":"a"},"k2":2,"k3":3,"v":4}]` {
+			t.Fatalf("incorrect row MapMapMap JSON: %s", b)
+		}
+		if !mmm.Equal(mmm2) {
+			t.Fatal("MapMapMap didn't round trip as rows")
+		}
+
+		b, err := mmm.MarshalJSONLayout(JSONRows)
+		if err != nil || b[0] != '[' {
+			t.Fatal("couldn't select rows")
+		}
+	}
+
+	var nilMMM MapMapMapAny[int, int, int, int]
+	var mmm MapMapMapAny[int, int, int, int]
+	b := roundTrip(t, nilMMM, &mmm)
+	if string(b) != "null" || mmm != nil {
+		t.Fatal("nil MapMapMap didn't round trip")
+	}
+	for _, bad := range []string{`1`, `{"a":{}}`, `[1]`} {
+		if json.Unmarshal([]byte(bad), &mmm) == nil {
+			t.Fatalf("could unmarshal %s into a MapMapMap", bad)
+		}
+	}
+}
+
+func TestMapSetJSON(t *testing.T) {
+	{
+		ms := MapSet[string, int]{}
+		ms.Add("a", 1)
+		ms["empty"] = Set[int]{}
+
+		var ms2 MapSet[string, int]
+		b := roundTrip(t, ms, &ms2)
+		if string(b) != `{"a":[1]}` {
+			t.Fatalf("incorrect nested MapSet JSON: %s", b)
+		}
+		delete(ms, "empty")
+		if !reflect.DeepEqual(ms, ms2) {
+			t.Fatal("MapSet didn't round trip")
+		}
+	}
+
+	{
+		ms := MapSet[structID, int]{}
+		ms.Add(structID{1, "a"}, 2)
+
+		var ms2 MapSet[structID, int]
+		b := roundTrip(t, ms, &ms2)
+		if string(b) != `[{"k1":{"A":1,"B":"a"},"k2":2}]` {
+			t.Fatalf("incorrect row MapSet JSON: %s", b)
+		}
+		if !reflect.DeepEqual(ms, ms2) {
+			t.Fatal("MapSet didn't round trip as rows")
+		}
+	}
+
+	var nilMS MapSet[int, int]
+	var ms MapSet[int, int]
+	b := roundTrip(t, nilMS, &ms)
+	if string(b) != "null" || ms != nil {
+		t.Fatal("nil MapSet didn't round trip")
+	}
+	for _, bad := range []string{`1`, `{"a":[]}`, `[1]`} {
+		if json.Unmarshal([]byte(bad), &ms) == nil {
+			t.Fatalf("could unmarshal %s into a MapSet", bad)
+		}
+	}
+}
+
+func TestDualMapJSON(t *testing.T) {
+	dm := DualMap[int, string, int]{}
+	dm.Set(1, "a", 2)
+	dm.Set(3, "a", 4)
+
+	var dm2 DualMap[int, string, int]
+	b := roundTrip(t, dm, &dm2)
+	if string(b) != `{"1":{"a":2},"3":{"a":4}}` {
+		t.Fatalf("incorrect DualMap JSON: %s", b)
+	}
+	if !reflect.DeepEqual(dm, dm2) {
+		t.Fatal("DualMap didn't round trip")
+	}
+
+	b, err := dm.MarshalJSONLayout(JSONRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dm3 DualMap[int, string, int]
+	err = json.Unmarshal(b, &dm3)
+	if err != nil || !reflect.DeepEqual(dm, dm3) {
+		t.Fatal("DualMap didn't round trip as rows")
+	}
+
+	if json.Unmarshal([]byte(`1`), &dm3) == nil {
+		t.Fatal("could unmarshal a number into a DualMap")
+	}
+
+	// embedded in a struct by value, it still uses the custom marshaler
+	type holder struct {
+		DM DualMap[int, string, int]
+	}
+	b, err = json.Marshal(holder{dm})
+	if err != nil || string(b) != `{"DM":{"1":{"a":2},"3":{"a":4}}}` {
+		t.Fatalf("incorrect embedded DualMap JSON: %s", b)
+	}
+}
+
+func TestJSONKeyNeedsUnmarshaler(t *testing.T) {
+	if isJSONKey[marshalOnlyID]() {
+		t.Fatal("a key that can't be unmarshaled is an object key")
+	}
+	if !isJSONKey[textID]() || !isJSONKey[int]() || isJSONKey[structID]() {
+		t.Fatal("incorrect object key detection")
+	}
+
+	mm := MapMap[marshalOnlyID, int, int]{}
+	mm.Set(1, 2, 3)
+
+	b, err := json.Marshal(mm)
+	if err != nil || string(b) != `[{"k1":"mo-1","k2":2,"v":3}]` {
+		t.Fatalf("marshal-only key not serialized as rows: %s", b)
+	}
+}
+
+func TestPathMapJSON(t *testing.T) {
+	pm := PathMap[string, int]{}
+	pm.Set([]string{"a"}, 1)
+	pm.Set([]string{"a", "b", "c"}, 2)
+
+	var pm2 PathMap[string, int]
+	roundTrip(t, pm, &pm2)
+	for path, val := range pm.All() {
+		if val2, exists := pm2.Get(path); !exists || val2 != val {
+			t.Fatal("PathMap didn't round trip")
+		}
+	}
+	if pm2.Len() != 2 {
+		t.Fatal("PathMap didn't round trip")
+	}
+
+	single := PathMap[string, int]{}
+	single.Set([]string{"a", "b"}, 1)
+	b, err := json.Marshal(single)
+	if err != nil || string(b) != `[{"k":["a","b"],"v":1}]` {
+		t.Fatalf("incorrect PathMap JSON: %s", b)
+	}
+
+	err = json.Unmarshal([]byte(`[{"k":["x"],"v":3}]`), &pm2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val, _ := pm2.Get([]string{"a"}); val != 1 || pm2.Len() != 3 {
+		t.Fatal("unmarshaling didn't retain existing values")
+	}
+
+	var nilPM PathMap[string, int]
+	var pm3 PathMap[string, int]
+	b = roundTrip(t, nilPM, &pm3)
+	if string(b) != "null" || pm3 != nil {
+		t.Fatal("nil PathMap didn't round trip")
+	}
+
+	err = json.Unmarshal([]byte(`[{"k":[],"v":1}]`), &pm3)
+	if !errors.Is(err, ErrJSONEmptyPath) || pm3 != nil {
+		t.Fatal("could unmarshal an empty path into a PathMap")
+	}
+	if json.Unmarshal([]byte(`{"a":1}`), &pm3) == nil {
+		t.Fatal("could unmarshal an object into a PathMap")
+	}
+}
@@ -400,3 +400,23 @@ func (mma MapMapAny[K1, K2, V]) Len() int {
 	}
 	return l
 }
+
+// Transpose returns a new MapMap with the keys swapped, so that the value
+// at mm[key1][key2] is at result[key2][key1]. The values are copied
+// shallowly.
+func (mm MapMap[K1, K2, V]) Transpose() MapMap[K2, K1, V] {
+	return MapMap[K2, K1, V](MapMapAny[K1, K2, V](mm).Transpose())
+}
+
+// Transpose returns a new MapMapAny with the keys swapped, so that the
+// value at mma[key1][key2] is at result[key2][key1]. The values are
+// copied shallowly.
+func (mma MapMapAny[K1, K2, V]) Transpose() MapMapAny[K2, K1, V] {
+	result := MapMapAny[K2, K1, V]{}
+	for key1, subMap := range mma {
+		for key2, val := range subMap {
+			result.Set(key2, key1, val)
+		}
+	}
+	return result
+}
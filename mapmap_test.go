@@ -408,3 +408,30 @@ func panics(t *testing.T, message string, f func()) {
 	}()
 	f()
 }
+
+func TestMapMapTranspose(t *testing.T) {
+	mm := MapMap[int, string, int]{}
+	mm.Set(1, "a", 1)
+	mm.Set(1, "b", 2)
+	mm.Set(2, "a", 3)
+	mm[3] = map[string]int{}
+
+	expected := MapMap[string, int, int]{}
+	expected.Set("a", 1, 1)
+	expected.Set("b", 1, 2)
+	expected.Set("a", 2, 3)
+
+	transposed := mm.Transpose()
+	if !transposed.Equal(expected) {
+		t.Fatal("Transpose didn't work")
+	}
+	if !transposed.Transpose().Equal(MapMap[int, string, int]{
+		1: {"a": 1, "b": 2},
+		2: {"a": 3},
+	}) {
+		t.Fatal("Transpose didn't round trip")
+	}
+	if len(MapMap[int, int, int](nil).Transpose()) != 0 {
+		t.Fatal("Transpose of a nil map isn't empty")
+	}
+}
@@ -370,3 +370,85 @@ func (mmma MapMapMapAny[K1, K2, K3, V]) Len() int {
 	}
 	return l
 }
+
+// Permute132 returns a new MapMapMap with the keys reordered so that the
+// value at mmm[key1][key2][key3] is at result[key1][key3][key2]. The
+// values are copied shallowly.
+//
+// The other Permute methods are named in the same way, by the order the
+// original keys appear in the result. The identity permutation is Clone.
+func (mmm MapMapMap[K1, K2, K3, V]) Permute132() MapMapMap[K1, K3, K2, V] {
+	return MapMapMap[K1, K3, K2, V](MapMapMapAny[K1, K2, K3, V](mmm).Permute132())
+}
+
+// Permute213 returns a new MapMapMap keyed by [key2][key1][key3].
+func (mmm MapMapMap[K1, K2, K3, V]) Permute213() MapMapMap[K2, K1, K3, V] {
+	return MapMapMap[K2, K1, K3, V](MapMapMapAny[K1, K2, K3, V](mmm).Permute213())
+}
+
+// Permute231 returns a new MapMapMap keyed by [key2][key3][key1].
+func (mmm MapMapMap[K1, K2, K3, V]) Permute231() MapMapMap[K2, K3, K1, V] {
+	return MapMapMap[K2, K3, K1, V](MapMapMapAny[K1, K2, K3, V](mmm).Permute231())
+}
+
+// Permute312 returns a new MapMapMap keyed by [key3][key1][key2].
+func (mmm MapMapMap[K1, K2, K3, V]) Permute312() MapMapMap[K3, K1, K2, V] {
+	return MapMapMap[K3, K1, K2, V](MapMapMapAny[K1, K2, K3, V](mmm).Permute312())
+}
+
+// Permute321 returns a new MapMapMap keyed by [key3][key2][key1].
+func (mmm MapMapMap[K1, K2, K3, V]) Permute321() MapMapMap[K3, K2, K1, V] {
+	return MapMapMap[K3, K2, K1, V](MapMapMapAny[K1, K2, K3, V](mmm).Permute321())
+}
+
+// Permute132 returns a new MapMapMapAny with the keys reordered so that
+// the value at mmma[key1][key2][key3] is at result[key1][key3][key2]. The
+// values are copied shallowly.
+//
+// The other Permute methods are named in the same way, by the order the
+// original keys appear in the result. The identity permutation is Clone.
+func (mmma MapMapMapAny[K1, K2, K3, V]) Permute132() MapMapMapAny[K1, K3, K2, V] {
+	result := make(MapMapMapAny[K1, K3, K2, V], len(mmma))
+	for key1, subMap := range mmma {
+		if transposed := subMap.Transpose(); len(transposed) != 0 {
+			result[key1] = transposed
+		}
+	}
+	return result
+}
+
+// Permute213 returns a new MapMapMapAny keyed by [key2][key1][key3].
+func (mmma MapMapMapAny[K1, K2, K3, V]) Permute213() MapMapMapAny[K2, K1, K3, V] {
+	result := MapMapMapAny[K2, K1, K3, V]{}
+	for key, val := range mmma.All() {
+		result.Set(key.Key2, key.Key1, key.Key3, val)
+	}
+	return result
+}
+
+// Permute231 returns a new MapMapMapAny keyed by [key2][key3][key1].
+func (mmma MapMapMapAny[K1, K2, K3, V]) Permute231() MapMapMapAny[K2, K3, K1, V] {
+	result := MapMapMapAny[K2, K3, K1, V]{}
+	for key, val := range mmma.All() {
+		result.Set(key.Key2, key.Key3, key.Key1, val)
+	}
+	return result
+}
+
+// Permute312 returns a new MapMapMapAny keyed by [key3][key1][key2].
+func (mmma MapMapMapAny[K1, K2, K3, V]) Permute312() MapMapMapAny[K3, K1, K2, V] {
+	result := MapMapMapAny[K3, K1, K2, V]{}
+	for key, val := range mmma.All() {
+		result.Set(key.Key3, key.Key1, key.Key2, val)
+	}
+	return result
+}
+
+// Permute321 returns a new MapMapMapAny keyed by [key3][key2][key1].
+func (mmma MapMapMapAny[K1, K2, K3, V]) Permute321() MapMapMapAny[K3, K2, K1, V] {
+	result := MapMapMapAny[K3, K2, K1, V]{}
+	for key, val := range mmma.All() {
+		result.Set(key.Key3, key.Key2, key.Key1, val)
+	}
+	return result
+}
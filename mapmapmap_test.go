@@ -319,3 +319,72 @@ func TestMapMapMapIteration(t *testing.T) {
 		break
 	}
 }
+
+func TestMapMapMapPermute(t *testing.T) {
+	mmm := MapMapMap[int, string, bool, int]{}
+	mmm.Set(1, "a", true, 1)
+	mmm.Set(1, "b", false, 2)
+	mmm.Set(2, "a", true, 3)
+	mmm[3] = MapMapAny[string, bool, int]{"c": {}}
+
+	check := func(name string, keys Set[Tuple2[Tuple3[int, string, bool], int]], expected []Tuple2[Tuple3[int, string, bool], int]) {
+		t.Helper()
+		if !keys.Equal(SetFromSlice(expected)) {
+			t.Fatalf("%s didn't work: %v", name, keys)
+		}
+	}
+	expected := []Tuple2[Tuple3[int, string, bool], int]{
+		{Tuple3[int, string, bool]{1, "a", true}, 1},
+		{Tuple3[int, string, bool]{1, "b", false}, 2},
+		{Tuple3[int, string, bool]{2, "a", true}, 3},
+	}
+	collect := func(f func(yield func(Tuple3[int, string, bool], int))) Set[Tuple2[Tuple3[int, string, bool], int]] {
+		s := Set[Tuple2[Tuple3[int, string, bool], int]]{}
+		f(func(key Tuple3[int, string, bool], val int) {
+			s.Add(Tuple2[Tuple3[int, string, bool], int]{key, val})
+		})
+		return s
+	}
+
+	p132 := mmm.Permute132()
+	check("Permute132", collect(func(yield func(Tuple3[int, string, bool], int)) {
+		for k, v := range p132.All() {
+			yield(Tuple3[int, string, bool]{k.Key1, k.Key3, k.Key2}, v)
+		}
+	}), expected)
+	if _, exists := p132[3]; exists {
+		t.Fatal("Permute132 left an empty submap")
+	}
+
+	p213 := mmm.Permute213()
+	check("Permute213", collect(func(yield func(Tuple3[int, string, bool], int)) {
+		for k, v := range p213.All() {
+			yield(Tuple3[int, string, bool]{k.Key2, k.Key1, k.Key3}, v)
+		}
+	}), expected)
+
+	p231 := mmm.Permute231()
+	check("Permute231", collect(func(yield func(Tuple3[int, string, bool], int)) {
+		for k, v := range p231.All() {
+			yield(Tuple3[int, string, bool]{k.Key3, k.Key1, k.Key2}, v)
+		}
+	}), expected)
+
+	p312 := mmm.Permute312()
+	check("Permute312", collect(func(yield func(Tuple3[int, string, bool], int)) {
+		for k, v := range p312.All() {
+			yield(Tuple3[int, string, bool]{k.Key2, k.Key3, k.Key1}, v)
+		}
+	}), expected)
+
+	p321 := mmm.Permute321()
+	check("Permute321", collect(func(yield func(Tuple3[int, string, bool], int)) {
+		for k, v := range p321.All() {
+			yield(Tuple3[int, string, bool]{k.Key3, k.Key2, k.Key1}, v)
+		}
+	}), expected)
+
+	if p321[true]["a"][2] != 3 || p231["b"][false][1] != 2 {
+		t.Fatal("permutation indexing is wrong")
+	}
+}
@@ -35,6 +35,9 @@ import "iter"
 // is no set there. Only the operations on Set that panic if they are
 // called on a nil set need to be wrapped by this data type, or Delete,
 // which cleans up the set if it is empty.
+//
+// Contains and All are provided anyway, so that a MapSet can be used as
+// a MapSetView.
 type MapSet[K, V comparable] map[K]Set[V]
 
 // AllValueSet returns a single set containing all values in the MapSet.
@@ -65,6 +68,25 @@ func (ms MapSet[K, V]) Add(key K, val V) {
 	s[val] = void
 }
 
+// Contains returns true if the set for the given key contains the value.
+func (ms MapSet[K, V]) Contains(key K, val V) bool {
+	return ms[key].Contains(val)
+}
+
+// All returns an iterator that yields every key and value pair in the
+// MapSet.
+func (ms MapSet[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, s := range ms {
+			for val := range s {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
 // Values yields all the values in the MapSet as an iterator.
 func (ms MapSet[K, V]) Values() iter.Seq[V] {
 	return func(yield func(V) bool) {
@@ -70,3 +70,26 @@ func TestNilMapSet(t *testing.T) {
 	panics(t, "failed on MapSet.Union",
 		func() { ms.Union(0, SetFromSlice([]int{1})) })
 }
+
+func TestMapSetContainsAll(t *testing.T) {
+	ms := MapSet[int, int]{}
+	ms.Add(1, 2)
+	ms.Add(1, 3)
+	ms.Add(2, 4)
+
+	if !ms.Contains(1, 2) || ms.Contains(1, 4) || ms.Contains(3, 4) {
+		t.Fatal("Contains is wrong")
+	}
+
+	pairs := Set[Tuple2[int, int]]{}
+	for key, val := range ms.All() {
+		pairs.Add(Tuple2[int, int]{key, val})
+	}
+	if !pairs.Equal(SetFromSlice([]Tuple2[int, int]{{1, 2}, {1, 3}, {2, 4}})) {
+		t.Fatal("All didn't yield all the pairs")
+	}
+
+	for _ = range ms.All() {
+		break
+	}
+}
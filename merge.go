@@ -0,0 +1,378 @@
+package cm
+
+import (
+	"fmt"
+	"maps"
+)
+
+// MergeSource identifies where an entry in the result of a
+// MergeFuncReport came from.
+type MergeSource int
+
+const (
+	// MergeLeft is an entry only in the map MergeFuncReport was called
+	// on, which was left unchanged.
+	MergeLeft MergeSource = iota + 1
+
+	// MergeRight is an entry only in the map passed in to
+	// MergeFuncReport, which was copied over.
+	MergeRight
+
+	// MergeBoth is an entry in both maps, whose value was chosen by the
+	// resolver.
+	MergeBoth
+)
+
+func (ms MergeSource) String() string {
+	switch ms {
+	case MergeLeft:
+		return "left"
+	case MergeRight:
+		return "right"
+	case MergeBoth:
+		return "both"
+	default:
+		return fmt.Sprintf("MergeSource(%d)", int(ms))
+	}
+}
+
+// Merge sets every entry of the passed-in MapMap into this one. Where
+// both have a value for the same keys, the passed-in value wins, so this
+// can be used to layer overrides over defaults.
+//
+// This will panic if called on a nil map.
+func (mma MapMapAny[K1, K2, V]) Merge(r MapMapAny[K1, K2, V]) {
+	if mma == nil {
+		panic("Merge called on a nil MapMap")
+	}
+	mma.MergeFunc(r, func(_ Tuple2[K1, K2], _, rVal V) V { return rVal })
+}
+
+// MergeFunc sets every entry of the passed-in MapMap into this one. Where
+// both have a value for the same keys, resolve is called with the keys,
+// this map's value and the passed-in map's value, and its result is
+// stored.
+//
+// Submaps of the passed-in map are copied, never shared, and empty ones
+// are skipped.
+//
+// This will panic if called on a nil map.
+func (mma MapMapAny[K1, K2, V]) MergeFunc(
+	r MapMapAny[K1, K2, V],
+	resolve func(key Tuple2[K1, K2], l, r V) V,
+) {
+	if mma == nil {
+		panic("MergeFunc called on a nil MapMap")
+	}
+	mma.merge(r, resolve, nil)
+}
+
+// MergeFuncReport merges as MergeFunc does, and returns where each entry
+// of the merged map came from.
+//
+// This will panic if called on a nil map.
+func (mma MapMapAny[K1, K2, V]) MergeFuncReport(
+	r MapMapAny[K1, K2, V],
+	resolve func(key Tuple2[K1, K2], l, r V) V,
+) map[Tuple2[K1, K2]]MergeSource {
+	if mma == nil {
+		panic("MergeFuncReport called on a nil MapMap")
+	}
+	report := map[Tuple2[K1, K2]]MergeSource{}
+	for key := range mma.Keys() {
+		report[key] = MergeLeft
+	}
+	mma.merge(r, resolve, report)
+	return report
+}
+
+func (mma MapMapAny[K1, K2, V]) merge(
+	r MapMapAny[K1, K2, V],
+	resolve func(key Tuple2[K1, K2], l, r V) V,
+	report map[Tuple2[K1, K2]]MergeSource,
+) {
+	for key1, rSubmap := range r {
+		if len(rSubmap) == 0 {
+			continue
+		}
+		submap := mma[key1]
+		if submap == nil {
+			submap = make(map[K2]V, len(rSubmap))
+			mma[key1] = submap
+		}
+		for key2, rVal := range rSubmap {
+			key := Tuple2[K1, K2]{key1, key2}
+			source := MergeRight
+			if val, exists := submap[key2]; exists {
+				rVal = resolve(key, val, rVal)
+				source = MergeBoth
+			}
+			submap[key2] = rVal
+			if report != nil {
+				report[key] = source
+			}
+		}
+	}
+}
+
+// Merge sets every entry of the passed-in MapMap into this one. See
+// MapMapAny.Merge.
+func (mm MapMap[K1, K2, V]) Merge(r MapMap[K1, K2, V]) {
+	MapMapAny[K1, K2, V](mm).Merge(MapMapAny[K1, K2, V](r))
+}
+
+// MergeFunc sets every entry of the passed-in MapMap into this one,
+// resolving collisions with the passed-in function. See
+// MapMapAny.MergeFunc.
+func (mm MapMap[K1, K2, V]) MergeFunc(
+	r MapMap[K1, K2, V],
+	resolve func(key Tuple2[K1, K2], l, r V) V,
+) {
+	MapMapAny[K1, K2, V](mm).MergeFunc(MapMapAny[K1, K2, V](r), resolve)
+}
+
+// MergeFuncReport merges as MergeFunc does, and returns where each entry
+// of the merged map came from.
+func (mm MapMap[K1, K2, V]) MergeFuncReport(
+	r MapMap[K1, K2, V],
+	resolve func(key Tuple2[K1, K2], l, r V) V,
+) map[Tuple2[K1, K2]]MergeSource {
+	return MapMapAny[K1, K2, V](mm).MergeFuncReport(MapMapAny[K1, K2, V](r), resolve)
+}
+
+// Merge sets every entry of the passed-in MapMapMap into this one. Where
+// both have a value for the same keys, the passed-in value wins.
+func (mmma MapMapMapAny[K1, K2, K3, V]) Merge(r MapMapMapAny[K1, K2, K3, V]) {
+	mmma.MergeFunc(r, func(_ Tuple3[K1, K2, K3], _, rVal V) V { return rVal })
+}
+
+// MergeFunc sets every entry of the passed-in MapMapMap into this one.
+// Where both have a value for the same keys, resolve is called with the
+// keys, this map's value and the passed-in map's value, and its result
+// is stored.
+//
+// Submaps of the passed-in map are copied, never shared, and empty ones
+// are skipped.
+func (mmma MapMapMapAny[K1, K2, K3, V]) MergeFunc(
+	r MapMapMapAny[K1, K2, K3, V],
+	resolve func(key Tuple3[K1, K2, K3], l, r V) V,
+) {
+	mmma.merge(r, resolve, nil)
+}
+
+// MergeFuncReport merges as MergeFunc does, and returns where each entry
+// of the merged map came from.
+func (mmma MapMapMapAny[K1, K2, K3, V]) MergeFuncReport(
+	r MapMapMapAny[K1, K2, K3, V],
+	resolve func(key Tuple3[K1, K2, K3], l, r V) V,
+) map[Tuple3[K1, K2, K3]]MergeSource {
+	report := map[Tuple3[K1, K2, K3]]MergeSource{}
+	for key := range mmma.Keys() {
+		report[key] = MergeLeft
+	}
+	mmma.merge(r, resolve, report)
+	return report
+}
+
+func (mmma MapMapMapAny[K1, K2, K3, V]) merge(
+	r MapMapMapAny[K1, K2, K3, V],
+	resolve func(key Tuple3[K1, K2, K3], l, r V) V,
+	report map[Tuple3[K1, K2, K3]]MergeSource,
+) {
+	for key1, rMapMap := range r {
+		mapmap := mmma[key1]
+		for key2, rSubmap := range rMapMap {
+			if len(rSubmap) == 0 {
+				continue
+			}
+			if mapmap == nil {
+				mapmap = MapMapAny[K2, K3, V]{}
+				mmma[key1] = mapmap
+			}
+			submap := mapmap[key2]
+			if submap == nil {
+				submap = make(map[K3]V, len(rSubmap))
+				mapmap[key2] = submap
+			}
+			for key3, rVal := range rSubmap {
+				key := Tuple3[K1, K2, K3]{key1, key2, key3}
+				source := MergeRight
+				if val, exists := submap[key3]; exists {
+					rVal = resolve(key, val, rVal)
+					source = MergeBoth
+				}
+				submap[key3] = rVal
+				if report != nil {
+					report[key] = source
+				}
+			}
+		}
+	}
+}
+
+// Merge sets every entry of the passed-in MapMapMap into this one. See
+// MapMapMapAny.Merge.
+func (mmm MapMapMap[K1, K2, K3, V]) Merge(r MapMapMap[K1, K2, K3, V]) {
+	MapMapMapAny[K1, K2, K3, V](mmm).Merge(MapMapMapAny[K1, K2, K3, V](r))
+}
+
+// MergeFunc sets every entry of the passed-in MapMapMap into this one,
+// resolving collisions with the passed-in function. See
+// MapMapMapAny.MergeFunc.
+func (mmm MapMapMap[K1, K2, K3, V]) MergeFunc(
+	r MapMapMap[K1, K2, K3, V],
+	resolve func(key Tuple3[K1, K2, K3], l, r V) V,
+) {
+	MapMapMapAny[K1, K2, K3, V](mmm).MergeFunc(MapMapMapAny[K1, K2, K3, V](r), resolve)
+}
+
+// MergeFuncReport merges as MergeFunc does, and returns where each entry
+// of the merged map came from.
+func (mmm MapMapMap[K1, K2, K3, V]) MergeFuncReport(
+	r MapMapMap[K1, K2, K3, V],
+	resolve func(key Tuple3[K1, K2, K3], l, r V) V,
+) map[Tuple3[K1, K2, K3]]MergeSource {
+	return MapMapMapAny[K1, K2, K3, V](mmm).MergeFuncReport(
+		MapMapMapAny[K1, K2, K3, V](r),
+		resolve,
+	)
+}
+
+// Merge adds every value of the passed-in MapSet into this one, so that
+// where both have a set for the same key, the result is their union.
+//
+// This will panic if called on a nil MapSet.
+func (ms MapSet[K, V]) Merge(r MapSet[K, V]) {
+	if ms == nil {
+		panic("Merge called on a nil MapSet")
+	}
+	ms.MergeFunc(r, func(_ K, l, r Set[V]) Set[V] { return l.Union(r) })
+}
+
+// MergeFunc sets every set of the passed-in MapSet into this one. Where
+// both have a set for the same key, resolve is called with the key, this
+// MapSet's set and the passed-in MapSet's set, and its result is stored.
+// resolve may modify and return this MapSet's set, but must not keep
+// the passed-in one, which belongs to the other MapSet. If it returns an
+// empty set, the key is removed.
+//
+// Sets of the passed-in MapSet are copied, never shared, and empty ones
+// are skipped.
+//
+// This will panic if called on a nil MapSet.
+func (ms MapSet[K, V]) MergeFunc(
+	r MapSet[K, V],
+	resolve func(key K, l, r Set[V]) Set[V],
+) {
+	if ms == nil {
+		panic("MergeFunc called on a nil MapSet")
+	}
+	ms.merge(r, resolve, nil)
+}
+
+// MergeFuncReport merges as MergeFunc does, and returns where the set for
+// each key of the merged MapSet came from.
+//
+// This will panic if called on a nil MapSet.
+func (ms MapSet[K, V]) MergeFuncReport(
+	r MapSet[K, V],
+	resolve func(key K, l, r Set[V]) Set[V],
+) map[K]MergeSource {
+	if ms == nil {
+		panic("MergeFuncReport called on a nil MapSet")
+	}
+	report := map[K]MergeSource{}
+	for key, set := range ms {
+		if len(set) != 0 {
+			report[key] = MergeLeft
+		}
+	}
+	ms.merge(r, resolve, report)
+	return report
+}
+
+func (ms MapSet[K, V]) merge(
+	r MapSet[K, V],
+	resolve func(key K, l, r Set[V]) Set[V],
+	report map[K]MergeSource,
+) {
+	for key, rSet := range r {
+		if len(rSet) == 0 {
+			continue
+		}
+		set := ms[key]
+		if len(set) == 0 {
+			ms[key] = maps.Clone(rSet)
+			if report != nil {
+				report[key] = MergeRight
+			}
+			continue
+		}
+		set = resolve(key, set, rSet)
+		if len(set) == 0 {
+			delete(ms, key)
+			if report != nil {
+				delete(report, key)
+			}
+			continue
+		}
+		ms[key] = set
+		if report != nil {
+			report[key] = MergeBoth
+		}
+	}
+}
+
+// Merge sets every entry of the passed-in DualMap into this one, keeping
+// the Primary and Reverse maps consistent. Where both have a value for
+// the same keys, the passed-in value wins.
+func (dm *DualMap[P, S, V]) Merge(r *DualMap[P, S, V]) {
+	dm.MergeFunc(r, func(_ Tuple2[P, S], _, rVal V) V { return rVal })
+}
+
+// MergeFunc sets every entry of the passed-in DualMap into this one,
+// keeping the Primary and Reverse maps consistent. Where both have a
+// value for the same keys, resolve is called with the keys in
+// primary/secondary order, this map's value and the passed-in map's
+// value, and its result is stored.
+func (dm *DualMap[P, S, V]) MergeFunc(
+	r *DualMap[P, S, V],
+	resolve func(key Tuple2[P, S], l, r V) V,
+) {
+	dm.merge(r, resolve, nil)
+}
+
+// MergeFuncReport merges as MergeFunc does, and returns where each entry
+// of the merged map came from, keyed in primary/secondary order.
+func (dm *DualMap[P, S, V]) MergeFuncReport(
+	r *DualMap[P, S, V],
+	resolve func(key Tuple2[P, S], l, r V) V,
+) map[Tuple2[P, S]]MergeSource {
+	report := map[Tuple2[P, S]]MergeSource{}
+	for key := range dm.Primary.Keys() {
+		report[key] = MergeLeft
+	}
+	dm.merge(r, resolve, report)
+	return report
+}
+
+func (dm *DualMap[P, S, V]) merge(
+	r *DualMap[P, S, V],
+	resolve func(key Tuple2[P, S], l, r V) V,
+	report map[Tuple2[P, S]]MergeSource,
+) {
+	for p, rSubmap := range r.Primary {
+		for s, rVal := range rSubmap {
+			key := Tuple2[P, S]{p, s}
+			source := MergeRight
+			if val, exists := dm.Primary[p][s]; exists {
+				rVal = resolve(key, val, rVal)
+				source = MergeBoth
+			}
+			dm.Set(p, s, rVal)
+			if report != nil {
+				report[key] = source
+			}
+		}
+	}
+}
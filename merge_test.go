@@ -0,0 +1,254 @@
+package cm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapMapMerge(t *testing.T) {
+	defaults := MapMap[string, string, int]{}
+	defaults.Set("server", "port", 80)
+	defaults.Set("server", "timeout", 30)
+	defaults.Set("client", "retries", 3)
+
+	overrides := MapMap[string, string, int]{}
+	overrides.Set("server", "port", 8080)
+	overrides.Set("log", "level", 2)
+	overrides["empty"] = map[string]int{}
+
+	merged := defaults.Clone()
+	merged.Merge(overrides)
+
+	expected := MapMap[string, string, int]{
+		"server": {"port": 8080, "timeout": 30},
+		"client": {"retries": 3},
+		"log":    {"level": 2},
+	}
+	if !merged.Equal(expected) {
+		t.Fatal("Merge didn't work:", merged)
+	}
+	if _, exists := merged["empty"]; exists {
+		t.Fatal("Merge copied an empty submap")
+	}
+	merged.Set("log", "level", 5)
+	if overrides["log"]["level"] != 2 {
+		t.Fatal("Merge shared a submap")
+	}
+
+	merged = defaults.Clone()
+	var collisions []Tuple2[string, string]
+	report := merged.MergeFuncReport(
+		overrides,
+		func(key Tuple2[string, string], l, r int) int {
+			collisions = append(collisions, key)
+			return l + r
+		},
+	)
+	if merged["server"]["port"] != 8160 {
+		t.Fatal("MergeFuncReport didn't resolve")
+	}
+	if !reflect.DeepEqual(collisions, []Tuple2[string, string]{{"server", "port"}}) {
+		t.Fatal("resolver called with the wrong keys:", collisions)
+	}
+	if !reflect.DeepEqual(report, map[Tuple2[string, string]]MergeSource{
+		{"server", "port"}:    MergeBoth,
+		{"server", "timeout"}: MergeLeft,
+		{"client", "retries"}: MergeLeft,
+		{"log", "level"}:      MergeRight,
+	}) {
+		t.Fatal("incorrect report:", report)
+	}
+
+	merged = defaults.Clone()
+	merged.MergeFunc(overrides, func(_ Tuple2[string, string], l, _ int) int { return l })
+	if merged["server"]["port"] != 80 || merged["log"]["level"] != 2 {
+		t.Fatal("MergeFunc didn't work")
+	}
+
+	panics(t, "merging into a nil map", func() {
+		MapMap[string, string, int](nil).Merge(overrides)
+	})
+	panics(t, "merging into a nil map", func() {
+		MapMap[string, string, int](nil).MergeFunc(nil, nil)
+	})
+	panics(t, "merging into a nil map", func() {
+		MapMap[string, string, int](nil).MergeFuncReport(nil, nil)
+	})
+}
+
+func TestMapMapMapMerge(t *testing.T) {
+	l := MapMapMap[int, int, int, int]{}
+	l.Set(1, 1, 1, 1)
+	l.Set(1, 2, 1, 2)
+
+	r := MapMapMap[int, int, int, int]{}
+	r.Set(1, 1, 1, 10)
+	r.Set(1, 1, 2, 20)
+	r.Set(2, 1, 1, 30)
+	r[3] = MapMapAny[int, int, int]{1: {}}
+
+	merged := l.Clone()
+	merged.Merge(r)
+	expected := MapMapMap[int, int, int, int]{}
+	expected.Set(1, 1, 1, 10)
+	expected.Set(1, 1, 2, 20)
+	expected.Set(1, 2, 1, 2)
+	expected.Set(2, 1, 1, 30)
+	if !merged.Equal(expected) {
+		t.Fatal("Merge didn't work:", merged)
+	}
+	if _, exists := merged[3]; exists {
+		t.Fatal("Merge copied an empty submap")
+	}
+
+	merged = l.Clone()
+	merged.MergeFunc(r, func(_ Tuple3[int, int, int], l, _ int) int { return l })
+	if merged[1][1][1] != 1 {
+		t.Fatal("MergeFunc didn't work")
+	}
+
+	merged = l.Clone()
+	report := merged.MergeFuncReport(
+		r,
+		func(key Tuple3[int, int, int], l, r int) int {
+			if key != (Tuple3[int, int, int]{1, 1, 1}) {
+				t.Fatal("resolver called with the wrong key:", key)
+			}
+			return l + r
+		},
+	)
+	if merged[1][1][1] != 11 {
+		t.Fatal("MergeFuncReport didn't resolve")
+	}
+	if !reflect.DeepEqual(report, map[Tuple3[int, int, int]]MergeSource{
+		{1, 1, 1}: MergeBoth,
+		{1, 1, 2}: MergeRight,
+		{1, 2, 1}: MergeLeft,
+		{2, 1, 1}: MergeRight,
+	}) {
+		t.Fatal("incorrect report:", report)
+	}
+}
+
+func TestMapSetMerge(t *testing.T) {
+	l := MapSet[int, int]{}
+	l.Add(1, 1)
+	l.Add(2, 2)
+
+	r := MapSet[int, int]{}
+	r.Add(1, 2)
+	r.Add(3, 3)
+	r[4] = Set[int]{}
+
+	merged := MapSet[int, int]{}
+	merged.Merge(l)
+	merged.Merge(r)
+	if !EqualMapSets[int, int](merged, MapSet[int, int]{
+		1: SetFromSlice([]int{1, 2}),
+		2: SetFromSlice([]int{2}),
+		3: SetFromSlice([]int{3}),
+	}) {
+		t.Fatal("Merge didn't work:", merged)
+	}
+	merged.Add(3, 4)
+	if r[3].Contains(4) {
+		t.Fatal("Merge shared a set")
+	}
+
+	merged = MapSet[int, int]{}
+	merged.Merge(l)
+	report := merged.MergeFuncReport(r, func(_ int, l, r Set[int]) Set[int] {
+		return l.Subtract(r).Subtract(SetFromSlice([]int{1}))
+	})
+	if _, exists := merged[1]; exists {
+		t.Fatal("MergeFunc didn't remove an emptied set")
+	}
+	if !reflect.DeepEqual(report, map[int]MergeSource{
+		2: MergeLeft,
+		3: MergeRight,
+	}) {
+		t.Fatal("incorrect report:", report)
+	}
+
+	merged = MapSet[int, int]{}
+	merged.Merge(l)
+	report = merged.MergeFuncReport(r, func(_ int, _, r Set[int]) Set[int] {
+		return r.Clone()
+	})
+	if !merged[1].Equal(SetFromSlice([]int{2})) || report[1] != MergeBoth {
+		t.Fatal("MergeFuncReport didn't resolve")
+	}
+}
+
+func TestMapSetMergeNil(t *testing.T) {
+	panics(t, "merging into a nil MapSet", func() {
+		MapSet[int, int](nil).Merge(nil)
+	})
+	panics(t, "merging into a nil MapSet", func() {
+		MapSet[int, int](nil).MergeFunc(nil, nil)
+	})
+	panics(t, "merging into a nil MapSet", func() {
+		MapSet[int, int](nil).MergeFuncReport(nil, nil)
+	})
+}
+
+func TestDualMapMerge(t *testing.T) {
+	l := &DualMap[int, string, int]{}
+	l.Set(1, "a", 1)
+	l.Set(2, "b", 2)
+
+	r := &DualMap[int, string, int]{}
+	r.Set(1, "a", 10)
+	r.Set(3, "a", 3)
+
+	expected := MapMapAny[int, string, int]{
+		1: {"a": 10},
+		2: {"b": 2},
+		3: {"a": 3},
+	}
+
+	merged := &DualMap[int, string, int]{}
+	merged.Merge(l)
+	merged.Merge(r)
+	if !reflect.DeepEqual(merged.Primary, expected) {
+		t.Fatal("Merge didn't work:", merged.Primary)
+	}
+	if !reflect.DeepEqual(merged.Reverse, expected.Transpose()) {
+		t.Fatal("Merge didn't keep Reverse consistent:", merged.Reverse)
+	}
+
+	merged = &DualMap[int, string, int]{}
+	merged.Merge(l)
+	report := merged.MergeFuncReport(r, func(key Tuple2[int, string], l, r int) int {
+		if key != (Tuple2[int, string]{1, "a"}) {
+			t.Fatal("resolver called with the wrong key:", key)
+		}
+		return l + r
+	})
+	if val, _ := merged.Primary.GetByTuple(Tuple2[int, string]{1, "a"}); val != 11 {
+		t.Fatal("MergeFuncReport didn't resolve")
+	}
+	if val, _ := merged.Reverse.GetByTuple(Tuple2[string, int]{"a", 1}); val != 11 {
+		t.Fatal("MergeFuncReport didn't update Reverse")
+	}
+	if !reflect.DeepEqual(report, map[Tuple2[int, string]]MergeSource{
+		{1, "a"}: MergeBoth,
+		{2, "b"}: MergeLeft,
+		{3, "a"}: MergeRight,
+	}) {
+		t.Fatal("incorrect report:", report)
+	}
+}
+
+func TestMergeSourceString(t *testing.T) {
+	for source, expected := range map[MergeSource]string{
+		MergeLeft:      "left",
+		MergeRight:     "right",
+		MergeBoth:      "both",
+		MergeSource(0): "MergeSource(0)",
+	} {
+		if source.String() != expected {
+			t.Fatal("incorrect string for", int(source))
+		}
+	}
+}
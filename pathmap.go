@@ -0,0 +1,393 @@
+package cm
+
+import (
+	"iter"
+	"slices"
+)
+
+// PathMap is a multi-level map of arbitrary depth, keyed by a path of
+// keys. It generalizes MapMapAny and MapMapMapAny for the cases where
+// you need four or more levels, or where different entries live at
+// different depths.
+//
+// Because Go generics can not be variadic, every level of a PathMap
+// shares the same key type. If your levels have different key types,
+// you either need an interface type as the key, or a fixed-depth map.
+//
+// A PathMap is a map from the first key of the path to a *PathNode,
+// which holds the value at that path (if any) and the children beneath
+// it. A value may be stored at any depth, including in a node that also
+// has children, so a PathMap can also be used as a trie.
+//
+// As with the other maps in this package, direct read access is
+// fine. The Delete methods will clean up any nodes left with no value
+// and no children; if you write directly to the nodes you are
+// responsible for that yourself, or else empty nodes may show up in
+// KeyTree.
+//
+// The empty path has no place to store a value. Set will panic if given
+// one, Get will report it as not existing, and Delete ignores it.
+type PathMap[K comparable, V any] map[K]*PathNode[K, V]
+
+// PathNode is a single node in a PathMap.
+type PathNode[K comparable, V any] struct {
+	Value    V
+	HasValue bool
+	Children PathMap[K, V]
+}
+
+// PathKeyTree is a data type that can represent the keys of a PathMap
+// via a tree structure. HasValue indicates whether the path ending at
+// this key has a value set on it.
+type PathKeyTree[K comparable] struct {
+	Key      K
+	HasValue bool
+	Children []PathKeyTree[K]
+}
+
+// PathMapFromMapMap creates a PathMap out of a MapMapAny whose keys are
+// of the same type. All paths in the resulting PathMap will be of length
+// two.
+func PathMapFromMapMap[K comparable, V any](mma MapMapAny[K, K, V]) PathMap[K, V] {
+	pm := PathMap[K, V]{}
+	for key1, submap := range mma {
+		for key2, val := range submap {
+			pm.Set([]K{key1, key2}, val)
+		}
+	}
+	return pm
+}
+
+// PathMapFromMapMapMap creates a PathMap out of a MapMapMapAny whose
+// keys are all of the same type. All paths in the resulting PathMap will
+// be of length three.
+func PathMapFromMapMapMap[K comparable, V any](mmma MapMapMapAny[K, K, K, V]) PathMap[K, V] {
+	pm := PathMap[K, V]{}
+	for key1, mapmap := range mmma {
+		for key2, submap := range mapmap {
+			for key3, val := range submap {
+				pm.Set([]K{key1, key2, key3}, val)
+			}
+		}
+	}
+	return pm
+}
+
+// Set will set the given value at the given path.
+//
+// This will panic if called on a nil map, or with an empty path.
+func (pm PathMap[K, V]) Set(path []K, value V) {
+	if pm == nil {
+		panic("Set called on a nil PathMap")
+	}
+	if len(path) == 0 {
+		panic("Set called on a PathMap with an empty path")
+	}
+
+	node := pm.vivify(path)
+	node.Value = value
+	node.HasValue = true
+}
+
+// vivify returns the node at the given path, creating it and any
+// intermediate nodes as necessary.
+func (pm PathMap[K, V]) vivify(path []K) *PathNode[K, V] {
+	m := pm
+	var node *PathNode[K, V]
+	for i, key := range path {
+		node = m[key]
+		if node == nil {
+			node = &PathNode[K, V]{}
+			m[key] = node
+		}
+		if i == len(path)-1 {
+			break
+		}
+		if node.Children == nil {
+			node.Children = PathMap[K, V]{}
+		}
+		m = node.Children
+	}
+	return node
+}
+
+// node returns the node at the given path, or nil if it does not exist.
+func (pm PathMap[K, V]) node(path []K) *PathNode[K, V] {
+	if len(path) == 0 {
+		return nil
+	}
+	m := pm
+	var node *PathNode[K, V]
+	for _, key := range path {
+		node = m[key]
+		if node == nil {
+			return nil
+		}
+		m = node.Children
+	}
+	return node
+}
+
+// Get retrieves the value at the given path. The second value is true
+// if the value exists, false otherwise.
+//
+// Get called on a nil PathMap will not panic, and return that the value
+// was not found.
+func (pm PathMap[K, V]) Get(path []K) (val V, exists bool) {
+	node := pm.node(path)
+	if node == nil || !node.HasValue {
+		return val, false
+	}
+	return node.Value, true
+}
+
+// Delete deletes the value at the given path. Any nodes left with
+// neither a value nor children are removed from the PathMap.
+func (pm PathMap[K, V]) Delete(path []K) {
+	if len(path) == 0 {
+		return
+	}
+	pm.delete(path)
+}
+
+// delete recursively deletes the given path, cleaning up on the way back
+// out.
+func (pm PathMap[K, V]) delete(path []K) {
+	node := pm[path[0]]
+	if node == nil {
+		return
+	}
+	if len(path) == 1 {
+		var zero V
+		node.Value = zero
+		node.HasValue = false
+	} else {
+		node.Children.delete(path[1:])
+	}
+	if !node.HasValue && len(node.Children) == 0 {
+		delete(pm, path[0])
+	}
+}
+
+// Sub returns the PathMap beneath the given prefix. The returned
+// PathMap is not a copy; writes to it will be reflected in this
+// PathMap. If nothing is beneath the given prefix, whether or not the
+// prefix exists, a nil PathMap is returned, which can be read but not
+// written; to write beneath such a prefix, Set the full path instead.
+//
+// Sub does not modify the PathMap, so it is as safe as any other read.
+//
+// An empty prefix returns the PathMap itself.
+func (pm PathMap[K, V]) Sub(prefix []K) PathMap[K, V] {
+	if len(prefix) == 0 {
+		return pm
+	}
+	node := pm.node(prefix)
+	if node == nil {
+		return nil
+	}
+	return node.Children
+}
+
+// Clone returns a copy of the PathMap structure. It's a shallow copy of
+// the full PathMap, with the values simply copied across.
+func (pm PathMap[K, V]) Clone() PathMap[K, V] {
+	if pm == nil {
+		return nil
+	}
+	newMap := make(PathMap[K, V], len(pm))
+	for key, node := range pm {
+		newMap[key] = &PathNode[K, V]{
+			Value:    node.Value,
+			HasValue: node.HasValue,
+			Children: node.Children.Clone(),
+		}
+	}
+	return newMap
+}
+
+// DeleteFunc deletes from the map the values for which the function
+// returns true. Any nodes left with neither a value nor children are
+// removed from the PathMap.
+//
+// The path passed to the function is only valid for the duration of the
+// call; clone it if you need to retain it.
+func (pm PathMap[K, V]) DeleteFunc(f func([]K, V) bool) {
+	pm.deleteFunc(nil, f)
+}
+
+func (pm PathMap[K, V]) deleteFunc(prefix []K, f func([]K, V) bool) {
+	for key, node := range pm {
+		path := append(prefix, key)
+		if node.HasValue && f(path, node.Value) {
+			var zero V
+			node.Value = zero
+			node.HasValue = false
+		}
+		node.Children.deleteFunc(path, f)
+		if !node.HasValue && len(node.Children) == 0 {
+			delete(pm, key)
+		}
+	}
+}
+
+// All returns an iterator over the map that yields the path of each
+// value in the key slot and the value in the value slot.
+//
+// Each path yielded is a freshly-allocated slice which may be retained.
+func (pm PathMap[K, V]) All() iter.Seq2[[]K, V] {
+	return func(yield func([]K, V) bool) {
+		pm.walk(nil, yield)
+	}
+}
+
+// AllWithPrefix returns an iterator that yields every path and value in
+// the map at or beneath the given prefix. The paths yielded include the
+// prefix.
+func (pm PathMap[K, V]) AllWithPrefix(prefix []K) iter.Seq2[[]K, V] {
+	return func(yield func([]K, V) bool) {
+		if len(prefix) == 0 {
+			pm.walk(nil, yield)
+			return
+		}
+		node := pm.node(prefix)
+		if node == nil {
+			return
+		}
+		if node.HasValue && !yield(slices.Clone(prefix), node.Value) {
+			return
+		}
+		node.Children.walk(slices.Clip(prefix), yield)
+	}
+}
+
+// walk yields everything in the map, returning false if iteration was
+// terminated.
+func (pm PathMap[K, V]) walk(prefix []K, yield func([]K, V) bool) bool {
+	for key, node := range pm {
+		path := append(prefix, key)
+		if node.HasValue && !yield(slices.Clone(path), node.Value) {
+			return false
+		}
+		if !node.Children.walk(path, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Keys returns an iterator over the paths that have values in the map.
+//
+// Each path yielded is a freshly-allocated slice which may be retained.
+func (pm PathMap[K, V]) Keys() iter.Seq[[]K] {
+	return func(yield func([]K) bool) {
+		for path := range pm.All() {
+			if !yield(path) {
+				return
+			}
+		}
+	}
+}
+
+// KeySlice returns the paths that have values in the map as a slice.
+//
+// A nil map will return a nil slice.
+func (pm PathMap[K, V]) KeySlice() [][]K {
+	if pm == nil {
+		return nil
+	}
+
+	r := [][]K{}
+	for path := range pm.All() {
+		r = append(r, path)
+	}
+	return r
+}
+
+// KeyTree returns the keys of the PathMap as a tree.
+//
+// A nil map will return a nil slice.
+func (pm PathMap[K, V]) KeyTree() []PathKeyTree[K] {
+	if pm == nil {
+		return nil
+	}
+
+	r := make([]PathKeyTree[K], 0, len(pm))
+	for key, node := range pm {
+		r = append(r, PathKeyTree[K]{
+			Key:      key,
+			HasValue: node.HasValue,
+			Children: node.Children.KeyTree(),
+		})
+	}
+	return r
+}
+
+// Values returns an iterator for all values in this PathMap in a
+// nondeterministic order.
+func (pm PathMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		pm.values(yield)
+	}
+}
+
+func (pm PathMap[K, V]) values(yield func(V) bool) bool {
+	for _, node := range pm {
+		if node.HasValue && !yield(node.Value) {
+			return false
+		}
+		if !node.Children.values(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the total number of values in the PathMap.
+func (pm PathMap[K, V]) Len() int {
+	l := 0
+	for _, node := range pm {
+		if node.HasValue {
+			l++
+		}
+		l += node.Children.Len()
+	}
+	return l
+}
+
+// MapMap returns a MapMapAny containing all the values in this PathMap
+// with paths of exactly length two. Combined with Sub, this allows
+// pulling a MapMapAny out of any level of the PathMap.
+//
+// The values are copied into a new MapMapAny; writes to it will not be
+// reflected in the PathMap.
+func (pm PathMap[K, V]) MapMap() MapMapAny[K, K, V] {
+	mma := MapMapAny[K, K, V]{}
+	for key1, node1 := range pm {
+		for key2, node2 := range node1.Children {
+			if node2.HasValue {
+				mma.Set(key1, key2, node2.Value)
+			}
+		}
+	}
+	return mma
+}
+
+// MapMapMap returns a MapMapMapAny containing all the values in this
+// PathMap with paths of exactly length three.
+//
+// The values are copied into a new MapMapMapAny; writes to it will not
+// be reflected in the PathMap.
+func (pm PathMap[K, V]) MapMapMap() MapMapMapAny[K, K, K, V] {
+	mmma := MapMapMapAny[K, K, K, V]{}
+	for key1, node1 := range pm {
+		for key2, node2 := range node1.Children {
+			for key3, node3 := range node2.Children {
+				if node3.HasValue {
+					mmma.Set(key1, key2, key3, node3.Value)
+				}
+			}
+		}
+	}
+	return mmma
+}
@@ -0,0 +1,261 @@
+package cm
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestPathMap(t *testing.T) {
+	pm := PathMap[string, int]{}
+
+	pm.Set([]string{"a", "b", "c", "d"}, 1)
+	pm.Set([]string{"a", "b", "c", "e"}, 2)
+	pm.Set([]string{"a", "x"}, 3)
+	pm.Set([]string{"a"}, 4)
+
+	val, exists := pm.Get([]string{"a", "b", "c", "d"})
+	if val != 1 || !exists {
+		t.Fatal("couldn't get a set value")
+	}
+	val, exists = pm.Get([]string{"a"})
+	if val != 4 || !exists {
+		t.Fatal("couldn't get a value set on an interior node")
+	}
+	_, exists = pm.Get([]string{"a", "b"})
+	if exists {
+		t.Fatal("interior node without a value claims to exist")
+	}
+	_, exists = pm.Get([]string{"q", "r"})
+	if exists {
+		t.Fatal("can get things that don't exist")
+	}
+	_, exists = pm.Get(nil)
+	if exists {
+		t.Fatal("empty path claims to exist")
+	}
+
+	if pm.Len() != 4 {
+		t.Fatalf("incorrect Len: %d", pm.Len())
+	}
+
+	keys := pm.KeySlice()
+	slices.SortFunc(keys, slices.Compare)
+	if !reflect.DeepEqual(keys, [][]string{
+		{"a"},
+		{"a", "b", "c", "d"},
+		{"a", "b", "c", "e"},
+		{"a", "x"},
+	}) {
+		t.Fatal("incorrect KeySlice")
+	}
+
+	values := slices.Collect(pm.Values())
+	sort.Ints(values)
+	if !reflect.DeepEqual(values, []int{1, 2, 3, 4}) {
+		t.Fatal("incorrect Values")
+	}
+
+	prefixed := map[string]int{}
+	for path, val := range pm.AllWithPrefix([]string{"a", "b"}) {
+		if len(path) != 4 {
+			t.Fatal("prefix query doesn't include the prefix")
+		}
+		prefixed[path[3]] = val
+	}
+	if !reflect.DeepEqual(prefixed, map[string]int{"d": 1, "e": 2}) {
+		t.Fatal("incorrect prefix query")
+	}
+	count := 0
+	for range pm.AllWithPrefix([]string{"a"}) {
+		count++
+	}
+	if count != 4 {
+		t.Fatal("prefix query doesn't include the prefix's own value")
+	}
+	for range pm.AllWithPrefix([]string{"nope"}) {
+		t.Fatal("prefix query for nonexistent prefix yields values")
+	}
+
+	sub := pm.Sub([]string{"a", "b", "c"})
+	if !reflect.DeepEqual(sub.MapMap(), MapMapAny[string, string, int]{}) ||
+		sub.Len() != 2 {
+		t.Fatal("incorrect Sub")
+	}
+	if pm.Sub(nil).Len() != pm.Len() {
+		t.Fatal("Sub of empty prefix isn't the PathMap")
+	}
+	if pm.Sub([]string{"nope"}) != nil {
+		t.Fatal("Sub of nonexistent prefix not nil")
+	}
+
+	leaf := PathMap[string, int]{}
+	leaf.Set([]string{"a"}, 1)
+	if leaf.Sub([]string{"a"}) != nil {
+		t.Fatal("Sub of a leaf not nil")
+	}
+	if tree := leaf.KeyTree(); tree[0].Children != nil {
+		t.Fatal("Sub of a leaf left an empty PathMap behind")
+	}
+
+	tree := pm.KeyTree()
+	if len(tree) != 1 || tree[0].Key != "a" || !tree[0].HasValue ||
+		len(tree[0].Children) != 2 {
+		t.Fatal("incorrect key tree")
+	}
+
+	clone := pm.Clone()
+	clone.Set([]string{"a", "b", "c", "f"}, 5)
+	if pm.Len() != 4 || clone.Len() != 5 {
+		t.Fatal("clone is not independent")
+	}
+
+	// Deleting the interior value leaves the children.
+	pm.Delete([]string{"a"})
+	if pm.Len() != 3 || pm["a"] == nil {
+		t.Fatal("deleting an interior value didn't work")
+	}
+
+	pm.Delete([]string{"a", "b", "c", "d"})
+	pm.Delete([]string{"a", "b", "nope", "nope"})
+	pm.Delete(nil)
+	if pm.Len() != 2 {
+		t.Fatal("delete didn't work")
+	}
+	pm.Delete([]string{"a", "b", "c", "e"})
+	if pm["a"].Children["b"] != nil {
+		t.Fatal("delete didn't clean up empty nodes")
+	}
+	pm.Delete([]string{"a", "x"})
+	if !reflect.DeepEqual(pm, PathMap[string, int]{}) {
+		t.Fatal("delete didn't clean up to the root")
+	}
+}
+
+func TestPathMapDeleteFunc(t *testing.T) {
+	pm := PathMap[int, int]{}
+	pm.Set([]int{0, 1, 2}, 3)
+	pm.Set([]int{0, 1}, 4)
+	pm.Set([]int{1, 2, 3, 4}, 5)
+
+	pm.DeleteFunc(func(path []int, val int) bool {
+		return path[0] == 0
+	})
+
+	target := PathMap[int, int]{}
+	target.Set([]int{1, 2, 3, 4}, 5)
+	if !reflect.DeepEqual(pm, target) {
+		t.Fatal("DeleteFunc did not operate correctly")
+	}
+
+	var nilPM PathMap[int, int]
+	nilPM.DeleteFunc(func([]int, int) bool { return true })
+}
+
+func TestPathMapConversion(t *testing.T) {
+	mm := MapMapAny[int, int, int]{}
+	mm.Set(0, 1, 2)
+	mm.Set(0, 2, 3)
+	mm.Set(1, 1, 4)
+
+	pm := PathMapFromMapMap(mm)
+	if pm.Len() != 3 {
+		t.Fatal("incorrect conversion from MapMap")
+	}
+	pm.Set([]int{5}, 6)
+	pm.Set([]int{5, 6, 7}, 8)
+	if !reflect.DeepEqual(pm.MapMap(), mm) {
+		t.Fatal("incorrect conversion to MapMap")
+	}
+
+	mmm := MapMapMapAny[int, int, int, int]{}
+	mmm.Set(0, 1, 2, 3)
+	mmm.Set(0, 1, 3, 4)
+	mmm.Set(1, 2, 3, 4)
+
+	pm = PathMapFromMapMapMap(mmm)
+	if pm.Len() != 3 {
+		t.Fatal("incorrect conversion from MapMapMap")
+	}
+	pm.Set([]int{5, 6}, 7)
+	if !reflect.DeepEqual(pm.MapMapMap(), mmm) {
+		t.Fatal("incorrect conversion to MapMapMap")
+	}
+
+	// level-by-level migration: pull a MapMap out from under a prefix
+	pm = PathMap[int, int]{}
+	pm.Set([]int{9, 0, 1}, 2)
+	if !reflect.DeepEqual(pm.Sub([]int{9}).MapMap(),
+		MapMapAny[int, int, int]{0: {1: 2}}) {
+		t.Fatal("couldn't pull a MapMap from a sub path")
+	}
+}
+
+func TestPathMapIteration(t *testing.T) {
+	pm := PathMap[int, int]{}
+	pm.Set([]int{0, 1}, 10)
+	pm.Set([]int{0, 1, 2}, 10)
+	pm.Set([]int{3}, 10)
+
+	count := 0
+	for path, val := range pm.All() {
+		count++
+		if val != 10 {
+			t.Fatal("incorrect value in iteration")
+		}
+		// paths may be retained, so they must not be shared
+		path[0] = 99
+	}
+	if count != 3 {
+		t.Fatal("incorrect number of values")
+	}
+
+	count = 0
+	for range pm.Keys() {
+		count++
+	}
+	if count != 3 {
+		t.Fatal("incorrect number of keys")
+	}
+
+	for range pm.Values() {
+		break
+	}
+	for range pm.Keys() {
+		break
+	}
+	for range pm.All() {
+		break
+	}
+	for range pm.AllWithPrefix([]int{0}) {
+		break
+	}
+	for range pm.AllWithPrefix([]int{0, 1}) {
+		break
+	}
+	for range pm.AllWithPrefix(nil) {
+		break
+	}
+}
+
+func TestNilPathMap(t *testing.T) {
+	var pm PathMap[int, int]
+
+	panics(t, "failed on set", func() { pm.Set([]int{0}, 1) })
+	panics(t, "failed on set with empty path",
+		func() { PathMap[int, int]{}.Set(nil, 1) })
+
+	// doesn't panic
+	pm.Delete([]int{0, 1})
+
+	if pm.KeySlice() != nil {
+		t.Fatal("incorrect KeySlice from nil map")
+	}
+	if pm.KeyTree() != nil {
+		t.Fatal("incorrect KeyTree from nil map")
+	}
+	if pm.Clone() != nil {
+		t.Fatal("incorrect Clone from nil map")
+	}
+}
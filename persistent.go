@@ -0,0 +1,440 @@
+package cm
+
+import "iter"
+
+// PersistentSet is an immutable Set. See the discussion of the
+// Persistent types in the package documentation.
+type PersistentSet[M comparable] struct {
+	h hamt[M, struct{}]
+}
+
+// PersistentSetFromSet returns a PersistentSet with the same contents as
+// the passed-in Set.
+func PersistentSetFromSet[M comparable](s Set[M]) PersistentSet[M] {
+	var b PersistentSetBuilder[M]
+	for val := range s {
+		b.Add(val)
+	}
+	return b.Persistent()
+}
+
+// With returns a version of the set that contains the given value.
+func (ps PersistentSet[M]) With(v M) PersistentSet[M] {
+	return PersistentSet[M]{ps.h.with(v, void, nil)}
+}
+
+// Without returns a version of the set that does not contain the given
+// value.
+func (ps PersistentSet[M]) Without(v M) PersistentSet[M] {
+	return PersistentSet[M]{ps.h.without(v, nil)}
+}
+
+// Contains returns true if the set contains the given value.
+func (ps PersistentSet[M]) Contains(v M) bool {
+	_, exists := ps.h.get(v)
+	return exists
+}
+
+// Len returns the number of values in the set.
+func (ps PersistentSet[M]) Len() int {
+	return ps.h.size
+}
+
+// All returns an iterator over the values in the set.
+func (ps PersistentSet[M]) All() iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for val := range ps.h.all() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// AsSet returns a new Set with the same contents as this set.
+func (ps PersistentSet[M]) AsSet() Set[M] {
+	s := make(Set[M], ps.h.size)
+	for val := range ps.h.all() {
+		s[val] = void
+	}
+	return s
+}
+
+// Builder returns a PersistentSetBuilder starting from this set.
+func (ps PersistentSet[M]) Builder() *PersistentSetBuilder[M] {
+	return &PersistentSetBuilder[M]{h: ps.h}
+}
+
+// PersistentSetBuilder is a mutable, transient version of a
+// PersistentSet, for making many changes at once.
+type PersistentSetBuilder[M comparable] struct {
+	h     hamt[M, struct{}]
+	owner *hamtOwner
+}
+
+func (psb *PersistentSetBuilder[M]) edit() *hamtOwner {
+	if psb.owner == nil {
+		psb.owner = &hamtOwner{}
+	}
+	return psb.owner
+}
+
+// Add adds the given value to the set.
+func (psb *PersistentSetBuilder[M]) Add(v M) {
+	psb.h = psb.h.with(v, void, psb.edit())
+}
+
+// Remove removes the given value from the set if it exists.
+func (psb *PersistentSetBuilder[M]) Remove(v M) {
+	psb.h = psb.h.without(v, psb.edit())
+}
+
+// Contains returns true if the set contains the given value.
+func (psb *PersistentSetBuilder[M]) Contains(v M) bool {
+	_, exists := psb.h.get(v)
+	return exists
+}
+
+// Len returns the number of values in the set.
+func (psb *PersistentSetBuilder[M]) Len() int {
+	return psb.h.size
+}
+
+// Persistent returns the current contents of the Builder as a
+// PersistentSet. The Builder may continue to be used afterwards without
+// affecting the returned set.
+func (psb *PersistentSetBuilder[M]) Persistent() PersistentSet[M] {
+	// Nodes owned by the current owner are about to be shared with the
+	// returned set, so they must not be modified in place any more.
+	psb.owner = nil
+	return PersistentSet[M]{psb.h}
+}
+
+// PersistentMapMap is an immutable MapMapAny. See the discussion of the
+// Persistent types in the package documentation.
+//
+// There is no comparable-value variant, as there is no Equal method.
+type PersistentMapMap[K1, K2 comparable, V any] struct {
+	h    hamt[K1, hamt[K2, V]]
+	size int
+}
+
+// PersistentMapMapFromMapMap returns a PersistentMapMap with the same
+// contents as the passed-in MapMap.
+func PersistentMapMapFromMapMap[K1, K2 comparable, V any](
+	mma MapMapAny[K1, K2, V],
+) PersistentMapMap[K1, K2, V] {
+	var b PersistentMapMapBuilder[K1, K2, V]
+	for key, val := range mma.All() {
+		b.SetByTuple(key, val)
+	}
+	return b.Persistent()
+}
+
+// with is the implementation of With, shared with the Builder.
+func (pmm PersistentMapMap[K1, K2, V]) with(
+	key1 K1,
+	key2 K2,
+	val V,
+	owner *hamtOwner,
+) PersistentMapMap[K1, K2, V] {
+	sub, _ := pmm.h.get(key1)
+	newSub := sub.with(key2, val, owner)
+	pmm.size += newSub.size - sub.size
+	pmm.h = pmm.h.with(key1, newSub, owner)
+	return pmm
+}
+
+// without is the implementation of Without, shared with the Builder.
+func (pmm PersistentMapMap[K1, K2, V]) without(
+	key1 K1,
+	key2 K2,
+	owner *hamtOwner,
+) PersistentMapMap[K1, K2, V] {
+	sub, exists := pmm.h.get(key1)
+	if !exists {
+		return pmm
+	}
+	newSub := sub.without(key2, owner)
+	if newSub.size == sub.size {
+		return pmm
+	}
+	pmm.size--
+	if newSub.size == 0 {
+		pmm.h = pmm.h.without(key1, owner)
+	} else {
+		pmm.h = pmm.h.with(key1, newSub, owner)
+	}
+	return pmm
+}
+
+// With returns a version of the map with the given value set for the
+// given keys.
+func (pmm PersistentMapMap[K1, K2, V]) With(
+	key1 K1,
+	key2 K2,
+	val V,
+) PersistentMapMap[K1, K2, V] {
+	return pmm.with(key1, key2, val, nil)
+}
+
+// WithByTuple returns a version of the map with the given value set for
+// the key tuple.
+func (pmm PersistentMapMap[K1, K2, V]) WithByTuple(
+	key Tuple2[K1, K2],
+	val V,
+) PersistentMapMap[K1, K2, V] {
+	return pmm.with(key.Key1, key.Key2, val, nil)
+}
+
+// Without returns a version of the map without the given keys. As with
+// MapMap's Delete, if this empties the submap for key1, the submap is
+// removed as well.
+func (pmm PersistentMapMap[K1, K2, V]) Without(
+	key1 K1,
+	key2 K2,
+) PersistentMapMap[K1, K2, V] {
+	return pmm.without(key1, key2, nil)
+}
+
+// WithoutByTuple returns a version of the map without the key tuple.
+func (pmm PersistentMapMap[K1, K2, V]) WithoutByTuple(
+	key Tuple2[K1, K2],
+) PersistentMapMap[K1, K2, V] {
+	return pmm.without(key.Key1, key.Key2, nil)
+}
+
+// Get returns the value for the given keys. The second value is true if
+// the keys exist, false otherwise.
+func (pmm PersistentMapMap[K1, K2, V]) Get(key1 K1, key2 K2) (val V, exists bool) {
+	sub, _ := pmm.h.get(key1)
+	return sub.get(key2)
+}
+
+// GetByTuple returns the value for the key tuple. The second value is
+// true if the key exists, false otherwise.
+func (pmm PersistentMapMap[K1, K2, V]) GetByTuple(key Tuple2[K1, K2]) (val V, exists bool) {
+	return pmm.Get(key.Key1, key.Key2)
+}
+
+// Len returns the total number of values in the map.
+func (pmm PersistentMapMap[K1, K2, V]) Len() int {
+	return pmm.size
+}
+
+// All returns an iterator over the map that yields the keys as a Tuple2,
+// and the value in the value slot.
+func (pmm PersistentMapMap[K1, K2, V]) All() iter.Seq2[Tuple2[K1, K2], V] {
+	return func(yield func(Tuple2[K1, K2], V) bool) {
+		for key1, sub := range pmm.h.all() {
+			for key2, val := range sub.all() {
+				if !yield(Tuple2[K1, K2]{key1, key2}, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns an iterator on the keys as a Tuple2.
+func (pmm PersistentMapMap[K1, K2, V]) Keys() iter.Seq[Tuple2[K1, K2]] {
+	return func(yield func(Tuple2[K1, K2]) bool) {
+		for key := range pmm.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator for all values in this map.
+func (pmm PersistentMapMap[K1, K2, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range pmm.All() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// AsMapMap returns a new MapMapAny with the same contents as this map.
+func (pmm PersistentMapMap[K1, K2, V]) AsMapMap() MapMapAny[K1, K2, V] {
+	mma := make(MapMapAny[K1, K2, V], pmm.h.size)
+	for key1, sub := range pmm.h.all() {
+		submap := make(map[K2]V, sub.size)
+		for key2, val := range sub.all() {
+			submap[key2] = val
+		}
+		mma[key1] = submap
+	}
+	return mma
+}
+
+// Builder returns a PersistentMapMapBuilder starting from this map.
+func (pmm PersistentMapMap[K1, K2, V]) Builder() *PersistentMapMapBuilder[K1, K2, V] {
+	return &PersistentMapMapBuilder[K1, K2, V]{m: pmm}
+}
+
+// PersistentMapMapBuilder is a mutable, transient version of a
+// PersistentMapMap, for making many changes at once.
+type PersistentMapMapBuilder[K1, K2 comparable, V any] struct {
+	m     PersistentMapMap[K1, K2, V]
+	owner *hamtOwner
+}
+
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) edit() *hamtOwner {
+	if pmmb.owner == nil {
+		pmmb.owner = &hamtOwner{}
+	}
+	return pmmb.owner
+}
+
+// Set sets the given value for the given keys.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) Set(key1 K1, key2 K2, val V) {
+	pmmb.m = pmmb.m.with(key1, key2, val, pmmb.edit())
+}
+
+// SetByTuple sets the given value for the key tuple.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) SetByTuple(key Tuple2[K1, K2], val V) {
+	pmmb.Set(key.Key1, key.Key2, val)
+}
+
+// Delete deletes the value for the given keys.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) Delete(key1 K1, key2 K2) {
+	pmmb.m = pmmb.m.without(key1, key2, pmmb.edit())
+}
+
+// DeleteByTuple deletes the value for the key tuple.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) DeleteByTuple(key Tuple2[K1, K2]) {
+	pmmb.Delete(key.Key1, key.Key2)
+}
+
+// Get returns the value for the given keys. The second value is true if
+// the keys exist, false otherwise.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) Get(key1 K1, key2 K2) (V, bool) {
+	return pmmb.m.Get(key1, key2)
+}
+
+// Len returns the total number of values in the map.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) Len() int {
+	return pmmb.m.size
+}
+
+// Persistent returns the current contents of the Builder as a
+// PersistentMapMap. The Builder may continue to be used afterwards
+// without affecting the returned map.
+func (pmmb *PersistentMapMapBuilder[K1, K2, V]) Persistent() PersistentMapMap[K1, K2, V] {
+	pmmb.owner = nil
+	return pmmb.m
+}
+
+// PersistentMapSet is an immutable MapSet. See the discussion of the
+// Persistent types in the package documentation.
+type PersistentMapSet[K, V comparable] struct {
+	m PersistentMapMap[K, V, struct{}]
+}
+
+// PersistentMapSetFromMapSet returns a PersistentMapSet with the same
+// contents as the passed-in MapSet.
+func PersistentMapSetFromMapSet[K, V comparable](ms MapSet[K, V]) PersistentMapSet[K, V] {
+	var b PersistentMapSetBuilder[K, V]
+	for key, set := range ms {
+		for val := range set {
+			b.Add(key, val)
+		}
+	}
+	return b.Persistent()
+}
+
+// With returns a version of the MapSet with the given value added to the
+// set for the given key.
+func (pms PersistentMapSet[K, V]) With(key K, val V) PersistentMapSet[K, V] {
+	return PersistentMapSet[K, V]{pms.m.with(key, val, void, nil)}
+}
+
+// Without returns a version of the MapSet with the given value removed
+// from the set for the given key. As with MapSet's Delete, if this
+// empties the set, the set is removed as well.
+func (pms PersistentMapSet[K, V]) Without(key K, val V) PersistentMapSet[K, V] {
+	return PersistentMapSet[K, V]{pms.m.without(key, val, nil)}
+}
+
+// Contains returns true if the set for the given key contains the given
+// value.
+func (pms PersistentMapSet[K, V]) Contains(key K, val V) bool {
+	_, exists := pms.m.Get(key, val)
+	return exists
+}
+
+// Get returns the set for the given key. This is cheap, as the set
+// shares its structure with the MapSet.
+func (pms PersistentMapSet[K, V]) Get(key K) PersistentSet[V] {
+	sub, _ := pms.m.h.get(key)
+	return PersistentSet[V]{sub}
+}
+
+// Len returns the total number of values in all the sets.
+func (pms PersistentMapSet[K, V]) Len() int {
+	return pms.m.size
+}
+
+// All returns an iterator over every key and value pair in the MapSet.
+func (pms PersistentMapSet[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key := range pms.m.All() {
+			if !yield(key.Key1, key.Key2) {
+				return
+			}
+		}
+	}
+}
+
+// AsMapSet returns a new MapSet with the same contents as this one.
+func (pms PersistentMapSet[K, V]) AsMapSet() MapSet[K, V] {
+	ms := make(MapSet[K, V], pms.m.h.size)
+	for key, sub := range pms.m.h.all() {
+		ms[key] = PersistentSet[V]{sub}.AsSet()
+	}
+	return ms
+}
+
+// Builder returns a PersistentMapSetBuilder starting from this MapSet.
+func (pms PersistentMapSet[K, V]) Builder() *PersistentMapSetBuilder[K, V] {
+	return &PersistentMapSetBuilder[K, V]{b: PersistentMapMapBuilder[K, V, struct{}]{m: pms.m}}
+}
+
+// PersistentMapSetBuilder is a mutable, transient version of a
+// PersistentMapSet, for making many changes at once.
+type PersistentMapSetBuilder[K, V comparable] struct {
+	b PersistentMapMapBuilder[K, V, struct{}]
+}
+
+// Add adds the given value to the set for the given key.
+func (pmsb *PersistentMapSetBuilder[K, V]) Add(key K, val V) {
+	pmsb.b.Set(key, val, void)
+}
+
+// Delete removes the given value from the set for the given key.
+func (pmsb *PersistentMapSetBuilder[K, V]) Delete(key K, val V) {
+	pmsb.b.Delete(key, val)
+}
+
+// Contains returns true if the set for the given key contains the given
+// value.
+func (pmsb *PersistentMapSetBuilder[K, V]) Contains(key K, val V) bool {
+	_, exists := pmsb.b.Get(key, val)
+	return exists
+}
+
+// Len returns the total number of values in all the sets.
+func (pmsb *PersistentMapSetBuilder[K, V]) Len() int {
+	return pmsb.b.Len()
+}
+
+// Persistent returns the current contents of the Builder as a
+// PersistentMapSet. The Builder may continue to be used afterwards
+// without affecting the returned MapSet.
+func (pmsb *PersistentMapSetBuilder[K, V]) Persistent() PersistentMapSet[K, V] {
+	return PersistentMapSet[K, V]{pmsb.b.Persistent()}
+}
@@ -0,0 +1,192 @@
+package cm
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPersistentSet(t *testing.T) {
+	var empty PersistentSet[int]
+	one := empty.With(1)
+	two := one.With(2)
+	three := two.With(3).With(3)
+
+	if empty.Len() != 0 || one.Len() != 1 || two.Len() != 2 || three.Len() != 3 {
+		t.Fatal("versions don't have the right sizes")
+	}
+	if one.Contains(2) || !two.Contains(2) || empty.Contains(1) {
+		t.Fatal("versions don't have the right contents")
+	}
+
+	removed := three.Without(2).Without(9)
+	if removed.Contains(2) || !three.Contains(2) || removed.Len() != 2 {
+		t.Fatal("Without doesn't work")
+	}
+
+	vals := slices.Collect(three.All())
+	slices.Sort(vals)
+	if !reflect.DeepEqual(vals, []int{1, 2, 3}) {
+		t.Fatal("incorrect All")
+	}
+	for range three.All() {
+		break
+	}
+
+	s := SetFromSlice([]int{4, 5, 6})
+	ps := PersistentSetFromSet(s)
+	if !ps.AsSet().Equal(s) {
+		t.Fatal("Set doesn't round trip")
+	}
+
+	b := ps.Builder()
+	b.Add(7)
+	b.Remove(4)
+	if !b.Contains(7) || b.Contains(4) || b.Len() != 3 {
+		t.Fatal("Builder doesn't work")
+	}
+	built := b.Persistent()
+	b.Add(8)
+	if built.Contains(8) || !b.Contains(8) {
+		t.Fatal("Builder modified the set it returned")
+	}
+	if ps.Len() != 3 || !ps.Contains(4) || ps.Contains(7) {
+		t.Fatal("Builder modified the set it started from")
+	}
+}
+
+func TestPersistentMapMap(t *testing.T) {
+	var empty PersistentMapMap[string, int, string]
+	v1 := empty.With("a", 1, "x")
+	v2 := v1.WithByTuple(Tuple2[string, int]{"a", 2}, "y")
+	v3 := v2.With("b", 1, "z").With("a", 1, "xx")
+
+	if empty.Len() != 0 || v1.Len() != 1 || v2.Len() != 2 || v3.Len() != 3 {
+		t.Fatal("versions don't have the right sizes")
+	}
+	if val, _ := v2.Get("a", 1); val != "x" {
+		t.Fatal("update modified a previous version")
+	}
+	if val, exists := v3.GetByTuple(Tuple2[string, int]{"a", 1}); val != "xx" || !exists {
+		t.Fatal("couldn't get the updated value")
+	}
+	if _, exists := v3.Get("c", 1); exists {
+		t.Fatal("got a nonexistent value")
+	}
+
+	v4 := v3.Without("a", 1).WithoutByTuple(Tuple2[string, int]{"a", 2})
+	if v4.Len() != 1 || v4.h.size != 1 {
+		t.Fatal("Without didn't clean up the emptied submap")
+	}
+	if v4.Without("a", 1) != v4 || v4.Without("b", 9) != v4 {
+		t.Fatal("removing nonexistent values changed the map")
+	}
+
+	expected := MapMapAny[string, int, string]{}
+	expected.Set("a", 1, "xx")
+	expected.Set("a", 2, "y")
+	expected.Set("b", 1, "z")
+	if !reflect.DeepEqual(v3.AsMapMap(), expected) {
+		t.Fatal("incorrect AsMapMap")
+	}
+	if !reflect.DeepEqual(PersistentMapMapFromMapMap(expected).AsMapMap(), expected) {
+		t.Fatal("MapMap doesn't round trip")
+	}
+
+	count := 0
+	for key, val := range v3.All() {
+		if expected[key.Key1][key.Key2] != val {
+			t.Fatal("incorrect All")
+		}
+		count++
+	}
+	for range v3.Keys() {
+		count++
+	}
+	for range v3.Values() {
+		count++
+	}
+	if count != 9 {
+		t.Fatal("incorrect iteration")
+	}
+	for range v3.All() {
+		break
+	}
+	for range v3.Keys() {
+		break
+	}
+	for range v3.Values() {
+		break
+	}
+
+	b := v3.Builder()
+	b.Set("c", 1, "new")
+	b.SetByTuple(Tuple2[string, int]{"c", 2}, "new2")
+	b.Delete("a", 1)
+	b.DeleteByTuple(Tuple2[string, int]{"a", 2})
+	if val, _ := b.Get("c", 2); val != "new2" || b.Len() != 3 {
+		t.Fatal("Builder doesn't work")
+	}
+	built := b.Persistent()
+	b.Set("c", 1, "changed")
+	if val, _ := built.Get("c", 1); val != "new" {
+		t.Fatal("Builder modified the map it returned")
+	}
+	if !reflect.DeepEqual(v3.AsMapMap(), expected) {
+		t.Fatal("Builder modified the map it started from")
+	}
+}
+
+func TestPersistentMapSet(t *testing.T) {
+	var empty PersistentMapSet[string, int]
+	v1 := empty.With("a", 1).With("a", 2).With("b", 1)
+	v2 := v1.Without("a", 1).Without("b", 1)
+
+	if v1.Len() != 3 || v2.Len() != 1 {
+		t.Fatal("versions don't have the right sizes")
+	}
+	if !v1.Contains("b", 1) || v2.Contains("b", 1) {
+		t.Fatal("versions don't have the right contents")
+	}
+	if !v1.Get("a").AsSet().Equal(SetFromSlice([]int{1, 2})) ||
+		v2.Get("b").Len() != 0 {
+		t.Fatal("incorrect Get")
+	}
+
+	expected := MapSet[string, int]{}
+	expected.Add("a", 1)
+	expected.Add("a", 2)
+	expected.Add("b", 1)
+	if !reflect.DeepEqual(v1.AsMapSet(), expected) {
+		t.Fatal("incorrect AsMapSet")
+	}
+	if !reflect.DeepEqual(PersistentMapSetFromMapSet(expected).AsMapSet(), expected) {
+		t.Fatal("MapSet doesn't round trip")
+	}
+
+	count := 0
+	for key, val := range v1.All() {
+		if !expected[key].Contains(val) {
+			t.Fatal("incorrect All")
+		}
+		count++
+	}
+	if count != 3 {
+		t.Fatal("incorrect All count")
+	}
+	for range v1.All() {
+		break
+	}
+
+	b := v1.Builder()
+	b.Add("c", 3)
+	b.Delete("a", 1)
+	if !b.Contains("c", 3) || b.Contains("a", 1) || b.Len() != 3 {
+		t.Fatal("Builder doesn't work")
+	}
+	built := b.Persistent()
+	b.Add("d", 4)
+	if built.Contains("d", 4) || !reflect.DeepEqual(v1.AsMapSet(), expected) {
+		t.Fatal("Builder modified a persistent version")
+	}
+}
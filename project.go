@@ -0,0 +1,176 @@
+package cm
+
+import (
+	"cmp"
+
+	"golang.org/x/exp/constraints"
+)
+
+// A Reducer folds the values that a projection collapses together into a
+// single aggregate value. Start is called with the first value of each
+// group, and Fold with the aggregate so far and each following value.
+//
+// Since the projections walk maps, the values of a group arrive in no
+// particular order, so the reducer should not depend on it.
+type Reducer[V, A any] struct {
+	Start func(val V) A
+	Fold  func(acc A, val V) A
+}
+
+// reduce folds the value into the aggregate for the key in m.
+func reduce[K comparable, V, A any](m map[K]A, key K, val V, r Reducer[V, A]) {
+	if acc, exists := m[key]; exists {
+		m[key] = r.Fold(acc, val)
+		return
+	}
+	m[key] = r.Start(val)
+}
+
+// reduce2 folds the value into the aggregate for the keys in mma.
+func reduce2[K1, K2 comparable, V, A any](
+	mma MapMapAny[K1, K2, A],
+	key1 K1,
+	key2 K2,
+	val V,
+	r Reducer[V, A],
+) {
+	submap := mma[key1]
+	if submap == nil {
+		submap = map[K2]A{}
+		mma[key1] = submap
+	}
+	reduce(submap, key2, val, r)
+}
+
+// CountReducer returns a Reducer that counts the values collapsed
+// together.
+func CountReducer[V any]() Reducer[V, int] {
+	return Reducer[V, int]{
+		Start: func(V) int { return 1 },
+		Fold:  func(acc int, _ V) int { return acc + 1 },
+	}
+}
+
+// SumReducer returns a Reducer that sums the values collapsed together.
+func SumReducer[V constraints.Integer | constraints.Float]() Reducer[V, V] {
+	return Reducer[V, V]{
+		Start: func(val V) V { return val },
+		Fold:  func(acc V, val V) V { return acc + val },
+	}
+}
+
+// MinReducer returns a Reducer that keeps the smallest of the values
+// collapsed together.
+func MinReducer[V cmp.Ordered]() Reducer[V, V] {
+	return Reducer[V, V]{
+		Start: func(val V) V { return val },
+		Fold:  func(acc V, val V) V { return min(acc, val) },
+	}
+}
+
+// MaxReducer returns a Reducer that keeps the largest of the values
+// collapsed together.
+func MaxReducer[V cmp.Ordered]() Reducer[V, V] {
+	return Reducer[V, V]{
+		Start: func(val V) V { return val },
+		Fold:  func(acc V, val V) V { return max(acc, val) },
+	}
+}
+
+// SetReducer returns a Reducer that collects the values collapsed
+// together into a Set.
+func SetReducer[V comparable]() Reducer[V, Set[V]] {
+	return Reducer[V, Set[V]]{
+		Start: func(val V) Set[V] { return Set[V]{val: void} },
+		Fold: func(acc Set[V], val V) Set[V] {
+			acc[val] = void
+			return acc
+		},
+	}
+}
+
+// MapMapDropKey1 projects the given MapMap or MapMapAny down to a map on
+// its second key, folding together the values of every first key with
+// the passed-in Reducer.
+func MapMapDropKey1[M ~map[K1]map[K2]V, K1, K2 comparable, V, A any](
+	mm M,
+	r Reducer[V, A],
+) map[K2]A {
+	result := map[K2]A{}
+	for _, submap := range mm {
+		for key2, val := range submap {
+			reduce(result, key2, val, r)
+		}
+	}
+	return result
+}
+
+// MapMapDropKey2 projects the given MapMap or MapMapAny down to a map on
+// its first key, folding together the values of every second key with
+// the passed-in Reducer.
+func MapMapDropKey2[M ~map[K1]map[K2]V, K1, K2 comparable, V, A any](
+	mm M,
+	r Reducer[V, A],
+) map[K1]A {
+	result := map[K1]A{}
+	for key1, submap := range mm {
+		for _, val := range submap {
+			reduce(result, key1, val, r)
+		}
+	}
+	return result
+}
+
+// MapMapMapDropKey1 projects the given MapMapMap or MapMapMapAny down to
+// a MapMapAny on its second and third keys, folding together the values
+// of every first key with the passed-in Reducer.
+func MapMapMapDropKey1[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 comparable, V, A any](
+	mmm M,
+	r Reducer[V, A],
+) MapMapAny[K2, K3, A] {
+	result := MapMapAny[K2, K3, A]{}
+	for _, mapmap := range mmm {
+		for key2, submap := range mapmap {
+			for key3, val := range submap {
+				reduce2(result, key2, key3, val, r)
+			}
+		}
+	}
+	return result
+}
+
+// MapMapMapDropKey2 projects the given MapMapMap or MapMapMapAny down to
+// a MapMapAny on its first and third keys, folding together the values
+// of every second key with the passed-in Reducer.
+func MapMapMapDropKey2[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 comparable, V, A any](
+	mmm M,
+	r Reducer[V, A],
+) MapMapAny[K1, K3, A] {
+	result := MapMapAny[K1, K3, A]{}
+	for key1, mapmap := range mmm {
+		for _, submap := range mapmap {
+			for key3, val := range submap {
+				reduce2(result, key1, key3, val, r)
+			}
+		}
+	}
+	return result
+}
+
+// MapMapMapDropKey3 projects the given MapMapMap or MapMapMapAny down to
+// a MapMapAny on its first and second keys, folding together the values
+// of every third key with the passed-in Reducer.
+func MapMapMapDropKey3[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 comparable, V, A any](
+	mmm M,
+	r Reducer[V, A],
+) MapMapAny[K1, K2, A] {
+	result := MapMapAny[K1, K2, A]{}
+	for key1, mapmap := range mmm {
+		for key2, submap := range mapmap {
+			for _, val := range submap {
+				reduce2(result, key1, key2, val, r)
+			}
+		}
+	}
+	return result
+}
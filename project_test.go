@@ -0,0 +1,82 @@
+package cm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapMapDropKey(t *testing.T) {
+	mm := MapMap[string, string, int]{}
+	mm.Set("us", "web", 3)
+	mm.Set("us", "db", 5)
+	mm.Set("eu", "web", 4)
+	mm["empty"] = map[string]int{}
+
+	if !reflect.DeepEqual(
+		MapMapDropKey1(mm, SumReducer[int]()),
+		map[string]int{"web": 7, "db": 5},
+	) {
+		t.Fatal("MapMapDropKey1 didn't work")
+	}
+	if !reflect.DeepEqual(
+		MapMapDropKey2(mm, CountReducer[int]()),
+		map[string]int{"us": 2, "eu": 1},
+	) {
+		t.Fatal("MapMapDropKey2 didn't work")
+	}
+	if !reflect.DeepEqual(
+		MapMapDropKey2(MapMapAny[string, string, int](mm), MinReducer[int]()),
+		map[string]int{"us": 3, "eu": 4},
+	) {
+		t.Fatal("MapMapDropKey2 didn't work on a MapMapAny")
+	}
+	if !reflect.DeepEqual(
+		MapMapDropKey1(mm, MaxReducer[int]()),
+		map[string]int{"web": 4, "db": 5},
+	) {
+		t.Fatal("MaxReducer didn't work")
+	}
+	if !reflect.DeepEqual(
+		MapMapDropKey2(MapMap[int, int, int](nil), MaxReducer[int]()),
+		map[int]int{},
+	) {
+		t.Fatal("projection of a nil map isn't empty")
+	}
+}
+
+func TestMapMapMapDropKey(t *testing.T) {
+	mmm := MapMapMap[string, string, string, float64]{}
+	mmm.Set("us", "web", "a", 1)
+	mmm.Set("us", "web", "b", 2)
+	mmm.Set("us", "db", "a", 3)
+	mmm.Set("eu", "web", "c", 4)
+
+	if !MapMap[string, string, float64](
+		MapMapMapDropKey1(mmm, MaxReducer[float64]()),
+	).Equal(MapMap[string, string, float64]{
+		"web": {"a": 1, "b": 2, "c": 4},
+		"db":  {"a": 3},
+	}) {
+		t.Fatal("MapMapMapDropKey1 didn't work")
+	}
+
+	if !MapMap[string, string, float64](
+		MapMapMapDropKey2(mmm, SumReducer[float64]()),
+	).Equal(MapMap[string, string, float64]{
+		"us": {"a": 4, "b": 2},
+		"eu": {"c": 4},
+	}) {
+		t.Fatal("MapMapMapDropKey2 didn't work")
+	}
+
+	dropped := MapMapMapDropKey3(
+		MapMapMapAny[string, string, string, float64](mmm),
+		SetReducer[float64](),
+	)
+	if len(dropped) != 2 ||
+		!dropped["us"]["web"].Equal(SetFromSlice([]float64{1, 2})) ||
+		!dropped["us"]["db"].Equal(SetFromSlice([]float64{3})) ||
+		!dropped["eu"]["web"].Equal(SetFromSlice([]float64{4})) {
+		t.Fatal("MapMapMapDropKey3 didn't work")
+	}
+}
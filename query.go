@@ -0,0 +1,262 @@
+package cm
+
+import "iter"
+
+// A Match matches one key of a query pattern. The zero value is a
+// wildcard, matching any key; MatchKey returns one that matches only the
+// given key.
+type Match[K comparable] struct {
+	Key   K
+	Fixed bool
+}
+
+// MatchKey returns a Match for exactly the given key.
+func MatchKey[K comparable](key K) Match[K] {
+	return Match[K]{key, true}
+}
+
+// MatchAny returns a wildcard Match, which matches any key. This is the
+// same as the zero value of Match, but may read better in a pattern.
+func MatchAny[K comparable]() Match[K] {
+	return Match[K]{}
+}
+
+func (m Match[K]) matches(key K) bool {
+	return !m.Fixed || m.Key == key
+}
+
+// A Pattern3 is a query on a MapMapMap, with a Match for each of its
+// three keys.
+type Pattern3[K1, K2, K3 comparable] struct {
+	Key1 Match[K1]
+	Key2 Match[K2]
+	Key3 Match[K3]
+}
+
+// Matches returns true if the key matches the pattern.
+func (p Pattern3[K1, K2, K3]) Matches(key Tuple3[K1, K2, K3]) bool {
+	return p.Key1.matches(key.Key1) &&
+		p.Key2.matches(key.Key2) &&
+		p.Key3.matches(key.Key3)
+}
+
+// Query returns an iterator over the keys and values of the MapMapMap
+// that match the pattern, in nondeterministic order.
+//
+// Fixed keys are looked up directly wherever the nesting of the maps
+// allows, so a fixed first key only visits that key's submap, and a fixed
+// third key is a lookup rather than a scan of the innermost maps. A
+// wildcard first key with a fixed second key still has to visit every
+// first key; an IndexedMapMapMap can index the inner keys to avoid that.
+func (mmma MapMapMapAny[K1, K2, K3, V]) Query(
+	p Pattern3[K1, K2, K3],
+) iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return func(yield func(Tuple3[K1, K2, K3], V) bool) {
+		if p.Key1.Fixed {
+			queryMapMap(p.Key1.Key, mmma[p.Key1.Key], p, yield)
+			return
+		}
+		for key1, mapmap := range mmma {
+			if !queryMapMap(key1, mapmap, p, yield) {
+				return
+			}
+		}
+	}
+}
+
+// queryMapMap yields the entries of the MapMapAny under key1 that match
+// the second and third keys of the pattern, returning false if the
+// iteration was stopped.
+func queryMapMap[K1, K2, K3 comparable, V any](
+	key1 K1,
+	mapmap MapMapAny[K2, K3, V],
+	p Pattern3[K1, K2, K3],
+	yield func(Tuple3[K1, K2, K3], V) bool,
+) bool {
+	if p.Key2.Fixed {
+		return querySubmap(key1, p.Key2.Key, mapmap[p.Key2.Key], p, yield)
+	}
+	for key2, submap := range mapmap {
+		if !querySubmap(key1, key2, submap, p, yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// querySubmap yields the entries of the innermost map under key1 and
+// key2 that match the third key of the pattern, returning false if the
+// iteration was stopped.
+func querySubmap[K1, K2, K3 comparable, V any](
+	key1 K1,
+	key2 K2,
+	submap map[K3]V,
+	p Pattern3[K1, K2, K3],
+	yield func(Tuple3[K1, K2, K3], V) bool,
+) bool {
+	if p.Key3.Fixed {
+		val, exists := submap[p.Key3.Key]
+		return !exists || yield(Tuple3[K1, K2, K3]{key1, key2, p.Key3.Key}, val)
+	}
+	for key3, val := range submap {
+		if !yield(Tuple3[K1, K2, K3]{key1, key2, key3}, val) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns an iterator over the keys and values of the MapMapMap
+// that match the pattern. See MapMapMapAny.Query.
+func (mmm MapMapMap[K1, K2, K3, V]) Query(
+	p Pattern3[K1, K2, K3],
+) iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).Query(p)
+}
+
+// QueryIndex selects which inner keys an IndexedMapMapMap indexes. The
+// values may be combined with |.
+type QueryIndex int
+
+const (
+	// IndexKey2 indexes the second key, so queries with a fixed second
+	// key and a wildcard first key only visit the first keys that have
+	// it.
+	IndexKey2 QueryIndex = 1 << iota
+
+	// IndexKey3 indexes the third key, so queries with a fixed third key
+	// and wildcards for the others only visit the entries that have it.
+	IndexKey3
+)
+
+// An IndexedMapMapMap is a MapMapMapAny that maintains secondary indexes
+// on its inner keys, so that Query can find the entries with a given
+// second or third key without scanning the whole map.
+//
+// Each index costs an entry per distinct combination of keys it covers,
+// and is updated on every Set and Delete. Which keys are indexed is
+// chosen when the IndexedMapMapMap is created.
+//
+// As with the Sorted types, the map must not be modified while Query or
+// All is iterating over it.
+type IndexedMapMapMap[K1, K2, K3 comparable, V any] struct {
+	m MapMapMapAny[K1, K2, K3, V]
+
+	// by2 maps each second key to the first keys that have it, and by3
+	// maps each third key to the first and second keys that have it.
+	// Each is nil if that key is not indexed.
+	by2 MapSet[K2, K1]
+	by3 MapSet[K3, Tuple2[K1, K2]]
+}
+
+// NewIndexedMapMapMap returns a new, empty IndexedMapMapMap maintaining
+// the given indexes.
+func NewIndexedMapMapMap[K1, K2, K3 comparable, V any](
+	indexes QueryIndex,
+) *IndexedMapMapMap[K1, K2, K3, V] {
+	imm := &IndexedMapMapMap[K1, K2, K3, V]{m: MapMapMapAny[K1, K2, K3, V]{}}
+	if indexes&IndexKey2 != 0 {
+		imm.by2 = MapSet[K2, K1]{}
+	}
+	if indexes&IndexKey3 != 0 {
+		imm.by3 = MapSet[K3, Tuple2[K1, K2]]{}
+	}
+	return imm
+}
+
+// Set will set the given value with the given keys.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) Set(key1 K1, key2 K2, key3 K3, value V) {
+	imm.m.Set(key1, key2, key3, value)
+	if imm.by2 != nil {
+		imm.by2.Add(key2, key1)
+	}
+	if imm.by3 != nil {
+		imm.by3.Add(key3, Tuple2[K1, K2]{key1, key2})
+	}
+}
+
+// SetByTuple sets by the key tuple.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) SetByTuple(key Tuple3[K1, K2, K3], value V) {
+	imm.Set(key.Key1, key.Key2, key.Key3, value)
+}
+
+// Get returns the value for the given keys. The second value is true if
+// the keys exist, false otherwise.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) Get(key1 K1, key2 K2, key3 K3) (val V, exists bool) {
+	val, exists = imm.m[key1][key2][key3]
+	return val, exists
+}
+
+// GetByTuple retrieves by the given tuple. The second value is true if
+// the key exists, false otherwise.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) GetByTuple(key Tuple3[K1, K2, K3]) (val V, exists bool) {
+	return imm.m.GetByTuple(key)
+}
+
+// Delete deletes the value from the map, cleaning up the indexes.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) Delete(key1 K1, key2 K2, key3 K3) {
+	if _, exists := imm.m[key1][key2][key3]; !exists {
+		return
+	}
+	imm.m.Delete(key1, key2, key3)
+	if imm.by2 != nil {
+		if _, exists := imm.m[key1][key2]; !exists {
+			imm.by2.Delete(key2, key1)
+		}
+	}
+	if imm.by3 != nil {
+		imm.by3.Delete(key3, Tuple2[K1, K2]{key1, key2})
+	}
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) DeleteByTuple(key Tuple3[K1, K2, K3]) {
+	imm.Delete(key.Key1, key.Key2, key.Key3)
+}
+
+// Len returns the number of values in the map.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) Len() int {
+	return imm.m.Len()
+}
+
+// All returns an iterator over all the keys and values in the map.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) All() iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return imm.m.All()
+}
+
+// Query returns an iterator over the keys and values in the map that
+// match the pattern, in nondeterministic order.
+//
+// A pattern with a fixed first key is answered as MapMapMapAny.Query
+// does. Otherwise, a fixed second key uses the second key's index, and
+// failing that a fixed third key uses the third key's index. Without a
+// usable index, this scans the map just as MapMapMapAny.Query does.
+func (imm *IndexedMapMapMap[K1, K2, K3, V]) Query(
+	p Pattern3[K1, K2, K3],
+) iter.Seq2[Tuple3[K1, K2, K3], V] {
+	switch {
+	case p.Key1.Fixed:
+	case p.Key2.Fixed && imm.by2 != nil:
+		return func(yield func(Tuple3[K1, K2, K3], V) bool) {
+			for key1 := range imm.by2[p.Key2.Key] {
+				submap := imm.m[key1][p.Key2.Key]
+				if !querySubmap(key1, p.Key2.Key, submap, p, yield) {
+					return
+				}
+			}
+		}
+	case p.Key3.Fixed && imm.by3 != nil:
+		return func(yield func(Tuple3[K1, K2, K3], V) bool) {
+			for keys := range imm.by3[p.Key3.Key] {
+				if !p.Key2.matches(keys.Key2) {
+					continue
+				}
+				key := Tuple3[K1, K2, K3]{keys.Key1, keys.Key2, p.Key3.Key}
+				if !yield(key, imm.m[keys.Key1][keys.Key2][p.Key3.Key]) {
+					return
+				}
+			}
+		}
+	}
+	return imm.m.Query(p)
+}
@@ -0,0 +1,141 @@
+package cm
+
+import (
+	"iter"
+	"testing"
+)
+
+// queryPatterns returns every pattern of fixed keys and wildcards, fixing
+// keys to the given values.
+func queryPatterns(key1, key2, key3 int) []Pattern3[int, int, int] {
+	var patterns []Pattern3[int, int, int]
+	for i := range 8 {
+		p := Pattern3[int, int, int]{MatchAny[int](), MatchAny[int](), MatchAny[int]()}
+		if i&1 != 0 {
+			p.Key1 = MatchKey(key1)
+		}
+		if i&2 != 0 {
+			p.Key2 = MatchKey(key2)
+		}
+		if i&4 != 0 {
+			p.Key3 = MatchKey(key3)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+func collectQuery(seq iter.Seq2[Tuple3[int, int, int], int]) map[Tuple3[int, int, int]]int {
+	result := map[Tuple3[int, int, int]]int{}
+	for key, val := range seq {
+		result[key] = val
+	}
+	return result
+}
+
+// checkQuery verifies that the query returns exactly the matching entries
+// of the map, and that it stops when asked to.
+func checkQuery(
+	t *testing.T,
+	mmm MapMapMap[int, int, int, int],
+	p Pattern3[int, int, int],
+	query iter.Seq2[Tuple3[int, int, int], int],
+) {
+	t.Helper()
+
+	expected := map[Tuple3[int, int, int]]int{}
+	for key, val := range mmm.All() {
+		if p.Matches(key) {
+			expected[key] = val
+		}
+	}
+
+	result := collectQuery(query)
+	if len(result) != len(expected) {
+		t.Fatalf("pattern %v: expected %v, got %v", p, expected, result)
+	}
+	for key, val := range expected {
+		if rVal, exists := result[key]; !exists || rVal != val {
+			t.Fatalf("pattern %v: expected %v, got %v", p, expected, result)
+		}
+	}
+
+	for stopAt := range len(expected) {
+		count := 0
+		for range query {
+			if count == stopAt {
+				break
+			}
+			count++
+		}
+	}
+}
+
+func queryTestMap() MapMapMap[int, int, int, int] {
+	mmm := MapMapMap[int, int, int, int]{}
+	for key1 := range 3 {
+		for key2 := range 3 {
+			for key3 := range 3 {
+				if (key1+key2+key3)%2 == 0 {
+					mmm.Set(key1, key2, key3, key1*100+key2*10+key3)
+				}
+			}
+		}
+	}
+	return mmm
+}
+
+func TestQuery(t *testing.T) {
+	mmm := queryTestMap()
+
+	for _, keys := range []Tuple3[int, int, int]{{0, 0, 0}, {1, 2, 1}, {0, 1, 2}, {5, 5, 5}} {
+		for _, p := range queryPatterns(keys.Key1, keys.Key2, keys.Key3) {
+			checkQuery(t, mmm, p, mmm.Query(p))
+		}
+	}
+}
+
+func TestIndexedMapMapMap(t *testing.T) {
+	mmm := queryTestMap()
+
+	for _, indexes := range []QueryIndex{0, IndexKey2, IndexKey3, IndexKey2 | IndexKey3} {
+		imm := NewIndexedMapMapMap[int, int, int, int](indexes)
+		for key, val := range mmm.All() {
+			imm.SetByTuple(key, val)
+		}
+		if imm.Len() != mmm.Len() || len(collectQuery(imm.All())) != mmm.Len() {
+			t.Fatal("IndexedMapMapMap doesn't contain the values")
+		}
+
+		check := func(expected MapMapMap[int, int, int, int]) {
+			t.Helper()
+			for _, keys := range []Tuple3[int, int, int]{{0, 0, 0}, {1, 2, 1}, {0, 1, 2}, {5, 5, 5}} {
+				for _, p := range queryPatterns(keys.Key1, keys.Key2, keys.Key3) {
+					checkQuery(t, expected, p, imm.Query(p))
+				}
+			}
+		}
+		check(mmm)
+
+		// Deleting every entry under a first and second key must clean
+		// up the indexes, as must deleting a single entry.
+		expected := mmm.Clone()
+		for _, key := range []Tuple3[int, int, int]{{1, 2, 1}, {0, 0, 0}, {0, 0, 2}, {9, 9, 9}} {
+			expected.DeleteByTuple(key)
+			imm.DeleteByTuple(key)
+		}
+		check(expected)
+		if val, exists := imm.Get(2, 2, 2); !exists || val != 222 {
+			t.Fatal("Get didn't work")
+		}
+		if _, exists := imm.GetByTuple(Tuple3[int, int, int]{0, 0, 0}); exists {
+			t.Fatal("deleted value is still present")
+		}
+		if imm.by2 != nil && imm.by2[0].Contains(0) {
+			t.Fatal("second key index wasn't cleaned up")
+		}
+		if imm.by3 != nil && imm.by3[1].Contains(Tuple2[int, int]{1, 2}) {
+			t.Fatal("third key index wasn't cleaned up")
+		}
+	}
+}
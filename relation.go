@@ -0,0 +1,126 @@
+package cm
+
+// A MapSet can also be viewed as a binary relation between its keys and
+// values, containing the pair (k, v) if ms[k] contains v. The methods in
+// this file implement relational operations on that view.
+
+// Invert returns a new MapSet with the keys and values swapped, so that
+// the result contains v -> k for every k -> v in this MapSet.
+func (ms MapSet[K, V]) Invert() MapSet[V, K] {
+	r := MapSet[V, K]{}
+	for key, set := range ms {
+		for val := range set {
+			r.Add(val, key)
+		}
+	}
+	return r
+}
+
+// Compose returns a new MapSet that contains a -> c whenever ab contains
+// a -> b and bc contains b -> c for some b. For instance, composing a
+// MapSet of users to groups with a MapSet of groups to permissions
+// yields a MapSet of users to permissions.
+func Compose[A, B, C comparable](ab MapSet[A, B], bc MapSet[B, C]) MapSet[A, C] {
+	r := MapSet[A, C]{}
+	for a, bs := range ab {
+		for b := range bs {
+			if cs := bc[b]; len(cs) > 0 {
+				r.Union(a, cs)
+			}
+		}
+	}
+	return r
+}
+
+// Restrict returns a new MapSet containing only the keys that are in the
+// passed-in set. The sets in the result are copies.
+func (ms MapSet[K, V]) Restrict(keys Set[K]) MapSet[K, V] {
+	r := MapSet[K, V]{}
+	if len(ms) <= len(keys) {
+		for key, set := range ms {
+			if keys.Contains(key) && len(set) > 0 {
+				r[key] = set.Clone()
+			}
+		}
+	} else {
+		for key := range keys {
+			if set := ms[key]; len(set) > 0 {
+				r[key] = set.Clone()
+			}
+		}
+	}
+	return r
+}
+
+// Image returns the set of all values associated with any of the keys
+// in the passed-in set.
+func (ms MapSet[K, V]) Image(keys Set[K]) Set[V] {
+	r := Set[V]{}
+	for key := range keys {
+		r.Union(ms[key])
+	}
+	return r
+}
+
+// PreImage returns the set of all keys associated with any of the values
+// in the passed-in set.
+func (ms MapSet[K, V]) PreImage(vals Set[V]) Set[K] {
+	r := Set[K]{}
+	for key, set := range ms {
+		if intersects(set, vals) {
+			r.Add(key)
+		}
+	}
+	return r
+}
+
+// intersects returns true if the two sets have any value in common,
+// without building their intersection.
+func intersects[M comparable](l, r Set[M]) bool {
+	if len(l) > len(r) {
+		l, r = r, l
+	}
+	for val := range l {
+		if r.Contains(val) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect returns a new MapSet where the set for each key is the
+// intersection of the sets for that key in this MapSet and the passed-in
+// MapSet. Keys whose intersection is empty are not included.
+func (ms MapSet[K, V]) Intersect(r MapSet[K, V]) MapSet[K, V] {
+	small, large := ms, r
+	if len(small) > len(large) {
+		small, large = large, small
+	}
+
+	intersection := MapSet[K, V]{}
+	for key, set := range small {
+		if s := set.Intersect(large[key]); len(s) > 0 {
+			intersection[key] = s
+		}
+	}
+	return intersection
+}
+
+// Subtract removes every key/value pair in the passed-in MapSet from
+// this MapSet. As with Delete, any set that becomes empty is removed
+// from the MapSet.
+//
+// The MapSet this is called on is returned, allowing for chaining.
+func (ms MapSet[K, V]) Subtract(r MapSet[K, V]) MapSet[K, V] {
+	for key, rSet := range r {
+		set, exists := ms[key]
+		if !exists {
+			continue
+		}
+		set.Subtract(rSet)
+		if len(set) == 0 {
+			delete(ms, key)
+		}
+	}
+	return ms
+}
@@ -0,0 +1,130 @@
+package cm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func relationOf(pairs ...Tuple2[string, int]) MapSet[string, int] {
+	ms := MapSet[string, int]{}
+	for _, pair := range pairs {
+		ms.AddByTuple(pair)
+	}
+	return ms
+}
+
+func TestMapSetInvertCompose(t *testing.T) {
+	userGroups := relationOf(
+		Tuple2[string, int]{"alice", 1},
+		Tuple2[string, int]{"alice", 2},
+		Tuple2[string, int]{"bob", 2},
+		Tuple2[string, int]{"carol", 3},
+	)
+
+	groupUsers := userGroups.Invert()
+	expected := MapSet[int, string]{}
+	expected.Add(1, "alice")
+	expected.Add(2, "alice")
+	expected.Add(2, "bob")
+	expected.Add(3, "carol")
+	if !reflect.DeepEqual(groupUsers, expected) {
+		t.Fatal("incorrect Invert")
+	}
+	if !reflect.DeepEqual(groupUsers.Invert(), userGroups) {
+		t.Fatal("Invert doesn't round trip")
+	}
+
+	groupPerms := MapSet[int, string]{}
+	groupPerms.Add(1, "read")
+	groupPerms.Add(2, "write")
+	groupPerms.Add(2, "read")
+	groupPerms[3] = Set[string]{}
+
+	userPerms := Compose(userGroups, groupPerms)
+	expectedPerms := MapSet[string, string]{}
+	expectedPerms.Add("alice", "read")
+	expectedPerms.Add("alice", "write")
+	expectedPerms.Add("bob", "read")
+	expectedPerms.Add("bob", "write")
+	if !reflect.DeepEqual(userPerms, expectedPerms) {
+		t.Fatal("incorrect Compose")
+	}
+	// the result must not share sets with the inputs
+	userPerms.Add("bob", "admin")
+	if groupPerms[2].Contains("admin") {
+		t.Fatal("Compose shares sets with its input")
+	}
+}
+
+func TestMapSetRestrictImage(t *testing.T) {
+	ms := relationOf(
+		Tuple2[string, int]{"a", 1},
+		Tuple2[string, int]{"a", 2},
+		Tuple2[string, int]{"b", 2},
+		Tuple2[string, int]{"c", 3},
+	)
+	ms["empty"] = Set[int]{}
+
+	for _, keys := range []Set[string]{
+		SetFromSlice([]string{"a", "c", "empty"}),
+		SetFromSlice([]string{"a", "c", "empty", "x", "y", "z", "w"}),
+	} {
+		restricted := ms.Restrict(keys)
+		if !reflect.DeepEqual(restricted, relationOf(
+			Tuple2[string, int]{"a", 1},
+			Tuple2[string, int]{"a", 2},
+			Tuple2[string, int]{"c", 3},
+		)) {
+			t.Fatal("incorrect Restrict")
+		}
+		restricted.Add("a", 9)
+		if ms["a"].Contains(9) {
+			t.Fatal("Restrict shares sets")
+		}
+	}
+
+	if !ms.Image(SetFromSlice([]string{"a", "b", "x"})).Equal(
+		SetFromSlice([]int{1, 2})) {
+		t.Fatal("incorrect Image")
+	}
+	if !ms.PreImage(SetFromSlice([]int{2, 3, 4})).Equal(
+		SetFromSlice([]string{"a", "b", "c"})) {
+		t.Fatal("incorrect PreImage")
+	}
+	if len(ms.PreImage(nil)) != 0 || len(ms.Image(nil)) != 0 {
+		t.Fatal("incorrect Image or PreImage of nothing")
+	}
+}
+
+func TestMapSetIntersectSubtract(t *testing.T) {
+	l := relationOf(
+		Tuple2[string, int]{"a", 1},
+		Tuple2[string, int]{"a", 2},
+		Tuple2[string, int]{"b", 1},
+		Tuple2[string, int]{"c", 1},
+	)
+	r := relationOf(
+		Tuple2[string, int]{"a", 2},
+		Tuple2[string, int]{"a", 3},
+		Tuple2[string, int]{"b", 2},
+	)
+
+	expected := relationOf(Tuple2[string, int]{"a", 2})
+	if !reflect.DeepEqual(l.Intersect(r), expected) ||
+		!reflect.DeepEqual(r.Intersect(l), expected) {
+		t.Fatal("incorrect Intersect")
+	}
+
+	l.Subtract(r).Subtract(relationOf(Tuple2[string, int]{"c", 1}))
+	if !reflect.DeepEqual(l, relationOf(
+		Tuple2[string, int]{"a", 1},
+		Tuple2[string, int]{"b", 1},
+	)) {
+		t.Fatal("incorrect Subtract")
+	}
+
+	var nilMS MapSet[string, int]
+	if nilMS.Subtract(r) != nil || len(nilMS.Intersect(r)) != 0 {
+		t.Fatal("nil MapSet doesn't act empty")
+	}
+}
@@ -0,0 +1,442 @@
+package cm
+
+import (
+	"math/bits"
+	"slices"
+	"sort"
+)
+
+// This file implements the containers underlying RoaringSet.
+//
+// A RoaringSet splits each value into its high bits, which select a
+// container, and its low 16 bits, which the container stores. Each
+// container uses whichever of three representations suits its contents:
+//
+//   - an array container is a sorted []uint16, for up to
+//     roaringArrayMax values;
+//   - a bitmap container is a fixed 8KB bitmap, for more values than
+//     that;
+//   - a run container is a sorted list of [start, last] runs, for values
+//     that are mostly contiguous.
+//
+// Adds and removes switch between the array and bitmap representations
+// as the number of values crosses roaringArrayMax, at which point they
+// take the same space. Run containers are only produced by the set
+// operations and by Optimize, which pick the smallest representation for
+// their results; a run container that is modified remains one unless it
+// fragments into too many runs.
+
+const (
+	roaringArrayMax = 4096
+	roaringWords    = 1 << 16 / 64
+	roaringRunMax   = 2048
+)
+
+type roaringContainer interface {
+	contains(v uint16) bool
+	// add and remove return the container to use in place of this one,
+	// which may be this container modified in place or a new one in a
+	// different representation. remove returns nil if the container is
+	// now empty.
+	add(v uint16) roaringContainer
+	remove(v uint16) roaringContainer
+	card() int
+	each(yield func(uint16) bool) bool
+	clone() roaringContainer
+	// bitmap returns a new bitmap container with the same values.
+	bitmap() *bitmapContainer
+}
+
+type arrayContainer []uint16
+
+func (a arrayContainer) contains(v uint16) bool {
+	_, found := slices.BinarySearch(a, v)
+	return found
+}
+
+func (a arrayContainer) add(v uint16) roaringContainer {
+	i, found := slices.BinarySearch(a, v)
+	if found {
+		return a
+	}
+	if len(a) == roaringArrayMax {
+		return a.bitmap().add(v)
+	}
+	return slices.Insert(a, i, v)
+}
+
+func (a arrayContainer) remove(v uint16) roaringContainer {
+	i, found := slices.BinarySearch(a, v)
+	if !found {
+		return a
+	}
+	if len(a) == 1 {
+		return nil
+	}
+	return slices.Delete(a, i, i+1)
+}
+
+func (a arrayContainer) card() int {
+	return len(a)
+}
+
+func (a arrayContainer) each(yield func(uint16) bool) bool {
+	for _, v := range a {
+		if !yield(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a arrayContainer) clone() roaringContainer {
+	return slices.Clone(a)
+}
+
+func (a arrayContainer) bitmap() *bitmapContainer {
+	b := &bitmapContainer{}
+	for _, v := range a {
+		b.words[v/64] |= 1 << (v % 64)
+	}
+	b.n = len(a)
+	return b
+}
+
+type bitmapContainer struct {
+	words [roaringWords]uint64
+	n     int
+}
+
+func (b *bitmapContainer) contains(v uint16) bool {
+	return b.words[v/64]&(1<<(v%64)) != 0
+}
+
+func (b *bitmapContainer) add(v uint16) roaringContainer {
+	if !b.contains(v) {
+		b.words[v/64] |= 1 << (v % 64)
+		b.n++
+	}
+	return b
+}
+
+func (b *bitmapContainer) remove(v uint16) roaringContainer {
+	if !b.contains(v) {
+		return b
+	}
+	b.words[v/64] &^= 1 << (v % 64)
+	b.n--
+	if b.n <= roaringArrayMax {
+		return b.array()
+	}
+	return b
+}
+
+func (b *bitmapContainer) card() int {
+	return b.n
+}
+
+func (b *bitmapContainer) each(yield func(uint16) bool) bool {
+	for i, w := range b.words {
+		for w != 0 {
+			if !yield(uint16(i*64 + bits.TrailingZeros64(w))) {
+				return false
+			}
+			w &= w - 1
+		}
+	}
+	return true
+}
+
+func (b *bitmapContainer) clone() roaringContainer {
+	return b.bitmap()
+}
+
+func (b *bitmapContainer) bitmap() *bitmapContainer {
+	r := *b
+	return &r
+}
+
+// array returns the values of the bitmap as an array container.
+func (b *bitmapContainer) array() roaringContainer {
+	a := make(arrayContainer, 0, b.n)
+	b.each(func(v uint16) bool {
+		a = append(a, v)
+		return true
+	})
+	return a
+}
+
+// recount recomputes the number of values after a word-level operation.
+func (b *bitmapContainer) recount() {
+	b.n = 0
+	for _, w := range b.words {
+		b.n += bits.OnesCount64(w)
+	}
+}
+
+type roaringRun struct {
+	start, last uint16
+}
+
+type runContainer []roaringRun
+
+// search returns the index of the first run that starts after v.
+func (r runContainer) search(v uint16) int {
+	return sort.Search(len(r), func(i int) bool { return r[i].start > v })
+}
+
+func (r runContainer) contains(v uint16) bool {
+	i := r.search(v)
+	return i > 0 && v <= r[i-1].last
+}
+
+func (r runContainer) add(v uint16) roaringContainer {
+	if r.contains(v) {
+		return r
+	}
+	// v is not in any run, so if there is a previous run it ends before
+	// v, and if there is a next run it starts after v.
+	i := r.search(v)
+	joinsPrev := i > 0 && r[i-1].last+1 == v
+	joinsNext := i < len(r) && r[i].start-1 == v
+	switch {
+	case joinsPrev && joinsNext:
+		r[i-1].last = r[i].last
+		return slices.Delete(r, i, i+1)
+	case joinsPrev:
+		r[i-1].last = v
+	case joinsNext:
+		r[i].start = v
+	default:
+		r = slices.Insert(r, i, roaringRun{v, v})
+		if len(r) > roaringRunMax {
+			return optimizeContainer(r)
+		}
+	}
+	return r
+}
+
+func (r runContainer) remove(v uint16) roaringContainer {
+	if !r.contains(v) {
+		return r
+	}
+	i := r.search(v) - 1
+	run := r[i]
+	switch {
+	case run.start == run.last:
+		if len(r) == 1 {
+			return nil
+		}
+		return slices.Delete(r, i, i+1)
+	case v == run.start:
+		r[i].start++
+	case v == run.last:
+		r[i].last--
+	default:
+		r[i].last = v - 1
+		r = slices.Insert(r, i+1, roaringRun{v + 1, run.last})
+		if len(r) > roaringRunMax {
+			return optimizeContainer(r)
+		}
+	}
+	return r
+}
+
+func (r runContainer) card() int {
+	n := 0
+	for _, run := range r {
+		n += int(run.last-run.start) + 1
+	}
+	return n
+}
+
+func (r runContainer) each(yield func(uint16) bool) bool {
+	for _, run := range r {
+		for v := int(run.start); v <= int(run.last); v++ {
+			if !yield(uint16(v)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r runContainer) clone() roaringContainer {
+	return slices.Clone(r)
+}
+
+func (r runContainer) bitmap() *bitmapContainer {
+	b := &bitmapContainer{}
+	r.each(func(v uint16) bool {
+		b.words[v/64] |= 1 << (v % 64)
+		return true
+	})
+	b.n = r.card()
+	return b
+}
+
+// optimizeContainer returns the container in its smallest
+// representation, or nil if it is empty. An array is 2 bytes per value, a
+// run container is 4 bytes per run, and a bitmap is always 8KB.
+func optimizeContainer(c roaringContainer) roaringContainer {
+	n := c.card()
+	if n == 0 {
+		return nil
+	}
+	runs := 0
+	next := -1
+	c.each(func(v uint16) bool {
+		if int(v) != next {
+			runs++
+		}
+		next = int(v) + 1
+		return true
+	})
+
+	switch {
+	case 4*runs < 2*n && 4*runs < 8*roaringWords:
+		if r, isRun := c.(runContainer); isRun {
+			return r
+		}
+		r := make(runContainer, 0, runs)
+		c.each(func(v uint16) bool {
+			if len(r) > 0 && r[len(r)-1].last+1 == v {
+				r[len(r)-1].last = v
+			} else {
+				r = append(r, roaringRun{v, v})
+			}
+			return true
+		})
+		return r
+	case n <= roaringArrayMax:
+		if a, isArray := c.(arrayContainer); isArray {
+			return a
+		}
+		a := make(arrayContainer, 0, n)
+		c.each(func(v uint16) bool {
+			a = append(a, v)
+			return true
+		})
+		return a
+	default:
+		if b, isBitmap := c.(*bitmapContainer); isBitmap {
+			return b
+		}
+		return c.bitmap()
+	}
+}
+
+// roaringOp describes a set operation by which values it keeps: those
+// only in the left operand, those only in the right, and those in both.
+type roaringOp struct {
+	onlyL, onlyR, both bool
+}
+
+var (
+	roaringUnion     = roaringOp{true, true, true}
+	roaringIntersect = roaringOp{false, false, true}
+	roaringSubtract  = roaringOp{true, false, false}
+	roaringXOR       = roaringOp{true, true, false}
+)
+
+func (op roaringOp) keep(inL, inR bool) bool {
+	switch {
+	case inL && inR:
+		return op.both
+	case inL:
+		return op.onlyL
+	default:
+		return op.onlyR
+	}
+}
+
+func (op roaringOp) word(l, r uint64) uint64 {
+	var w uint64
+	if op.onlyL {
+		w |= l &^ r
+	}
+	if op.onlyR {
+		w |= r &^ l
+	}
+	if op.both {
+		w |= l & r
+	}
+	return w
+}
+
+// combine applies the operation to two containers, returning a new
+// container in its smallest representation, or nil if the result is
+// empty. Neither operand is modified.
+func (op roaringOp) combine(l, r roaringContainer) roaringContainer {
+	la, lIsArray := l.(arrayContainer)
+	ra, rIsArray := r.(arrayContainer)
+
+	switch {
+	case lIsArray && rIsArray:
+		return optimizeContainer(op.mergeArrays(la, ra))
+	case lIsArray && !op.onlyR:
+		// The result can only contain values from l, so just check
+		// each of them against r.
+		return optimizeContainer(op.filter(la, r, true))
+	case rIsArray && !op.onlyL:
+		return optimizeContainer(op.filter(ra, l, false))
+	}
+
+	b := l.bitmap()
+	rb := r.bitmap()
+	for i := range b.words {
+		b.words[i] = op.word(b.words[i], rb.words[i])
+	}
+	b.recount()
+	return optimizeContainer(b)
+}
+
+func (op roaringOp) mergeArrays(l, r arrayContainer) arrayContainer {
+	result := make(arrayContainer, 0, max(len(l), len(r)))
+	i, j := 0, 0
+	for i < len(l) || j < len(r) {
+		switch {
+		case j == len(r) || (i < len(l) && l[i] < r[j]):
+			if op.onlyL {
+				result = append(result, l[i])
+			}
+			i++
+		case i == len(l) || r[j] < l[i]:
+			if op.onlyR {
+				result = append(result, r[j])
+			}
+			j++
+		default:
+			if op.both {
+				result = append(result, l[i])
+			}
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+// filter returns the values of a that the operation keeps, checking them
+// against the other container. isL is true if a is the left operand.
+func (op roaringOp) filter(a arrayContainer, other roaringContainer, isL bool) arrayContainer {
+	result := make(arrayContainer, 0, len(a))
+	for _, v := range a {
+		inL, inR := true, other.contains(v)
+		if !isL {
+			inL, inR = inR, inL
+		}
+		if op.keep(inL, inR) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// containerSubset returns true if every value in l is in r.
+func containerSubset(l, r roaringContainer) bool {
+	if l.card() > r.card() {
+		return false
+	}
+	return l.each(r.contains)
+}
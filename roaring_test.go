@@ -0,0 +1,241 @@
+package cm
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func containerValues(c roaringContainer) []uint16 {
+	var vals []uint16
+	if c != nil {
+		c.each(func(v uint16) bool {
+			vals = append(vals, v)
+			return true
+		})
+	}
+	return vals
+}
+
+func checkContainer(t *testing.T, c roaringContainer, model Set[uint16]) {
+	t.Helper()
+	vals := containerValues(c)
+	expected := model.AsSlice()
+	slices.Sort(expected)
+	if !slices.Equal(vals, expected) {
+		t.Fatalf("container has %d values, expected %d", len(vals), len(expected))
+	}
+	if len(model) == 0 {
+		if c != nil {
+			t.Fatal("empty container isn't nil")
+		}
+		return
+	}
+	if c.card() != len(model) {
+		t.Fatal("incorrect card")
+	}
+	switch c := c.(type) {
+	case arrayContainer:
+		if len(c) > roaringArrayMax {
+			t.Fatal("array container is too large")
+		}
+	case *bitmapContainer:
+		if c.n <= roaringArrayMax {
+			t.Fatal("bitmap container is too small")
+		}
+	case runContainer:
+		if len(c) > roaringRunMax {
+			t.Fatal("run container has too many runs")
+		}
+	}
+}
+
+// randomContainer returns a container of the given type and its
+// contents: sparse values for arrays, dense ones for bitmaps, and a few
+// long runs for run containers.
+func randomContainer(rng *rand.Rand, kind int) (roaringContainer, Set[uint16]) {
+	model := Set[uint16]{}
+	switch kind {
+	case 0:
+		for range rng.Intn(100) + 1 {
+			model.Add(uint16(rng.Intn(1 << 16)))
+		}
+	case 1:
+		for range 10000 {
+			model.Add(uint16(rng.Intn(1 << 15)))
+		}
+	default:
+		for range rng.Intn(4) + 1 {
+			start := rng.Intn(1<<16 - 1000)
+			for v := start; v < start+rng.Intn(1000)+1; v++ {
+				model.Add(uint16(v))
+			}
+		}
+	}
+
+	var c roaringContainer = arrayContainer{}
+	for v := range model {
+		c = c.add(v)
+	}
+	if kind == 2 {
+		c = optimizeContainer(c)
+	}
+	return c, model
+}
+
+func TestRoaringContainerRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, limit := range []int{100, 10000, 1 << 16} {
+		var c roaringContainer = arrayContainer{}
+		model := Set[uint16]{}
+		for i := range 30000 {
+			v := uint16(rng.Intn(limit))
+			// grow past the array limit, then shrink back below it
+			if rng.Intn(30000) < i {
+				if c != nil {
+					c = c.remove(v)
+				}
+				model.Remove(v)
+			} else {
+				if c == nil {
+					c = arrayContainer{}
+				}
+				c = c.add(v)
+				model.Add(v)
+			}
+			if c != nil && c.contains(v) != model.Contains(v) {
+				t.Fatal("incorrect contains")
+			}
+			if i%1000 == 0 {
+				checkContainer(t, c, model)
+			}
+		}
+		checkContainer(t, c, model)
+	}
+}
+
+func TestRoaringRunContainer(t *testing.T) {
+	var c roaringContainer = runContainer{{10, 20}, {30, 30}, {40, 50}}
+	model := Set[uint16]{}
+	for _, v := range containerValues(c) {
+		model.Add(v)
+	}
+
+	for _, v := range []uint16{15, 21, 29, 25, 0, 65535, 10, 20, 30, 30, 50, 45} {
+		if model.Contains(v) {
+			c = c.remove(v)
+			model.Remove(v)
+		} else {
+			c = c.add(v)
+			model.Add(v)
+		}
+		if _, isRun := c.(runContainer); !isRun {
+			t.Fatal("run container changed type")
+		}
+		checkContainer(t, c, model)
+	}
+
+	c = runContainer{{1, 2}, {4, 5}, {7, 7}}
+	c = c.add(3).add(2).remove(10).remove(7)
+	if !slices.Equal(containerValues(c), []uint16{1, 2, 3, 4, 5}) || len(c.(runContainer)) != 1 {
+		t.Fatal("runs weren't joined")
+	}
+
+	if runContainer([]roaringRun{{5, 5}}).remove(5) != nil {
+		t.Fatal("emptied run container isn't nil")
+	}
+	if len(containerValues(runContainer{{65530, 65535}})) != 6 {
+		t.Fatal("run at the end of the range iterated incorrectly")
+	}
+
+	// Fragmenting a run container, by adding or by removing, converts it
+	// once it has too many runs.
+	c = runContainer{}
+	for v := 0; v < 2*roaringRunMax+2; v += 2 {
+		c = c.add(uint16(v))
+	}
+	if _, isRun := c.(runContainer); isRun {
+		t.Fatal("fragmented run container wasn't converted")
+	}
+	c = runContainer{{0, 65535}}
+	for v := 1; v < 2*roaringRunMax+2; v += 2 {
+		c = c.remove(uint16(v))
+	}
+	if _, isBitmap := c.(*bitmapContainer); !isBitmap {
+		t.Fatal("fragmented run container wasn't converted")
+	}
+	if c.card() != 1<<16-roaringRunMax-1 {
+		t.Fatal("incorrect card after conversion")
+	}
+}
+
+func TestRoaringOptimize(t *testing.T) {
+	full := runContainer{{0, 65535}}
+	if _, isRun := optimizeContainer(full.bitmap()).(runContainer); !isRun {
+		t.Fatal("full bitmap didn't become a run")
+	}
+	if _, isRun := optimizeContainer(full).(runContainer); !isRun {
+		t.Fatal("full run didn't stay a run")
+	}
+	sparse := arrayContainer{1, 3, 5}
+	if _, isArray := optimizeContainer(sparse.bitmap()).(arrayContainer); !isArray {
+		t.Fatal("sparse bitmap didn't become an array")
+	}
+	if _, isArray := optimizeContainer(runContainer{{1, 1}, {3, 3}}).(arrayContainer); !isArray {
+		t.Fatal("fragmented run didn't become an array")
+	}
+	var dense roaringContainer = arrayContainer{}
+	for v := 0; v < 1<<16; v += 3 {
+		dense = dense.add(uint16(v))
+	}
+	if optimizeContainer(dense) != dense {
+		t.Fatal("dense bitmap didn't stay a bitmap")
+	}
+	if _, isBitmap := optimizeContainer(runContainer(nil).clone()).(*bitmapContainer); isBitmap {
+		t.Fatal("empty container became a bitmap")
+	}
+}
+
+func TestRoaringCombine(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	ops := []struct {
+		op    roaringOp
+		model func(l, r Set[uint16]) Set[uint16]
+	}{
+		{roaringUnion, func(l, r Set[uint16]) Set[uint16] { return l.Clone().Union(r) }},
+		{roaringIntersect, Set[uint16].Intersect},
+		{roaringSubtract, func(l, r Set[uint16]) Set[uint16] { return l.Clone().Subtract(r) }},
+		{roaringXOR, Set[uint16].XOR},
+	}
+
+	l, r := arrayContainer{1, 2, 3}, arrayContainer{2, 3, 4}
+	if !slices.Equal(containerValues(roaringIntersect.combine(l, r)), []uint16{2, 3}) ||
+		!slices.Equal(containerValues(roaringXOR.combine(l, r)), []uint16{1, 4}) {
+		t.Fatal("incorrect combination of overlapping arrays")
+	}
+
+	for range 10 {
+		for lKind := range 3 {
+			for rKind := range 3 {
+				l, lModel := randomContainer(rng, lKind)
+				r, rModel := randomContainer(rng, rKind)
+				lVals, rVals := containerValues(l), containerValues(r)
+
+				for _, op := range ops {
+					checkContainer(t, op.op.combine(l, r), op.model(lModel, rModel))
+				}
+				if !slices.Equal(containerValues(l), lVals) ||
+					!slices.Equal(containerValues(r), rVals) {
+					t.Fatal("combine modified its operands")
+				}
+				if containerSubset(l, r) != lModel.SubsetOf(rModel) {
+					t.Fatal("incorrect containerSubset")
+				}
+				if !containerSubset(l, l.clone()) {
+					t.Fatal("container isn't a subset of its clone")
+				}
+			}
+		}
+	}
+}
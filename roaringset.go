@@ -0,0 +1,855 @@
+package cm
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+
+	"golang.org/x/exp/constraints"
+)
+
+// A RoaringSet is a compressed set of non-negative integers, in the
+// style of Roaring bitmaps. Values are grouped into chunks of 65536 by
+// their high bits, and each chunk is stored in whichever of a sorted
+// array, a bitmap or a list of runs is smallest for its contents. Sparse
+// sets cost about 2 bytes per value, dense ones about 1 bit per value,
+// and contiguous ranges almost nothing, with set operations working a
+// chunk at a time.
+//
+// RoaringSet offers the same methods as Set. Adding a negative value
+// panics; a negative value is never contained in the set. Values are
+// iterated in ascending order.
+//
+// Adds and removes do not convert chunks into runs; after building a set
+// with many contiguous values, call Optimize to compress it. The results
+// of Union, Subtract, Intersect and XOR are always compressed.
+//
+// A RoaringSet can be serialized with MarshalBinary into a compact
+// format of its own, which UnmarshalBinary reads back. It is not the
+// portable Roaring serialization format.
+//
+// The zero-value of this struct is an empty set, ready to use.
+type RoaringSet[I constraints.Integer] struct {
+	// keys are the high bits of the values in each container, in
+	// ascending order.
+	keys       []uint64
+	containers []roaringContainer
+}
+
+// RoaringSetFromSlice loads a RoaringSet in from a slice.
+func RoaringSetFromSlice[I constraints.Integer](l []I) *RoaringSet[I] {
+	rs := &RoaringSet[I]{}
+	for _, val := range l {
+		rs.Add(val)
+	}
+	return rs
+}
+
+// RoaringSetFromIter loads a RoaringSet in from an iterator.
+func RoaringSetFromIter[I constraints.Integer](i iter.Seq[I]) *RoaringSet[I] {
+	rs := &RoaringSet[I]{}
+	for val := range i {
+		rs.Add(val)
+	}
+	return rs
+}
+
+// RoaringSetFromSet loads a RoaringSet in from a Set.
+func RoaringSetFromSet[I constraints.Integer](s Set[I]) *RoaringSet[I] {
+	rs := &RoaringSet[I]{}
+	for val := range s {
+		rs.Add(val)
+	}
+	return rs
+}
+
+func roaringSplit[I constraints.Integer](v I) (uint64, uint16) {
+	return uint64(v) >> 16, uint16(v)
+}
+
+func (rs *RoaringSet[I]) find(key uint64) (int, bool) {
+	return slices.BinarySearch(rs.keys, key)
+}
+
+// Add will add the given value in to the set.
+func (rs *RoaringSet[I]) Add(v I) {
+	if v < 0 {
+		panic("negative value added to RoaringSet")
+	}
+	key, low := roaringSplit(v)
+	i, found := rs.find(key)
+	if found {
+		rs.containers[i] = rs.containers[i].add(low)
+		return
+	}
+	rs.keys = slices.Insert(rs.keys, i, key)
+	rs.containers = slices.Insert(rs.containers, i, roaringContainer(arrayContainer{low}))
+}
+
+// Remove will remove the given value from the set if it exists.
+func (rs *RoaringSet[I]) Remove(v I) {
+	if v < 0 {
+		return
+	}
+	key, low := roaringSplit(v)
+	i, found := rs.find(key)
+	if !found {
+		return
+	}
+	c := rs.containers[i].remove(low)
+	if c == nil {
+		rs.keys = slices.Delete(rs.keys, i, i+1)
+		rs.containers = slices.Delete(rs.containers, i, i+1)
+		return
+	}
+	rs.containers[i] = c
+}
+
+// Contains returns true if the set contains the given value.
+func (rs *RoaringSet[I]) Contains(v I) bool {
+	if v < 0 {
+		return false
+	}
+	key, low := roaringSplit(v)
+	i, found := rs.find(key)
+	return found && rs.containers[i].contains(low)
+}
+
+// Len returns the number of values in the set.
+func (rs *RoaringSet[I]) Len() int {
+	n := 0
+	for _, c := range rs.containers {
+		n += c.card()
+	}
+	return n
+}
+
+// Optimize converts each chunk of the set into its most compact
+// representation, including runs.
+func (rs *RoaringSet[I]) Optimize() {
+	for i, c := range rs.containers {
+		rs.containers[i] = optimizeContainer(c)
+	}
+}
+
+// Clone returns a copy of the set.
+func (rs *RoaringSet[I]) Clone() *RoaringSet[I] {
+	r := &RoaringSet[I]{
+		keys:       slices.Clone(rs.keys),
+		containers: make([]roaringContainer, len(rs.containers)),
+	}
+	for i, c := range rs.containers {
+		r.containers[i] = c.clone()
+	}
+	return r
+}
+
+// Equal returns if two sets are equal.
+func (rs *RoaringSet[I]) Equal(r *RoaringSet[I]) bool {
+	if !slices.Equal(rs.keys, r.keys) {
+		return false
+	}
+	for i, c := range rs.containers {
+		if c.card() != r.containers[i].card() || !containerSubset(c, r.containers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SubsetOf returns true if the set this is called on is a subset of the
+// passed-in set.
+func (rs *RoaringSet[I]) SubsetOf(r *RoaringSet[I]) bool {
+	for i, key := range rs.keys {
+		j, found := r.find(key)
+		if !found || !containerSubset(rs.containers[i], r.containers[j]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SupersetOf returns true if the set this is called on is a superset of
+// the passed-in set.
+func (rs *RoaringSet[I]) SupersetOf(r *RoaringSet[I]) bool {
+	return r.SubsetOf(rs)
+}
+
+// Union will add all elements of the passed-in set to this set. This set
+// is then returned, allowing chaining.
+func (rs *RoaringSet[I]) Union(r *RoaringSet[I]) *RoaringSet[I] {
+	rs.keys, rs.containers = rs.combine(r, roaringUnion, false)
+	return rs
+}
+
+// Subtract removes all elements from this set that are in the passed-in
+// set.
+//
+// The set this is called on is returned, allowing for chaining.
+func (rs *RoaringSet[I]) Subtract(r *RoaringSet[I]) *RoaringSet[I] {
+	rs.keys, rs.containers = rs.combine(r, roaringSubtract, false)
+	return rs
+}
+
+// Intersect returns a new set containing only the elements that exist
+// in both sets.
+func (rs *RoaringSet[I]) Intersect(r *RoaringSet[I]) *RoaringSet[I] {
+	keys, containers := rs.combine(r, roaringIntersect, true)
+	return &RoaringSet[I]{keys, containers}
+}
+
+// XOR returns a new set containing the values in either the set this
+// method is called on, or the set passed in, but not both.
+func (rs *RoaringSet[I]) XOR(r *RoaringSet[I]) *RoaringSet[I] {
+	keys, containers := rs.combine(r, roaringXOR, true)
+	return &RoaringSet[I]{keys, containers}
+}
+
+// combine applies the operation to the two sets chunk by chunk. The
+// containers of r are never shared with the result; those of this set
+// are unless cloneL is true.
+func (rs *RoaringSet[I]) combine(
+	r *RoaringSet[I],
+	op roaringOp,
+	cloneL bool,
+) ([]uint64, []roaringContainer) {
+	var keys []uint64
+	var containers []roaringContainer
+	keep := func(key uint64, c roaringContainer) {
+		if c != nil {
+			keys = append(keys, key)
+			containers = append(containers, c)
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(rs.keys) || j < len(r.keys) {
+		switch {
+		case j == len(r.keys) || (i < len(rs.keys) && rs.keys[i] < r.keys[j]):
+			if op.onlyL {
+				c := rs.containers[i]
+				if cloneL {
+					c = c.clone()
+				}
+				keep(rs.keys[i], c)
+			}
+			i++
+		case i == len(rs.keys) || r.keys[j] < rs.keys[i]:
+			if op.onlyR {
+				keep(r.keys[j], r.containers[j].clone())
+			}
+			j++
+		default:
+			keep(rs.keys[i], op.combine(rs.containers[i], r.containers[j]))
+			i++
+			j++
+		}
+	}
+	return keys, containers
+}
+
+// All returns an iterator over the values in the set, in ascending
+// order.
+func (rs *RoaringSet[I]) All() iter.Seq[I] {
+	return func(yield func(I) bool) {
+		for i, key := range rs.keys {
+			high := key << 16
+			if !rs.containers[i].each(func(low uint16) bool {
+				return yield(I(high | uint64(low)))
+			}) {
+				return
+			}
+		}
+	}
+}
+
+// AsSlice returns the values in the set as a slice, in ascending order.
+func (rs *RoaringSet[I]) AsSlice() []I {
+	vals := make([]I, 0, rs.Len())
+	for val := range rs.All() {
+		vals = append(vals, val)
+	}
+	return vals
+}
+
+// AsSet returns the values in the set as a Set.
+func (rs *RoaringSet[I]) AsSet() Set[I] {
+	s := make(Set[I], rs.Len())
+	for val := range rs.All() {
+		s[val] = void
+	}
+	return s
+}
+
+// ErrRoaringEncoding is returned by RoaringSet's UnmarshalBinary for data
+// that was not produced by MarshalBinary, or that holds values that do
+// not fit in the set's type.
+var ErrRoaringEncoding = errors.New("invalid RoaringSet encoding")
+
+const roaringFormatVersion = 1
+
+const (
+	roaringTypeArray byte = iota
+	roaringTypeBitmap
+	roaringTypeRun
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+//
+// The encoding is a version byte and the number of chunks, followed by
+// each chunk's key as a delta from the previous one, its type, and its
+// contents as stored in memory. It never returns an error.
+func (rs *RoaringSet[I]) MarshalBinary() ([]byte, error) {
+	buf := []byte{roaringFormatVersion}
+	buf = binary.AppendUvarint(buf, uint64(len(rs.keys)))
+	prev := uint64(0)
+	for i, key := range rs.keys {
+		buf = binary.AppendUvarint(buf, key-prev)
+		prev = key
+		switch c := rs.containers[i].(type) {
+		case arrayContainer:
+			buf = append(buf, roaringTypeArray)
+			buf = binary.AppendUvarint(buf, uint64(len(c)))
+			for _, v := range c {
+				buf = binary.LittleEndian.AppendUint16(buf, v)
+			}
+		case *bitmapContainer:
+			buf = append(buf, roaringTypeBitmap)
+			for _, w := range c.words {
+				buf = binary.LittleEndian.AppendUint64(buf, w)
+			}
+		case runContainer:
+			buf = append(buf, roaringTypeRun)
+			buf = binary.AppendUvarint(buf, uint64(len(c)))
+			for _, run := range c {
+				buf = binary.LittleEndian.AppendUint16(buf, run.start)
+				buf = binary.LittleEndian.AppendUint16(buf, run.last)
+			}
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, replacing the
+// contents of the set with the encoded set.
+//
+// Invalid data returns an error wrapping ErrRoaringEncoding, and leaves
+// the set unchanged.
+func (rs *RoaringSet[I]) UnmarshalBinary(data []byte) error {
+	d := &roaringDecoder{data: data}
+	if version := d.readByte(); d.err == nil && version != roaringFormatVersion {
+		d.fail(fmt.Sprintf("unknown version %d", version))
+	}
+	count := d.uvarint()
+	// Every chunk takes at least three bytes, so this rejects absurd
+	// counts before allocating for them.
+	if count > uint64(len(data)) {
+		d.fail("too many chunks")
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	keys := make([]uint64, 0, count)
+	containers := make([]roaringContainer, 0, count)
+	var key uint64
+	for i := range count {
+		delta := d.uvarint()
+		c := d.container()
+		if d.err != nil {
+			return d.err
+		}
+		if i > 0 && delta == 0 {
+			return d.fail("chunks out of order")
+		}
+		if delta > math.MaxUint64>>16-key {
+			return d.fail("chunk key out of range")
+		}
+		key += delta
+
+		last := uint16(0)
+		c.each(func(v uint16) bool {
+			last = v
+			return true
+		})
+		u := key<<16 | uint64(last)
+		if v := I(u); v < 0 || uint64(v) != u {
+			return d.fail(fmt.Sprintf("value %d does not fit in %T", u, v))
+		}
+
+		keys = append(keys, key)
+		containers = append(containers, c)
+	}
+	if len(d.data) != 0 {
+		return d.fail("trailing data")
+	}
+
+	rs.keys, rs.containers = keys, containers
+	return nil
+}
+
+// roaringDecoder reads the binary encoding of a RoaringSet. After the
+// first failure, it records the error and all further reads return zero
+// values.
+type roaringDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *roaringDecoder) fail(msg string) error {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w: %s", ErrRoaringEncoding, msg)
+	}
+	return d.err
+}
+
+func (d *roaringDecoder) next(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.data)) < n {
+		d.fail("truncated")
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b
+}
+
+func (d *roaringDecoder) readByte() byte {
+	b := d.next(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *roaringDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail("bad varint")
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+// container reads a container, checking that it is in the form
+// MarshalBinary produces.
+func (d *roaringDecoder) container() roaringContainer {
+	switch d.readByte() {
+	case roaringTypeArray:
+		n := d.uvarint()
+		if d.err == nil && (n == 0 || n > roaringArrayMax) {
+			d.fail("bad array size")
+		}
+		raw := d.next(2 * n)
+		if d.err != nil {
+			return nil
+		}
+		a := make(arrayContainer, n)
+		for i := range a {
+			a[i] = binary.LittleEndian.Uint16(raw[2*i:])
+			if i > 0 && a[i] <= a[i-1] {
+				d.fail("array out of order")
+				return nil
+			}
+		}
+		return a
+
+	case roaringTypeBitmap:
+		raw := d.next(8 * roaringWords)
+		if d.err != nil {
+			return nil
+		}
+		b := &bitmapContainer{}
+		for i := range b.words {
+			b.words[i] = binary.LittleEndian.Uint64(raw[8*i:])
+		}
+		b.recount()
+		if b.n <= roaringArrayMax {
+			d.fail("bitmap too sparse")
+			return nil
+		}
+		return b
+
+	case roaringTypeRun:
+		n := d.uvarint()
+		if d.err == nil && (n == 0 || n > roaringRunMax) {
+			d.fail("bad run count")
+		}
+		raw := d.next(4 * n)
+		if d.err != nil {
+			return nil
+		}
+		r := make(runContainer, n)
+		for i := range r {
+			r[i].start = binary.LittleEndian.Uint16(raw[4*i:])
+			r[i].last = binary.LittleEndian.Uint16(raw[4*i+2:])
+			if r[i].last < r[i].start ||
+				(i > 0 && int(r[i].start) <= int(r[i-1].last)+1) {
+				d.fail("runs out of order")
+				return nil
+			}
+		}
+		return r
+	}
+
+	d.fail("unknown chunk type")
+	return nil
+}
+
+// A RoaringMapSet is a MapSet whose values are integers stored in
+// RoaringSets, for when there are so many values that the memory used by
+// a Set for each key dominates.
+//
+// As with MapSet, it is safe to read and write the sets directly, and
+// Delete removes a key when its set becomes empty. Unlike a nil Set, a
+// nil *RoaringSet can not be used as an empty set, so use Contains rather
+// than indexing to check for values.
+//
+// A RoaringMapSet can be serialized with MarshalBinary if its keys are
+// strings, integers, or implement encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler. For other keys, MarshalBinaryFunc and
+// UnmarshalBinaryFunc take functions to encode and decode them.
+type RoaringMapSet[K comparable, V constraints.Integer] map[K]*RoaringSet[V]
+
+// RoaringMapSetFromMapSet returns a RoaringMapSet with the same contents
+// as the passed-in MapSet.
+func RoaringMapSetFromMapSet[K comparable, V constraints.Integer](
+	ms MapSet[K, V],
+) RoaringMapSet[K, V] {
+	rms := make(RoaringMapSet[K, V], len(ms))
+	for key, s := range ms {
+		if len(s) > 0 {
+			rms[key] = RoaringSetFromSet(s)
+		}
+	}
+	return rms
+}
+
+// Add adds the given value to the set for the given key.
+func (rms RoaringMapSet[K, V]) Add(key K, val V) {
+	if rms == nil {
+		panic("Add called on a nil RoaringMapSet")
+	}
+	s := rms[key]
+	if s == nil {
+		s = &RoaringSet[V]{}
+		rms[key] = s
+	}
+	s.Add(val)
+}
+
+// AddByTuple will add to the set via the given tuple.
+func (rms RoaringMapSet[K, V]) AddByTuple(key Tuple2[K, V]) {
+	rms.Add(key.Key1, key.Key2)
+}
+
+// Union will union the passed-in set into the set for the given key,
+// creating it if necessary. An empty or nil set creates nothing.
+func (rms RoaringMapSet[K, V]) Union(key K, r *RoaringSet[V]) {
+	if rms == nil {
+		panic("Union called on a nil RoaringMapSet")
+	}
+	if r == nil || len(r.keys) == 0 {
+		return
+	}
+	s := rms[key]
+	if s == nil {
+		s = &RoaringSet[V]{}
+		rms[key] = s
+	}
+	s.Union(r)
+}
+
+// Delete removes the value from the set for the given key, deleting the
+// set if it is now empty.
+func (rms RoaringMapSet[K, V]) Delete(key K, val V) {
+	s := rms[key]
+	if s == nil {
+		return
+	}
+	s.Remove(val)
+	if len(s.keys) == 0 {
+		delete(rms, key)
+	}
+}
+
+// Contains returns true if the set for the given key contains the value.
+func (rms RoaringMapSet[K, V]) Contains(key K, val V) bool {
+	s := rms[key]
+	return s != nil && s.Contains(val)
+}
+
+// Optimize calls Optimize on every set in the RoaringMapSet.
+func (rms RoaringMapSet[K, V]) Optimize() {
+	for _, s := range rms {
+		s.Optimize()
+	}
+}
+
+// AllValueSet returns a single set containing all values in the
+// RoaringMapSet.
+func (rms RoaringMapSet[K, V]) AllValueSet() *RoaringSet[V] {
+	retSet := &RoaringSet[V]{}
+	for _, s := range rms {
+		retSet.Union(s)
+	}
+	return retSet
+}
+
+// All returns an iterator that yields every key and value pair in the
+// RoaringMapSet.
+func (rms RoaringMapSet[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, s := range rms {
+			for val := range s.All() {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Values yields all the values in the RoaringMapSet as an iterator. A
+// value in the sets of several keys is yielded once for each of them.
+func (rms RoaringMapSet[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range rms.All() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// AsMapSet returns the contents of the RoaringMapSet as a MapSet.
+func (rms RoaringMapSet[K, V]) AsMapSet() MapSet[K, V] {
+	ms := make(MapSet[K, V], len(rms))
+	for key, s := range rms {
+		ms[key] = s.AsSet()
+	}
+	return ms
+}
+
+// ErrRoaringKeyType is returned by RoaringMapSet's MarshalBinary and
+// UnmarshalBinary for a key type they can not encode. Use
+// MarshalBinaryFunc and UnmarshalBinaryFunc for such keys.
+var ErrRoaringKeyType = errors.New("RoaringMapSet key type has no binary encoding")
+
+var (
+	binaryMarshalerType   = reflect.TypeFor[encoding.BinaryMarshaler]()
+	binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+)
+
+// roaringKeyCodec returns the functions MarshalBinary and
+// UnmarshalBinary use to encode and decode keys of the given type, or
+// false if there are none.
+func roaringKeyCodec[K comparable]() (
+	encode func(K) ([]byte, error),
+	decode func([]byte) (K, error),
+	ok bool,
+) {
+	t := reflect.TypeFor[K]()
+	if t.Implements(binaryMarshalerType) &&
+		reflect.PointerTo(t).Implements(binaryUnmarshalerType) {
+		encode = func(key K) ([]byte, error) {
+			return any(key).(encoding.BinaryMarshaler).MarshalBinary()
+		}
+		decode = func(b []byte) (K, error) {
+			var key K
+			err := any(&key).(encoding.BinaryUnmarshaler).UnmarshalBinary(b)
+			return key, err
+		}
+		return encode, decode, true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		encode = func(key K) ([]byte, error) {
+			return []byte(reflect.ValueOf(key).String()), nil
+		}
+		decode = func(b []byte) (K, error) {
+			var key K
+			reflect.ValueOf(&key).Elem().SetString(string(b))
+			return key, nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		encode = func(key K) ([]byte, error) {
+			return binary.AppendVarint(nil, reflect.ValueOf(key).Int()), nil
+		}
+		decode = func(b []byte) (K, error) {
+			var key K
+			v := reflect.ValueOf(&key).Elem()
+			i, n := binary.Varint(b)
+			if n != len(b) || v.OverflowInt(i) {
+				return key, fmt.Errorf("%w: bad key", ErrRoaringEncoding)
+			}
+			v.SetInt(i)
+			return key, nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		encode = func(key K) ([]byte, error) {
+			return binary.AppendUvarint(nil, reflect.ValueOf(key).Uint()), nil
+		}
+		decode = func(b []byte) (K, error) {
+			var key K
+			v := reflect.ValueOf(&key).Elem()
+			u, n := binary.Uvarint(b)
+			if n != len(b) || v.OverflowUint(u) {
+				return key, fmt.Errorf("%w: bad key", ErrRoaringEncoding)
+			}
+			v.SetUint(u)
+			return key, nil
+		}
+
+	default:
+		return nil, nil, false
+	}
+	return encode, decode, true
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, for keys that are
+// strings, integers, or implement encoding.BinaryMarshaler. For any
+// other key type, it returns ErrRoaringKeyType.
+//
+// See MarshalBinaryFunc for the encoding.
+func (rms RoaringMapSet[K, V]) MarshalBinary() ([]byte, error) {
+	encodeKey, _, ok := roaringKeyCodec[K]()
+	if !ok {
+		return nil, ErrRoaringKeyType
+	}
+	return rms.MarshalBinaryFunc(encodeKey)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, for keys that
+// are strings, integers, or implement encoding.BinaryUnmarshaler. For
+// any other key type, it returns ErrRoaringKeyType.
+//
+// See UnmarshalBinaryFunc.
+func (rms *RoaringMapSet[K, V]) UnmarshalBinary(data []byte) error {
+	_, decodeKey, ok := roaringKeyCodec[K]()
+	if !ok {
+		return ErrRoaringKeyType
+	}
+	return rms.UnmarshalBinaryFunc(data, decodeKey)
+}
+
+// MarshalBinaryFunc serializes the RoaringMapSet, using the passed-in
+// function to encode each key. An error from that function is returned
+// as-is.
+//
+// The encoding is a version byte and the number of keys, followed by
+// each encoded key and the MarshalBinary encoding of its set, each
+// prefixed by its length. Keys are written in the order of their
+// encodings, so equal RoaringMapSets encode identically. Empty sets are
+// not written.
+func (rms RoaringMapSet[K, V]) MarshalBinaryFunc(
+	encodeKey func(K) ([]byte, error),
+) ([]byte, error) {
+	type entry struct {
+		key, set []byte
+	}
+	entries := make([]entry, 0, len(rms))
+	for key, s := range rms {
+		if s == nil || len(s.keys) == 0 {
+			continue
+		}
+		k, err := encodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		set, _ := s.MarshalBinary()
+		entries = append(entries, entry{k, set})
+	}
+	slices.SortFunc(entries, func(l, r entry) int {
+		return bytes.Compare(l.key, r.key)
+	})
+
+	buf := []byte{roaringFormatVersion}
+	buf = binary.AppendUvarint(buf, uint64(len(entries)))
+	for _, e := range entries {
+		buf = binary.AppendUvarint(buf, uint64(len(e.key)))
+		buf = append(buf, e.key...)
+		buf = binary.AppendUvarint(buf, uint64(len(e.set)))
+		buf = append(buf, e.set...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinaryFunc replaces the contents of the RoaringMapSet with the
+// encoding produced by MarshalBinaryFunc, using the passed-in function to
+// decode each key.
+//
+// Invalid data returns an error wrapping ErrRoaringEncoding, and an error
+// from the key function is returned as-is. Either way, the RoaringMapSet
+// is left unchanged.
+func (rms *RoaringMapSet[K, V]) UnmarshalBinaryFunc(
+	data []byte,
+	decodeKey func([]byte) (K, error),
+) error {
+	d := &roaringDecoder{data: data}
+	if version := d.readByte(); d.err == nil && version != roaringFormatVersion {
+		d.fail(fmt.Sprintf("unknown version %d", version))
+	}
+	count := d.uvarint()
+	// Every key takes at least two bytes, so this rejects absurd counts
+	// before allocating for them.
+	if count > uint64(len(data)) {
+		d.fail("too many keys")
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	decoded := make(RoaringMapSet[K, V], count)
+	for range count {
+		k := d.next(d.uvarint())
+		set := d.next(d.uvarint())
+		if d.err != nil {
+			return d.err
+		}
+		key, err := decodeKey(k)
+		if err != nil {
+			return err
+		}
+		if _, exists := decoded[key]; exists {
+			return d.fail("duplicate key")
+		}
+		s := &RoaringSet[V]{}
+		err = s.UnmarshalBinary(set)
+		if err != nil {
+			return err
+		}
+		if len(s.keys) == 0 {
+			return d.fail("empty set")
+		}
+		decoded[key] = s
+	}
+	if len(d.data) != 0 {
+		return d.fail("trailing data")
+	}
+
+	if *rms == nil {
+		*rms = decoded
+		return nil
+	}
+	clear(*rms)
+	maps.Copy(*rms, decoded)
+	return nil
+}
@@ -0,0 +1,414 @@
+package cm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRoaringSet(t *testing.T) {
+	var rs RoaringSet[int]
+	rs.Add(3)
+	rs.Add(1 << 20)
+	rs.Add(70000)
+	rs.Add(3)
+	rs.Remove(70000)
+	rs.Remove(1 << 30)
+	rs.Remove(4)
+	rs.Remove(-1)
+
+	if rs.Len() != 2 || !rs.Contains(1<<20) || rs.Contains(70000) ||
+		rs.Contains(-1) || rs.Contains(1<<30) {
+		t.Fatal("incorrect contents")
+	}
+	if len(rs.keys) != 2 {
+		t.Fatal("empty chunk wasn't removed")
+	}
+	if !slices.Equal(rs.AsSlice(), []int{3, 1 << 20}) {
+		t.Fatal("incorrect AsSlice")
+	}
+	for range rs.All() {
+		break
+	}
+
+	clone := rs.Clone()
+	if !clone.Equal(&rs) {
+		t.Fatal("incorrect Clone")
+	}
+	clone.Add(4)
+	if clone.Equal(&rs) || rs.Contains(4) {
+		t.Fatal("clone isn't independent")
+	}
+	clone.Remove(4)
+	clone.Remove(3)
+	clone.Add(5)
+	if clone.Equal(&rs) {
+		t.Fatal("sets with different values are equal")
+	}
+
+	s := rs.AsSet()
+	if !s.Equal(SetFromSlice([]int{3, 1 << 20})) {
+		t.Fatal("incorrect AsSet")
+	}
+	if !RoaringSetFromSet(s).Equal(&rs) ||
+		!RoaringSetFromSlice([]int{1 << 20, 3}).Equal(&rs) ||
+		!RoaringSetFromIter(rs.All()).Equal(&rs) {
+		t.Fatal("incorrect construction")
+	}
+
+	panics(t, "negative value added to RoaringSet", func() { rs.Add(-1) })
+}
+
+// randomRoaringModel returns a random set of values spread over a few
+// chunks, with a mix of sparse, dense and contiguous chunks.
+func randomRoaringModel(rng *rand.Rand) Set[uint32] {
+	model := Set[uint32]{}
+	for range rng.Intn(4) {
+		base := uint32(rng.Intn(6)) << 16
+		switch rng.Intn(3) {
+		case 0:
+			for range rng.Intn(50) {
+				model.Add(base | uint32(rng.Intn(1<<16)))
+			}
+		case 1:
+			for range 6000 {
+				model.Add(base | uint32(rng.Intn(1<<13)))
+			}
+		default:
+			start := rng.Intn(1<<16 - 2000)
+			for v := start; v < start+rng.Intn(2000); v++ {
+				model.Add(base | uint32(v))
+			}
+		}
+	}
+	return model
+}
+
+func TestRoaringSetOperations(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for range 100 {
+		ls, rs := randomRoaringModel(rng), randomRoaringModel(rng)
+		l, r := RoaringSetFromSet(ls), RoaringSetFromSet(rs)
+		if rng.Intn(2) == 0 {
+			l.Optimize()
+		}
+
+		if !l.Clone().Union(r).AsSet().Equal(ls.Clone().Union(rs)) {
+			t.Fatal("incorrect Union")
+		}
+		if !l.Clone().Subtract(r).AsSet().Equal(ls.Clone().Subtract(rs)) {
+			t.Fatal("incorrect Subtract")
+		}
+		if !l.Intersect(r).AsSet().Equal(ls.Intersect(rs)) {
+			t.Fatal("incorrect Intersect")
+		}
+		if !l.XOR(r).AsSet().Equal(ls.XOR(rs)) {
+			t.Fatal("incorrect XOR")
+		}
+		if l.SubsetOf(r) != ls.SubsetOf(rs) || l.SupersetOf(r) != ls.SupersetOf(rs) {
+			t.Fatal("incorrect SubsetOf")
+		}
+		if l.Equal(r) != ls.Equal(rs) {
+			t.Fatal("incorrect Equal")
+		}
+		if !l.Intersect(r).SubsetOf(l) || !l.Union(r).SupersetOf(r) {
+			t.Fatal("incorrect SubsetOf on derived sets")
+		}
+
+		if l.Len() != len(l.AsSet()) {
+			t.Fatal("incorrect Len")
+		}
+		vals := l.AsSlice()
+		if !slices.IsSorted(vals) {
+			t.Fatal("values aren't in order")
+		}
+	}
+}
+
+func TestRoaringSetBinary(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for range 50 {
+		rs := RoaringSetFromSet(randomRoaringModel(rng))
+		rs.Optimize()
+		data, err := rs.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded RoaringSet[uint32]
+		if err := decoded.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if !decoded.Equal(rs) {
+			t.Fatal("round trip changed the set")
+		}
+	}
+
+	// a set with one of each container type, which also covers the
+	// unoptimized array and bitmap types.
+	var all RoaringSet[uint32]
+	all.Add(1)
+	for v := range 10000 {
+		all.Add(1<<16 | uint32(v*2))
+	}
+	all.Union(RoaringSetFromSlice([]uint32{2 << 16, 2<<16 | 1, 2<<16 | 2}))
+	all.Optimize()
+	data, _ := all.MarshalBinary()
+	var decoded RoaringSet[uint32]
+	if err := decoded.UnmarshalBinary(data); err != nil || !decoded.Equal(&all) {
+		t.Fatal("round trip of all container types failed")
+	}
+}
+
+func TestRoaringSetBinaryErrors(t *testing.T) {
+	uv := func(v uint64) []byte { return binary.AppendUvarint(nil, v) }
+	u16 := func(vals ...uint16) []byte {
+		var b []byte
+		for _, v := range vals {
+			b = binary.LittleEndian.AppendUint16(b, v)
+		}
+		return b
+	}
+	enc := func(parts ...[]byte) []byte {
+		return slices.Concat(append([][]byte{{roaringFormatVersion}}, parts...)...)
+	}
+	array := func(vals ...uint16) []byte {
+		return slices.Concat([]byte{roaringTypeArray}, uv(uint64(len(vals))), u16(vals...))
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":               nil,
+		"version":             {2, 0},
+		"too many chunks":     enc(uv(100)),
+		"bad varint":          enc([]byte{0x80}),
+		"truncated chunk":     enc(uv(1), uv(0)),
+		"out of order":        enc(uv(2), uv(1), array(1), uv(0), array(2)),
+		"key overflow":        enc(uv(2), uv(0), array(1), uv(1<<48), array(1)),
+		"empty array":         enc(uv(1), uv(0), []byte{roaringTypeArray}, uv(0)),
+		"huge array":          enc(uv(1), uv(0), []byte{roaringTypeArray}, uv(5000)),
+		"truncated array":     enc(uv(1), uv(0), []byte{roaringTypeArray}, uv(2), u16(1)),
+		"unsorted array":      enc(uv(1), uv(0), array(2, 1)),
+		"sparse bitmap":       enc(uv(1), uv(0), []byte{roaringTypeBitmap}, make([]byte, 8192)),
+		"truncated bitmap":    enc(uv(1), uv(0), []byte{roaringTypeBitmap}, make([]byte, 10)),
+		"no runs":             enc(uv(1), uv(0), []byte{roaringTypeRun}, uv(0)),
+		"truncated runs":      enc(uv(1), uv(0), []byte{roaringTypeRun}, uv(1), u16(1)),
+		"backwards run":       enc(uv(1), uv(0), []byte{roaringTypeRun}, uv(1), u16(5, 1)),
+		"adjacent runs":       enc(uv(1), uv(0), []byte{roaringTypeRun}, uv(2), u16(1, 2, 3, 4)),
+		"unknown chunk type":  enc(uv(1), uv(0), []byte{3}),
+		"trailing data":       enc(uv(1), uv(0), array(1), []byte{0}),
+		"value out of range":  enc(uv(1), uv(1<<47), array(1)),
+		"truncated chunk key": enc(uv(1)),
+	} {
+		rs := RoaringSetFromSlice([]int64{1, 2})
+		err := rs.UnmarshalBinary(data)
+		if !errors.Is(err, ErrRoaringEncoding) {
+			t.Fatalf("%s: expected an encoding error, got %v", name, err)
+		}
+		if !slices.Equal(rs.AsSlice(), []int64{1, 2}) {
+			t.Fatalf("%s: failed unmarshal changed the set", name)
+		}
+	}
+
+	// values that fit in a larger type do not fit in a smaller one
+	data, _ := RoaringSetFromSlice([]int{200}).MarshalBinary()
+	var small RoaringSet[int8]
+	if err := small.UnmarshalBinary(data); !errors.Is(err, ErrRoaringEncoding) {
+		t.Fatal("out of range value was accepted")
+	}
+	var fits RoaringSet[uint8]
+	if err := fits.UnmarshalBinary(data); err != nil || !fits.Contains(200) {
+		t.Fatal("in range value was rejected")
+	}
+}
+
+func TestRoaringMapSet(t *testing.T) {
+	rms := RoaringMapSet[string, int]{}
+	rms.Add("a", 1)
+	rms.Add("a", 2)
+	rms.AddByTuple(Tuple2[string, int]{"b", 2})
+	rms.Union("c", RoaringSetFromSlice([]int{3, 4}))
+	rms.Union("c", RoaringSetFromSlice([]int{5}))
+	rms.Union("empty", &RoaringSet[int]{})
+	rms.Union("nil", nil)
+	if _, exists := rms["empty"]; exists {
+		t.Fatal("Union with an empty set created an empty set")
+	}
+	if _, exists := rms["nil"]; exists {
+		t.Fatal("Union with a nil set created an empty set")
+	}
+	rms.Optimize()
+
+	if !rms.Contains("a", 2) || rms.Contains("a", 3) || rms.Contains("z", 1) {
+		t.Fatal("incorrect Contains")
+	}
+	if !slices.Equal(rms.AllValueSet().AsSlice(), []int{1, 2, 3, 4, 5}) {
+		t.Fatal("incorrect AllValueSet")
+	}
+	values := slices.Collect(rms.Values())
+	slices.Sort(values)
+	if !slices.Equal(values, []int{1, 2, 2, 3, 4, 5}) {
+		t.Fatal("incorrect Values")
+	}
+	for range rms.Values() {
+		break
+	}
+
+	ms := rms.AsMapSet()
+	if !ms["c"].Equal(SetFromSlice([]int{3, 4, 5})) || len(ms) != 3 {
+		t.Fatal("incorrect AsMapSet")
+	}
+	ms["d"] = Set[int]{}
+	back := RoaringMapSetFromMapSet(ms)
+	if len(back) != 3 || !back["a"].Equal(rms["a"]) {
+		t.Fatal("incorrect RoaringMapSetFromMapSet")
+	}
+
+	rms.Delete("a", 1)
+	rms.Delete("a", 2)
+	rms.Delete("z", 1)
+	if _, exists := rms["a"]; exists {
+		t.Fatal("Delete didn't clean up the set")
+	}
+
+	var nilMapSet RoaringMapSet[string, int]
+	panics(t, "Add called on a nil RoaringMapSet", func() { nilMapSet.Add("a", 1) })
+	panics(t, "Union called on a nil RoaringMapSet", func() {
+		nilMapSet.Union("a", &RoaringSet[int]{})
+	})
+}
+
+func TestRoaringMapSetBinary(t *testing.T) {
+	rms := RoaringMapSet[string, int]{}
+	rms.Add("a", 1)
+	rms.Union("b", RoaringSetFromSlice([]int{2, 3}))
+	rms["empty"] = &RoaringSet[int]{}
+	rms["nil"] = nil
+
+	data, err := rms.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := RoaringMapSet[string, int]{}
+	decoded.Add("old", 1)
+	err = decoded.UnmarshalBinary(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || !decoded["a"].Equal(rms["a"]) || !decoded["b"].Equal(rms["b"]) {
+		t.Fatal("RoaringMapSet didn't round trip")
+	}
+
+	// the encoding doesn't depend on map order
+	again := RoaringMapSet[string, int]{}
+	again.Union("b", RoaringSetFromSlice([]int{2, 3}))
+	again.Add("a", 1)
+	if data2, _ := again.MarshalBinary(); !bytes.Equal(data, data2) {
+		t.Fatal("equal RoaringMapSets encoded differently")
+	}
+
+	var nilRMS RoaringMapSet[int8, int]
+	data, _ = RoaringMapSet[int, int]{-1: RoaringSetFromSlice([]int{1})}.MarshalBinary()
+	if err := nilRMS.UnmarshalBinary(data); err != nil || !nilRMS.Contains(-1, 1) {
+		t.Fatal("signed keys didn't round trip")
+	}
+	data, _ = RoaringMapSet[int, int]{200: RoaringSetFromSlice([]int{1})}.MarshalBinary()
+	if err := nilRMS.UnmarshalBinary(data); !errors.Is(err, ErrRoaringEncoding) {
+		t.Fatal("out of range signed key was accepted")
+	}
+	var uintRMS RoaringMapSet[uint8, int]
+	data, _ = RoaringMapSet[uint, int]{200: RoaringSetFromSlice([]int{1})}.MarshalBinary()
+	if err := uintRMS.UnmarshalBinary(data); err != nil || !uintRMS.Contains(200, 1) {
+		t.Fatal("unsigned keys didn't round trip")
+	}
+	data, _ = RoaringMapSet[uint, int]{300: RoaringSetFromSlice([]int{1})}.MarshalBinary()
+	if err := uintRMS.UnmarshalBinary(data); !errors.Is(err, ErrRoaringEncoding) {
+		t.Fatal("out of range unsigned key was accepted")
+	}
+
+	timeRMS := RoaringMapSet[time.Time, int]{}
+	timeRMS.Add(time.Unix(100, 0).UTC(), 1)
+	data, err = timeRMS.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var timeDecoded RoaringMapSet[time.Time, int]
+	err = timeDecoded.UnmarshalBinary(data)
+	if err != nil || !timeDecoded.Contains(time.Unix(100, 0).UTC(), 1) {
+		t.Fatal("BinaryMarshaler keys didn't round trip")
+	}
+
+	structRMS := RoaringMapSet[structID, int]{}
+	if _, err := structRMS.MarshalBinary(); err != ErrRoaringKeyType {
+		t.Fatal("could marshal a key type with no binary encoding")
+	}
+	if err := structRMS.UnmarshalBinary(nil); err != ErrRoaringKeyType {
+		t.Fatal("could unmarshal a key type with no binary encoding")
+	}
+
+	structRMS.Add(structID{1, "x"}, 5)
+	data, err = structRMS.MarshalBinaryFunc(func(key structID) ([]byte, error) {
+		return []byte(key.B), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var structDecoded RoaringMapSet[structID, int]
+	err = structDecoded.UnmarshalBinaryFunc(data, func(b []byte) (structID, error) {
+		return structID{1, string(b)}, nil
+	})
+	if err != nil || !structDecoded.Contains(structID{1, "x"}, 5) {
+		t.Fatal("RoaringMapSet didn't round trip with key functions")
+	}
+
+	keyErr := errors.New("key error")
+	_, err = structRMS.MarshalBinaryFunc(func(structID) ([]byte, error) {
+		return nil, keyErr
+	})
+	if err != keyErr {
+		t.Fatal("key encoding error not returned")
+	}
+	err = structDecoded.UnmarshalBinaryFunc(data, func([]byte) (structID, error) {
+		return structID{}, keyErr
+	})
+	if err != keyErr || !structDecoded.Contains(structID{1, "x"}, 5) {
+		t.Fatal("key decoding error not returned")
+	}
+}
+
+func TestRoaringMapSetBinaryErrors(t *testing.T) {
+	uv := func(v uint64) []byte { return binary.AppendUvarint(nil, v) }
+	set, _ := RoaringSetFromSlice([]int{1}).MarshalBinary()
+	emptySet, _ := (&RoaringSet[int]{}).MarshalBinary()
+	entry := func(key string, set []byte) []byte {
+		return slices.Concat(uv(uint64(len(key))), []byte(key), uv(uint64(len(set))), set)
+	}
+	enc := func(parts ...[]byte) []byte {
+		return slices.Concat(append([][]byte{{roaringFormatVersion}}, parts...)...)
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":         nil,
+		"version":       {2, 0},
+		"too many keys": enc(uv(100)),
+		"truncated key": enc(uv(1), uv(5), []byte("a")),
+		"duplicate key": enc(uv(2), entry("a", set), entry("a", set)),
+		"empty set":     enc(uv(1), entry("a", emptySet)),
+		"bad set":       enc(uv(1), entry("a", []byte{2})),
+		"trailing data": enc(uv(1), entry("a", set), []byte{0}),
+	} {
+		rms := RoaringMapSet[string, int]{}
+		rms.Add("old", 1)
+		err := rms.UnmarshalBinary(data)
+		if !errors.Is(err, ErrRoaringEncoding) {
+			t.Fatalf("%s: expected an encoding error, got %v", name, err)
+		}
+		if len(rms) != 1 || !rms.Contains("old", 1) {
+			t.Fatalf("%s: failed unmarshal changed the RoaringMapSet", name)
+		}
+	}
+}
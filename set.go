@@ -10,8 +10,8 @@ import "iter"
 // maps.Keys. to clear a set, use maps.Clear, to get the number of elements
 // in a set, use length(), to iterate over the set, use range in a for
 // loop, etc. This only implements additional useful set
-// functionality. (In particular, there is no useful rangefunc to
-// offer that is not already provided by being a map under the hood.)
+// functionality. The exceptions are Len and All, which exist so that a
+// Set can be used as a ReadSet.
 //
 // This Set offer high-efficiency mutating operations on the map; for
 // instance, Union will copy the target Set into the Set the method is
@@ -29,10 +29,10 @@ var void = struct{}{}
 
 // SetFromSlice loads a set in from a slice.
 //
-// It is not appropriate or indeed even possible for a library to select
-// how to serialize specific sets, but it can be helpful for JSON or YAML
-// serialization to embed a specific Set and then use this and AsSlice as a
-// pair to serialize a set as a slice instead of a map.
+// Sets already serialize to and from JSON as an array of their values,
+// via MarshalJSON and UnmarshalJSON. For other serializations, such as
+// YAML, this and AsSlice can be used as a pair to serialize a set as a
+// slice instead of a map.
 func SetFromSlice[M comparable](l []M) Set[M] {
 	s := Set[M]{}
 
@@ -85,6 +85,23 @@ func (s Set[M]) Clone() Set[M] {
 	return newSet
 }
 
+// Len returns the number of values in the set, just as len does.
+func (s Set[M]) Len() int {
+	return len(s)
+}
+
+// All returns an iterator over the values in the set, just as ranging
+// over it does.
+func (s Set[M]) All() iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for val := range s {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
 // Contains returns true if the set contains the given value.
 func (s Set[M]) Contains(v M) bool {
 	if s == nil {
@@ -169,3 +169,22 @@ func TestSetFromIterator(t *testing.T) {
 		t.Fatal("unexpected set from iter construction")
 	}
 }
+
+func TestSetLenAll(t *testing.T) {
+	s := SetFromSlice([]int{1, 2, 3})
+	if s.Len() != 3 {
+		t.Fatal("Len is wrong")
+	}
+
+	total := 0
+	for val := range s.All() {
+		total += val
+	}
+	if total != 6 {
+		t.Fatal("All didn't yield all the values")
+	}
+
+	for _ = range s.All() {
+		break
+	}
+}
@@ -0,0 +1,129 @@
+package cm
+
+import "iter"
+
+// The functions in this file combine sets lazily, streaming the result
+// as an iterator rather than building a new Set. Where one of the inputs
+// only needs to be iterated over, it is accepted as an iter.Seq, so it
+// can come from anywhere, including another of these functions; where
+// an input needs to be checked for membership, it must be a Set.
+//
+// To materialize a result, pass it to SetFromIter.
+//
+// None of these functions remove duplicates from an iter.Seq input, as
+// that would require building a set of the values seen so far. If the
+// sequence comes from a Set, or from one of these functions applied to
+// Sets, it will not contain duplicates, and neither will the result.
+
+// UnionSeq returns an iterator over the values in the set, followed by
+// the values in the sequence that are not in the set.
+func UnionSeq[M comparable](s Set[M], seq iter.Seq[M]) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for val := range s {
+			if !yield(val) {
+				return
+			}
+		}
+		for val := range seq {
+			if !s.Contains(val) && !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// IntersectSeq returns an iterator over the values in the sequence that
+// are in all of the passed-in sets. With no sets, this yields the whole
+// sequence.
+func IntersectSeq[M comparable](seq iter.Seq[M], sets ...Set[M]) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for val := range seq {
+			if inAll(val, sets) && !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// DifferenceSeq returns an iterator over the values in the sequence that
+// are in none of the passed-in sets.
+func DifferenceSeq[M comparable](seq iter.Seq[M], sets ...Set[M]) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for val := range seq {
+			if !inAny(val, sets) && !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// SymmetricDifferenceSeq returns an iterator over the values that are in
+// exactly one of the two sets. This is the streaming equivalent of XOR.
+func SymmetricDifferenceSeq[M comparable](l, r Set[M]) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for val := range l {
+			if !r.Contains(val) && !yield(val) {
+				return
+			}
+		}
+		for val := range r {
+			if !l.Contains(val) && !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// UnionAll returns an iterator over the values in any of the sets. Each
+// value is yielded once.
+func UnionAll[M comparable](sets ...Set[M]) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		for i, s := range sets {
+			for val := range s {
+				if !inAny(val, sets[:i]) && !yield(val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// IntersectAll returns an iterator over the values in all of the sets.
+// It iterates over the smallest set, checking each value against the
+// others. With no sets, it yields nothing.
+func IntersectAll[M comparable](sets ...Set[M]) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		if len(sets) == 0 {
+			return
+		}
+		smallest := 0
+		for i, s := range sets {
+			if len(s) < len(sets[smallest]) {
+				smallest = i
+			}
+		}
+		for val := range sets[smallest] {
+			if inAll(val, sets) && !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+func inAll[M comparable](val M, sets []Set[M]) bool {
+	for _, s := range sets {
+		if !s.Contains(val) {
+			return false
+		}
+	}
+	return true
+}
+
+func inAny[M comparable](val M, sets []Set[M]) bool {
+	for _, s := range sets {
+		if s.Contains(val) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package cm
+
+import (
+	"iter"
+	"slices"
+	"testing"
+)
+
+func TestSetSeq(t *testing.T) {
+	a := SetFromSlice([]int{1, 2, 3, 4})
+	b := SetFromSlice([]int{3, 4, 5})
+	c := SetFromSlice([]int{4, 5, 6})
+
+	for _, test := range []struct {
+		name   string
+		result Set[int]
+		expect []int
+	}{
+		{"UnionSeq", SetFromIter(UnionSeq(a, slices.Values([]int{4, 5, 6}))),
+			[]int{1, 2, 3, 4, 5, 6}},
+		{"IntersectSeq", SetFromIter(IntersectSeq(slices.Values([]int{1, 3, 4, 9}), a, b)),
+			[]int{3, 4}},
+		{"IntersectSeq no sets", SetFromIter(IntersectSeq(slices.Values([]int{1, 2}))),
+			[]int{1, 2}},
+		{"DifferenceSeq", SetFromIter(DifferenceSeq(slices.Values([]int{1, 3, 5, 7}), b, c)),
+			[]int{1, 7}},
+		{"SymmetricDifferenceSeq", SetFromIter(SymmetricDifferenceSeq(a, b)),
+			[]int{1, 2, 5}},
+		{"UnionAll", SetFromIter(UnionAll(a, b, c)), []int{1, 2, 3, 4, 5, 6}},
+		{"IntersectAll", SetFromIter(IntersectAll(a, b, c)), []int{4}},
+		{"IntersectAll none", SetFromIter(IntersectAll[int]()), nil},
+		{"composed", SetFromIter(DifferenceSeq(UnionAll(a, c), b)), []int{1, 2, 6}},
+	} {
+		if !test.result.Equal(SetFromSlice(test.expect)) {
+			t.Fatalf("incorrect %s: %v", test.name, test.result)
+		}
+	}
+
+	// the all-sets functions yield each value once
+	if len(slices.Collect(UnionAll(a, b, c))) != 6 ||
+		len(slices.Collect(SymmetricDifferenceSeq(a, b))) != 3 {
+		t.Fatal("yielded duplicates")
+	}
+
+	// IntersectAll iterates over the smallest set, here the empty one.
+	if len(slices.Collect(IntersectAll(a, Set[int]{}, b))) != 0 {
+		t.Fatal("incorrect IntersectAll with an empty set")
+	}
+
+	for _, seq := range []iter.Seq[int]{
+		UnionSeq(a, slices.Values([]int{9})),
+		UnionSeq(nil, slices.Values([]int{9})),
+		IntersectSeq(slices.Values([]int{1}), a),
+		DifferenceSeq(slices.Values([]int{1}), b),
+		SymmetricDifferenceSeq(a, b),
+		SymmetricDifferenceSeq(nil, b),
+		UnionAll(a, b),
+		IntersectAll(a, b),
+	} {
+		for range seq {
+			break
+		}
+	}
+}
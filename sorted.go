@@ -0,0 +1,529 @@
+package cm
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// This file contains the sorted variants of the iteration methods. Go
+// maps iterate in hash order, which is fine for most uses but painful for
+// golden tests, logs, and anything else meant to be diffed.
+//
+// The methods all take a comparison function for each key level, in the
+// style of slices.SortFunc. Since a method can not add constraints to the
+// type's parameters, the cmp.Ordered conveniences are functions instead,
+// named after the type they work on, like MapMapSortedAll.
+//
+// Each level of keys is sorted only as the iteration reaches it, so
+// breaking out of an iteration early does not pay for sorting the whole
+// map. Of course, the top level always needs to be fully sorted.
+
+// sortedKeys returns the keys of the given map, sorted by the given
+// function.
+func sortedKeys[K comparable, V any](m map[K]V, c func(K, K) int) []K {
+	keys := make([]K, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, c)
+	return keys
+}
+
+// SortedKeysFunc returns an iterator on the keys as a Tuple2, in
+// lexicographic order as determined by the given comparison functions.
+func (mma MapMapAny[K1, K2, V]) SortedKeysFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) iter.Seq[Tuple2[K1, K2]] {
+	return func(yield func(Tuple2[K1, K2]) bool) {
+		for key := range mma.SortedAllFunc(cmp1, cmp2) {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// SortedAllFunc returns an iterator over the map that yields the keys as
+// a Tuple2 and the value in the value slot, in lexicographic key order as
+// determined by the given comparison functions.
+func (mma MapMapAny[K1, K2, V]) SortedAllFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) iter.Seq2[Tuple2[K1, K2], V] {
+	return func(yield func(Tuple2[K1, K2], V) bool) {
+		for _, key1 := range sortedKeys(mma, cmp1) {
+			m1 := mma[key1]
+			for _, key2 := range sortedKeys(m1, cmp2) {
+				if !yield(Tuple2[K1, K2]{key1, key2}, m1[key2]) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SortedKeySliceFunc returns the keys of the MapMap as a slice of Tuple2
+// values, in lexicographic order as determined by the given comparison
+// functions.
+//
+// A nil map will return a nil slice.
+func (mma MapMapAny[K1, K2, V]) SortedKeySliceFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) []Tuple2[K1, K2] {
+	if mma == nil {
+		return nil
+	}
+
+	r := make([]Tuple2[K1, K2], 0, mma.Len())
+	for key := range mma.SortedKeysFunc(cmp1, cmp2) {
+		r = append(r, key)
+	}
+	return r
+}
+
+// SortedKeyTreeFunc returns the keys of the MapMap as a 2-level tree of
+// the various keys, with each level sorted by the given comparison
+// functions.
+//
+// A nil map will return a nil slice.
+func (mma MapMapAny[K1, K2, V]) SortedKeyTreeFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) []KeyTree[K1, K2] {
+	if mma == nil {
+		return nil
+	}
+
+	r := make([]KeyTree[K1, K2], 0, len(mma))
+	for _, key1 := range sortedKeys(mma, cmp1) {
+		r = append(r, KeyTree[K1, K2]{key1, sortedKeys(mma[key1], cmp2)})
+	}
+	return r
+}
+
+// SortedKeysFunc returns an iterator on the keys as a Tuple2, in
+// lexicographic order as determined by the given comparison functions.
+func (mm MapMap[K1, K2, V]) SortedKeysFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) iter.Seq[Tuple2[K1, K2]] {
+	return MapMapAny[K1, K2, V](mm).SortedKeysFunc(cmp1, cmp2)
+}
+
+// SortedAllFunc returns an iterator over the map that yields the keys as
+// a Tuple2 and the value in the value slot, in lexicographic key order as
+// determined by the given comparison functions.
+func (mm MapMap[K1, K2, V]) SortedAllFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) iter.Seq2[Tuple2[K1, K2], V] {
+	return MapMapAny[K1, K2, V](mm).SortedAllFunc(cmp1, cmp2)
+}
+
+// SortedKeySliceFunc returns the keys of the MapMap as a slice of Tuple2
+// values, in lexicographic order as determined by the given comparison
+// functions.
+//
+// A nil map will return a nil slice.
+func (mm MapMap[K1, K2, V]) SortedKeySliceFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) []Tuple2[K1, K2] {
+	return MapMapAny[K1, K2, V](mm).SortedKeySliceFunc(cmp1, cmp2)
+}
+
+// SortedKeyTreeFunc returns the keys of the MapMap as a 2-level tree of
+// the various keys, with each level sorted by the given comparison
+// functions.
+//
+// A nil map will return a nil slice.
+func (mm MapMap[K1, K2, V]) SortedKeyTreeFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) []KeyTree[K1, K2] {
+	return MapMapAny[K1, K2, V](mm).SortedKeyTreeFunc(cmp1, cmp2)
+}
+
+// MapMapSortedKeys returns an iterator on the keys of the given MapMap
+// or MapMapAny in sorted order.
+func MapMapSortedKeys[M ~map[K1]map[K2]V, K1, K2 cmp.Ordered, V any](
+	mm M,
+) iter.Seq[Tuple2[K1, K2]] {
+	return MapMapAny[K1, K2, V](mm).SortedKeysFunc(cmp.Compare[K1], cmp.Compare[K2])
+}
+
+// MapMapSortedAll returns an iterator over the keys and values of the
+// given MapMap or MapMapAny in sorted key order.
+func MapMapSortedAll[M ~map[K1]map[K2]V, K1, K2 cmp.Ordered, V any](
+	mm M,
+) iter.Seq2[Tuple2[K1, K2], V] {
+	return MapMapAny[K1, K2, V](mm).SortedAllFunc(cmp.Compare[K1], cmp.Compare[K2])
+}
+
+// MapMapSortedKeySlice returns the keys of the given MapMap or MapMapAny
+// as a sorted slice.
+//
+// A nil map will return a nil slice.
+func MapMapSortedKeySlice[M ~map[K1]map[K2]V, K1, K2 cmp.Ordered, V any](
+	mm M,
+) []Tuple2[K1, K2] {
+	return MapMapAny[K1, K2, V](mm).SortedKeySliceFunc(cmp.Compare[K1], cmp.Compare[K2])
+}
+
+// MapMapSortedKeyTree returns the keys of the given MapMap or MapMapAny
+// as a KeyTree, with each level sorted.
+//
+// A nil map will return a nil slice.
+func MapMapSortedKeyTree[M ~map[K1]map[K2]V, K1, K2 cmp.Ordered, V any](
+	mm M,
+) []KeyTree[K1, K2] {
+	return MapMapAny[K1, K2, V](mm).SortedKeyTreeFunc(cmp.Compare[K1], cmp.Compare[K2])
+}
+
+// SortedKeysFunc returns an iterator on the keys as a Tuple3, in
+// lexicographic order as determined by the given comparison functions.
+func (mmma MapMapMapAny[K1, K2, K3, V]) SortedKeysFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) iter.Seq[Tuple3[K1, K2, K3]] {
+	return func(yield func(Tuple3[K1, K2, K3]) bool) {
+		for key := range mmma.SortedAllFunc(cmp1, cmp2, cmp3) {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// SortedAllFunc returns an iterator over the map that yields the keys as
+// a Tuple3 and the value in the value slot, in lexicographic key order as
+// determined by the given comparison functions.
+func (mmma MapMapMapAny[K1, K2, K3, V]) SortedAllFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return func(yield func(Tuple3[K1, K2, K3], V) bool) {
+		for _, key1 := range sortedKeys(mmma, cmp1) {
+			for key, val := range mmma[key1].SortedAllFunc(cmp2, cmp3) {
+				if !yield(Tuple3[K1, K2, K3]{key1, key.Key1, key.Key2}, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SortedKeySliceFunc returns the keys of the MapMapMap as a slice of
+// Tuple3 values, in lexicographic order as determined by the given
+// comparison functions.
+//
+// A nil map will return a nil slice.
+func (mmma MapMapMapAny[K1, K2, K3, V]) SortedKeySliceFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) []Tuple3[K1, K2, K3] {
+	if mmma == nil {
+		return nil
+	}
+
+	r := make([]Tuple3[K1, K2, K3], 0, mmma.Len())
+	for key := range mmma.SortedKeysFunc(cmp1, cmp2, cmp3) {
+		r = append(r, key)
+	}
+	return r
+}
+
+// SortedKeyTreeFunc returns the keys of the MapMapMap as a 3-level tree
+// of the various keys, with each level sorted by the given comparison
+// functions.
+//
+// A nil map will return a nil slice.
+func (mmma MapMapMapAny[K1, K2, K3, V]) SortedKeyTreeFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) []KeyTree[K1, KeyTree[K2, K3]] {
+	if mmma == nil {
+		return nil
+	}
+
+	r := make([]KeyTree[K1, KeyTree[K2, K3]], 0, len(mmma))
+	for _, key1 := range sortedKeys(mmma, cmp1) {
+		keyTree := mmma[key1].SortedKeyTreeFunc(cmp2, cmp3)
+		r = append(r, KeyTree[K1, KeyTree[K2, K3]]{key1, keyTree})
+	}
+	return r
+}
+
+// SortedKeysFunc returns an iterator on the keys as a Tuple3, in
+// lexicographic order as determined by the given comparison functions.
+func (mmm MapMapMap[K1, K2, K3, V]) SortedKeysFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) iter.Seq[Tuple3[K1, K2, K3]] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedKeysFunc(cmp1, cmp2, cmp3)
+}
+
+// SortedAllFunc returns an iterator over the map that yields the keys as
+// a Tuple3 and the value in the value slot, in lexicographic key order as
+// determined by the given comparison functions.
+func (mmm MapMapMap[K1, K2, K3, V]) SortedAllFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedAllFunc(cmp1, cmp2, cmp3)
+}
+
+// SortedKeySliceFunc returns the keys of the MapMapMap as a slice of
+// Tuple3 values, in lexicographic order as determined by the given
+// comparison functions.
+//
+// A nil map will return a nil slice.
+func (mmm MapMapMap[K1, K2, K3, V]) SortedKeySliceFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) []Tuple3[K1, K2, K3] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedKeySliceFunc(cmp1, cmp2, cmp3)
+}
+
+// SortedKeyTreeFunc returns the keys of the MapMapMap as a 3-level tree
+// of the various keys, with each level sorted by the given comparison
+// functions.
+//
+// A nil map will return a nil slice.
+func (mmm MapMapMap[K1, K2, K3, V]) SortedKeyTreeFunc(
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+	cmp3 func(K3, K3) int,
+) []KeyTree[K1, KeyTree[K2, K3]] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedKeyTreeFunc(cmp1, cmp2, cmp3)
+}
+
+// MapMapMapSortedKeys returns an iterator on the keys of the given
+// MapMapMap or MapMapMapAny in sorted order.
+func MapMapMapSortedKeys[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 cmp.Ordered, V any](
+	mmm M,
+) iter.Seq[Tuple3[K1, K2, K3]] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedKeysFunc(
+		cmp.Compare[K1], cmp.Compare[K2], cmp.Compare[K3])
+}
+
+// MapMapMapSortedAll returns an iterator over the keys and values of the
+// given MapMapMap or MapMapMapAny in sorted key order.
+func MapMapMapSortedAll[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 cmp.Ordered, V any](
+	mmm M,
+) iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedAllFunc(
+		cmp.Compare[K1], cmp.Compare[K2], cmp.Compare[K3])
+}
+
+// MapMapMapSortedKeySlice returns the keys of the given MapMapMap or
+// MapMapMapAny as a sorted slice.
+//
+// A nil map will return a nil slice.
+func MapMapMapSortedKeySlice[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 cmp.Ordered, V any](
+	mmm M,
+) []Tuple3[K1, K2, K3] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedKeySliceFunc(
+		cmp.Compare[K1], cmp.Compare[K2], cmp.Compare[K3])
+}
+
+// MapMapMapSortedKeyTree returns the keys of the given MapMapMap or
+// MapMapMapAny as a KeyTree, with each level sorted.
+//
+// A nil map will return a nil slice.
+func MapMapMapSortedKeyTree[M ~map[K1]MapMapAny[K2, K3, V], K1, K2, K3 cmp.Ordered, V any](
+	mmm M,
+) []KeyTree[K1, KeyTree[K2, K3]] {
+	return MapMapMapAny[K1, K2, K3, V](mmm).SortedKeyTreeFunc(
+		cmp.Compare[K1], cmp.Compare[K2], cmp.Compare[K3])
+}
+
+// SortedKeysFunc returns an iterator on the key and value pairs of the
+// MapSet as a Tuple2, in lexicographic order as determined by the given
+// comparison functions.
+func (ms MapSet[K, V]) SortedKeysFunc(
+	cmpK func(K, K) int,
+	cmpV func(V, V) int,
+) iter.Seq[Tuple2[K, V]] {
+	return func(yield func(Tuple2[K, V]) bool) {
+		for key, val := range ms.SortedAllFunc(cmpK, cmpV) {
+			if !yield(Tuple2[K, V]{key, val}) {
+				return
+			}
+		}
+	}
+}
+
+// SortedAllFunc returns an iterator that yields every key and value pair
+// in the MapSet, in lexicographic order as determined by the given
+// comparison functions.
+func (ms MapSet[K, V]) SortedAllFunc(
+	cmpK func(K, K) int,
+	cmpV func(V, V) int,
+) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, key := range sortedKeys(ms, cmpK) {
+			for _, val := range sortedKeys(ms[key], cmpV) {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SortedKeyTreeFunc returns the contents of the MapSet as a KeyTree, with
+// the keys and the values in each set sorted by the given comparison
+// functions.
+//
+// A nil MapSet will return a nil slice.
+func (ms MapSet[K, V]) SortedKeyTreeFunc(
+	cmpK func(K, K) int,
+	cmpV func(V, V) int,
+) []KeyTree[K, V] {
+	if ms == nil {
+		return nil
+	}
+
+	r := make([]KeyTree[K, V], 0, len(ms))
+	for _, key := range sortedKeys(ms, cmpK) {
+		r = append(r, KeyTree[K, V]{key, sortedKeys(ms[key], cmpV)})
+	}
+	return r
+}
+
+// SortedKeySliceFunc returns the key and value pairs of the MapSet as a
+// slice of Tuple2 values, in lexicographic order as determined by the
+// given comparison functions.
+//
+// A nil MapSet will return a nil slice.
+func (ms MapSet[K, V]) SortedKeySliceFunc(
+	cmpK func(K, K) int,
+	cmpV func(V, V) int,
+) []Tuple2[K, V] {
+	if ms == nil {
+		return nil
+	}
+
+	r := make([]Tuple2[K, V], 0, len(ms))
+	for pair := range ms.SortedKeysFunc(cmpK, cmpV) {
+		r = append(r, pair)
+	}
+	return r
+}
+
+// MapSetSortedKeys returns an iterator on the key and value pairs of the
+// given MapSet as a Tuple2, in sorted order.
+func MapSetSortedKeys[K, V cmp.Ordered](ms MapSet[K, V]) iter.Seq[Tuple2[K, V]] {
+	return ms.SortedKeysFunc(cmp.Compare[K], cmp.Compare[V])
+}
+
+// MapSetSortedAll returns an iterator over every key and value pair in
+// the given MapSet in sorted order.
+func MapSetSortedAll[K, V cmp.Ordered](ms MapSet[K, V]) iter.Seq2[K, V] {
+	return ms.SortedAllFunc(cmp.Compare[K], cmp.Compare[V])
+}
+
+// MapSetSortedKeySlice returns the key and value pairs of the given
+// MapSet as a slice of Tuple2 values, in sorted order.
+//
+// A nil MapSet will return a nil slice.
+func MapSetSortedKeySlice[K, V cmp.Ordered](ms MapSet[K, V]) []Tuple2[K, V] {
+	return ms.SortedKeySliceFunc(cmp.Compare[K], cmp.Compare[V])
+}
+
+// MapSetSortedKeyTree returns the contents of the given MapSet as a
+// KeyTree, with the keys and the values of each set sorted.
+//
+// A nil MapSet will return a nil slice.
+func MapSetSortedKeyTree[K, V cmp.Ordered](ms MapSet[K, V]) []KeyTree[K, V] {
+	return ms.SortedKeyTreeFunc(cmp.Compare[K], cmp.Compare[V])
+}
+
+// SortedKeysFunc returns an iterator on the keys of the Primary map as a
+// Tuple2, in lexicographic order as determined by the given comparison
+// functions.
+func (dm *DualMap[P, S, V]) SortedKeysFunc(
+	cmpP func(P, P) int,
+	cmpS func(S, S) int,
+) iter.Seq[Tuple2[P, S]] {
+	return dm.Primary.SortedKeysFunc(cmpP, cmpS)
+}
+
+// SortedAllFunc returns an iterator over the Primary map that yields the
+// keys as a Tuple2 and the value in the value slot, in lexicographic key
+// order as determined by the given comparison functions.
+//
+// To iterate in order of the secondary key, use the Reverse map's
+// SortedAllFunc.
+func (dm *DualMap[P, S, V]) SortedAllFunc(
+	cmpP func(P, P) int,
+	cmpS func(S, S) int,
+) iter.Seq2[Tuple2[P, S], V] {
+	return dm.Primary.SortedAllFunc(cmpP, cmpS)
+}
+
+// SortedKeySliceFunc returns the keys of the Primary map as a slice of
+// Tuple2 values, in lexicographic order as determined by the given
+// comparison functions.
+//
+// A DualMap with nothing set will return a nil slice.
+func (dm *DualMap[P, S, V]) SortedKeySliceFunc(
+	cmpP func(P, P) int,
+	cmpS func(S, S) int,
+) []Tuple2[P, S] {
+	return dm.Primary.SortedKeySliceFunc(cmpP, cmpS)
+}
+
+// SortedKeyTreeFunc returns the keys of the Primary map as a KeyTree,
+// with each level sorted by the given comparison functions.
+//
+// A DualMap with nothing set will return a nil slice.
+func (dm *DualMap[P, S, V]) SortedKeyTreeFunc(
+	cmpP func(P, P) int,
+	cmpS func(S, S) int,
+) []KeyTree[P, S] {
+	return dm.Primary.SortedKeyTreeFunc(cmpP, cmpS)
+}
+
+// DualMapSortedKeys returns an iterator on the keys of the given
+// DualMap's Primary map as a Tuple2, in sorted order.
+func DualMapSortedKeys[P, S cmp.Ordered, V any](
+	dm *DualMap[P, S, V],
+) iter.Seq[Tuple2[P, S]] {
+	return dm.SortedKeysFunc(cmp.Compare[P], cmp.Compare[S])
+}
+
+// DualMapSortedAll returns an iterator over the keys and values of the
+// given DualMap in sorted primary/secondary key order.
+func DualMapSortedAll[P, S cmp.Ordered, V any](
+	dm *DualMap[P, S, V],
+) iter.Seq2[Tuple2[P, S], V] {
+	return dm.SortedAllFunc(cmp.Compare[P], cmp.Compare[S])
+}
+
+// DualMapSortedKeySlice returns the keys of the given DualMap's Primary
+// map as a slice of Tuple2 values, in sorted order.
+func DualMapSortedKeySlice[P, S cmp.Ordered, V any](
+	dm *DualMap[P, S, V],
+) []Tuple2[P, S] {
+	return dm.SortedKeySliceFunc(cmp.Compare[P], cmp.Compare[S])
+}
+
+// DualMapSortedKeyTree returns the keys of the given DualMap's Primary
+// map as a KeyTree, with each level sorted.
+func DualMapSortedKeyTree[P, S cmp.Ordered, V any](
+	dm *DualMap[P, S, V],
+) []KeyTree[P, S] {
+	return dm.SortedKeyTreeFunc(cmp.Compare[P], cmp.Compare[S])
+}
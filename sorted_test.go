@@ -0,0 +1,220 @@
+package cm
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMapMapSorted(t *testing.T) {
+	mm := MapMap[int, string, int]{}
+	mm.Set(3, "b", 1)
+	mm.Set(3, "a", 2)
+	mm.Set(1, "z", 3)
+	mm.Set(2, "c", 4)
+	mm.Set(2, "a", 5)
+
+	expectedKeys := []Tuple2[int, string]{
+		{1, "z"}, {2, "a"}, {2, "c"}, {3, "a"}, {3, "b"},
+	}
+
+	if !reflect.DeepEqual(MapMapSortedKeySlice(mm), expectedKeys) {
+		t.Fatal("incorrect sorted key slice")
+	}
+	if !reflect.DeepEqual(slices.Collect(MapMapSortedKeys(mm)), expectedKeys) {
+		t.Fatal("incorrect sorted keys")
+	}
+
+	values := []int{}
+	for _, val := range MapMapSortedAll(MapMapAny[int, string, int](mm)) {
+		values = append(values, val)
+	}
+	if !reflect.DeepEqual(values, []int{3, 5, 4, 2, 1}) {
+		t.Fatal("incorrect sorted All")
+	}
+
+	if !reflect.DeepEqual(MapMapSortedKeyTree(mm), []KeyTree[int, string]{
+		{1, []string{"z"}},
+		{2, []string{"a", "c"}},
+		{3, []string{"a", "b"}},
+	}) {
+		t.Fatal("incorrect sorted key tree")
+	}
+
+	// And backwards, via the Func variants.
+	rev := func(a, b int) int { return cmp.Compare(b, a) }
+	revStr := func(a, b string) int { return strings.Compare(b, a) }
+	reversed := slices.Clone(expectedKeys)
+	slices.Reverse(reversed)
+	if !reflect.DeepEqual(mm.SortedKeySliceFunc(rev, revStr), reversed) {
+		t.Fatal("incorrect reversed sorted key slice")
+	}
+	if !reflect.DeepEqual(
+		slices.Collect(mm.SortedKeysFunc(rev, revStr)), reversed) {
+		t.Fatal("incorrect reversed sorted keys")
+	}
+	count := 0
+	for range mm.SortedAllFunc(rev, revStr) {
+		count++
+	}
+	if count != 5 {
+		t.Fatal("incorrect reversed sorted All")
+	}
+	if mm.SortedKeyTreeFunc(rev, revStr)[0].Key != 3 {
+		t.Fatal("incorrect reversed key tree")
+	}
+
+	for range MapMapSortedKeys(mm) {
+		break
+	}
+	for range MapMapSortedAll(mm) {
+		break
+	}
+
+	var nilMM MapMap[int, int, int]
+	if MapMapSortedKeySlice(nilMM) != nil || MapMapSortedKeyTree(nilMM) != nil {
+		t.Fatal("nil map doesn't return nil slices")
+	}
+}
+
+func TestMapMapMapSorted(t *testing.T) {
+	mmm := MapMapMap[int, int, int, int]{}
+	mmm.Set(2, 1, 1, 1)
+	mmm.Set(1, 2, 2, 2)
+	mmm.Set(1, 2, 1, 3)
+	mmm.Set(1, 1, 9, 4)
+
+	expectedKeys := []Tuple3[int, int, int]{
+		{1, 1, 9}, {1, 2, 1}, {1, 2, 2}, {2, 1, 1},
+	}
+	if !reflect.DeepEqual(MapMapMapSortedKeySlice(mmm), expectedKeys) {
+		t.Fatal("incorrect sorted key slice")
+	}
+	if !reflect.DeepEqual(slices.Collect(MapMapMapSortedKeys(mmm)), expectedKeys) {
+		t.Fatal("incorrect sorted keys")
+	}
+	values := []int{}
+	for _, val := range MapMapMapSortedAll(mmm) {
+		values = append(values, val)
+	}
+	if !reflect.DeepEqual(values, []int{4, 3, 2, 1}) {
+		t.Fatal("incorrect sorted All")
+	}
+
+	if !reflect.DeepEqual(MapMapMapSortedKeyTree(mmm),
+		[]KeyTree[int, KeyTree[int, int]]{
+			{1, []KeyTree[int, int]{{1, []int{9}}, {2, []int{1, 2}}}},
+			{2, []KeyTree[int, int]{{1, []int{1}}}},
+		}) {
+		t.Fatal("incorrect sorted key tree")
+	}
+
+	c := cmp.Compare[int]
+	if len(mmm.SortedKeySliceFunc(c, c, c)) != 4 ||
+		len(mmm.SortedKeyTreeFunc(c, c, c)) != 2 {
+		t.Fatal("incorrect sorted Func methods")
+	}
+	for range mmm.SortedKeysFunc(c, c, c) {
+		break
+	}
+	for range mmm.SortedAllFunc(c, c, c) {
+		break
+	}
+
+	var nilMMM MapMapMap[int, int, int, int]
+	if MapMapMapSortedKeySlice(nilMMM) != nil ||
+		MapMapMapSortedKeyTree(nilMMM) != nil {
+		t.Fatal("nil map doesn't return nil slices")
+	}
+}
+
+func TestMapSetSorted(t *testing.T) {
+	ms := MapSet[string, int]{}
+	ms.Add("b", 2)
+	ms.Add("b", 1)
+	ms.Add("a", 3)
+
+	keys := []string{}
+	values := []int{}
+	for key, val := range MapSetSortedAll(ms) {
+		keys = append(keys, key)
+		values = append(values, val)
+	}
+	if !reflect.DeepEqual(keys, []string{"a", "b", "b"}) ||
+		!reflect.DeepEqual(values, []int{3, 1, 2}) {
+		t.Fatal("incorrect sorted MapSet iteration")
+	}
+	for range MapSetSortedAll(ms) {
+		break
+	}
+
+	if !reflect.DeepEqual(MapSetSortedKeyTree(ms), []KeyTree[string, int]{
+		{"a", []int{3}},
+		{"b", []int{1, 2}},
+	}) {
+		t.Fatal("incorrect sorted MapSet key tree")
+	}
+
+	if !reflect.DeepEqual(MapSetSortedKeySlice(ms), []Tuple2[string, int]{
+		{"a", 3}, {"b", 1}, {"b", 2},
+	}) {
+		t.Fatal("incorrect sorted MapSet key slice")
+	}
+	for pair := range MapSetSortedKeys(ms) {
+		if pair != (Tuple2[string, int]{"a", 3}) {
+			t.Fatal("incorrect first sorted MapSet key")
+		}
+		break
+	}
+
+	var nilMS MapSet[string, int]
+	if MapSetSortedKeyTree(nilMS) != nil {
+		t.Fatal("nil MapSet doesn't return a nil key tree")
+	}
+	if MapSetSortedKeySlice(nilMS) != nil {
+		t.Fatal("nil MapSet doesn't return a nil key slice")
+	}
+}
+
+func TestDualMapSorted(t *testing.T) {
+	dm := &DualMap[int, string, int]{}
+	dm.Set(2, "a", 1)
+	dm.Set(1, "b", 2)
+	dm.Set(1, "a", 3)
+
+	keys := []Tuple2[int, string]{}
+	for key := range DualMapSortedAll(dm) {
+		keys = append(keys, key)
+	}
+	if !reflect.DeepEqual(keys, []Tuple2[int, string]{
+		{1, "a"}, {1, "b"}, {2, "a"},
+	}) {
+		t.Fatal("incorrect sorted DualMap iteration")
+	}
+	if !reflect.DeepEqual(slices.Collect(DualMapSortedKeys(dm)), keys) {
+		t.Fatal("incorrect sorted DualMap keys")
+	}
+	if !reflect.DeepEqual(DualMapSortedKeySlice(dm), keys) {
+		t.Fatal("incorrect sorted DualMap key slice")
+	}
+	empty := &DualMap[int, string, int]{}
+	if empty.SortedKeySliceFunc(cmp.Compare[int], cmp.Compare[string]) != nil {
+		t.Fatal("empty DualMap doesn't return a nil key slice")
+	}
+
+	if !reflect.DeepEqual(DualMapSortedKeyTree(dm), []KeyTree[int, string]{
+		{1, []string{"a", "b"}},
+		{2, []string{"a"}},
+	}) {
+		t.Fatal("incorrect sorted DualMap key tree")
+	}
+
+	reverse := MapMapSortedKeySlice(dm.Reverse)
+	if !reflect.DeepEqual(reverse, []Tuple2[string, int]{
+		{"a", 1}, {"a", 2}, {"b", 1},
+	}) {
+		t.Fatal("incorrect sorted DualMap reverse iteration")
+	}
+}
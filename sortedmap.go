@@ -0,0 +1,476 @@
+package cm
+
+import (
+	"cmp"
+	"iter"
+)
+
+// A SortedMap is a map that keeps its keys in order, as determined by a
+// comparison function in the style of slices.SortFunc. It is the
+// submap type of SortedMapMap, and is also useful on its own.
+//
+// As with SortedSet, it is backed by a B-tree, must be created with one
+// of its constructors, and must not be modified while it is being
+// iterated over. A nil *SortedMap functions as an empty map, except that
+// it can not be Set.
+type SortedMap[K comparable, V any] struct {
+	t *btree[K, V]
+}
+
+// NewSortedMap returns a new, empty SortedMap in the natural order of
+// its keys.
+func NewSortedMap[K cmp.Ordered, V any]() *SortedMap[K, V] {
+	return NewSortedMapFunc[K, V](cmp.Compare[K])
+}
+
+// NewSortedMapFunc returns a new, empty SortedMap ordered by the given
+// comparison function.
+func NewSortedMapFunc[K comparable, V any](cmp func(K, K) int) *SortedMap[K, V] {
+	return &SortedMap[K, V]{newBtree[K, V](cmp)}
+}
+
+// tree returns the map's B-tree, or an empty one for a nil map.
+func (sm *SortedMap[K, V]) tree() *btree[K, V] {
+	if sm == nil {
+		return &btree[K, V]{}
+	}
+	return sm.t
+}
+
+// Set sets the value for the given key.
+//
+// This will panic if called on a nil map.
+func (sm *SortedMap[K, V]) Set(key K, val V) {
+	if sm == nil {
+		panic("Set called on a nil SortedMap")
+	}
+	sm.t.set(key, val)
+}
+
+// Get returns the value for the given key. The second value is true if
+// the key exists, false otherwise.
+func (sm *SortedMap[K, V]) Get(key K) (V, bool) {
+	return sm.tree().get(key)
+}
+
+// Delete deletes the value for the given key.
+func (sm *SortedMap[K, V]) Delete(key K) {
+	if sm != nil {
+		sm.t.delete(key)
+	}
+}
+
+// DeleteFunc deletes from the map the values for which the function
+// returns true.
+func (sm *SortedMap[K, V]) DeleteFunc(f func(K, V) bool) {
+	var keys []K
+	for key, val := range sm.All() {
+		if f(key, val) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		sm.t.delete(key)
+	}
+}
+
+// Len returns the number of values in the map.
+func (sm *SortedMap[K, V]) Len() int {
+	return sm.tree().size
+}
+
+// Clone returns a shallow copy of the map.
+func (sm *SortedMap[K, V]) Clone() *SortedMap[K, V] {
+	if sm == nil {
+		return nil
+	}
+	return &SortedMap[K, V]{sm.t.clone(nil)}
+}
+
+// EqualFunc returns true if the two maps have the same keys, and the
+// function returns true for the values of each key.
+func (sm *SortedMap[K, V]) EqualFunc(r *SortedMap[K, V], eq func(V, V) bool) bool {
+	if sm.Len() != r.Len() {
+		return false
+	}
+	for key, val := range sm.All() {
+		rVal, exists := r.Get(key)
+		if !exists || !eq(val, rVal) {
+			return false
+		}
+	}
+	return true
+}
+
+// All returns an iterator over the keys and values of the map, in
+// ascending key order.
+func (sm *SortedMap[K, V]) All() iter.Seq2[K, V] {
+	return sm.tree().all
+}
+
+// Backward returns an iterator over the keys and values of the map, in
+// descending key order.
+func (sm *SortedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sm.tree().descend(yield)
+	}
+}
+
+// Range returns an iterator over the keys and values of the map whose
+// keys are greater than or equal to lo and less than hi, in ascending
+// key order.
+func (sm *SortedMap[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		sm.tree().ascend(&lo, &hi, yield)
+	}
+}
+
+// Keys returns an iterator over the keys of the map, in ascending order.
+func (sm *SortedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		sm.tree().ascend(nil, nil, func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// Values returns an iterator over the values of the map, in ascending
+// key order.
+func (sm *SortedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		sm.tree().ascend(nil, nil, func(_ K, val V) bool {
+			return yield(val)
+		})
+	}
+}
+
+// Floor returns the largest key in the map that is less than or equal to
+// the given key, and its value. The last value is false if there is
+// none.
+func (sm *SortedMap[K, V]) Floor(key K) (K, V, bool) {
+	item, exists := sm.tree().floor(key)
+	return item.key, item.val, exists
+}
+
+// Ceiling returns the smallest key in the map that is greater than or
+// equal to the given key, and its value. The last value is false if
+// there is none.
+func (sm *SortedMap[K, V]) Ceiling(key K) (K, V, bool) {
+	item, exists := sm.tree().ceiling(key)
+	return item.key, item.val, exists
+}
+
+// Min returns the smallest key in the map and its value. The last value
+// is false if the map is empty.
+func (sm *SortedMap[K, V]) Min() (K, V, bool) {
+	item, exists := sm.tree().first()
+	return item.key, item.val, exists
+}
+
+// Max returns the largest key in the map and its value. The last value
+// is false if the map is empty.
+func (sm *SortedMap[K, V]) Max() (K, V, bool) {
+	item, exists := sm.tree().last()
+	return item.key, item.val, exists
+}
+
+// A SortedMapMap is a 2-level map, like MapMapAny, that keeps both levels
+// of keys in order. Every iteration is in lexicographic key order, and it
+// adds range queries on the first key; the SortedMap returned by Submap
+// offers the same on the second key.
+//
+// As with MapMapAny, a submap is deleted when Delete removes its last
+// value. A submap obtained from Submap may be modified directly, but
+// emptying it that way leaves the empty submap in place.
+//
+// A SortedMapMap must be created with NewSortedMapMap or
+// NewSortedMapMapFunc, and must not be modified while it is being
+// iterated over. A nil *SortedMapMap functions as an empty map, except
+// that it can not be Set.
+type SortedMapMap[K1, K2 comparable, V any] struct {
+	t    *btree[K1, *SortedMap[K2, V]]
+	cmp2 func(K2, K2) int
+}
+
+// NewSortedMapMap returns a new, empty SortedMapMap in the natural order
+// of its keys.
+func NewSortedMapMap[K1, K2 cmp.Ordered, V any]() *SortedMapMap[K1, K2, V] {
+	return NewSortedMapMapFunc[K1, K2, V](cmp.Compare[K1], cmp.Compare[K2])
+}
+
+// NewSortedMapMapFunc returns a new, empty SortedMapMap ordered by the
+// given comparison functions.
+func NewSortedMapMapFunc[K1, K2 comparable, V any](
+	cmp1 func(K1, K1) int,
+	cmp2 func(K2, K2) int,
+) *SortedMapMap[K1, K2, V] {
+	return &SortedMapMap[K1, K2, V]{newBtree[K1, *SortedMap[K2, V]](cmp1), cmp2}
+}
+
+// tree returns the map's B-tree, or an empty one for a nil map.
+func (smm *SortedMapMap[K1, K2, V]) tree() *btree[K1, *SortedMap[K2, V]] {
+	if smm == nil {
+		return &btree[K1, *SortedMap[K2, V]]{}
+	}
+	return smm.t
+}
+
+// Set will set the given value with the given keys.
+//
+// This will panic if called on a nil map.
+func (smm *SortedMapMap[K1, K2, V]) Set(key1 K1, key2 K2, value V) {
+	if smm == nil {
+		panic("Set called on a nil SortedMapMap")
+	}
+	submap, _ := smm.t.get(key1)
+	if submap == nil {
+		submap = NewSortedMapFunc[K2, V](smm.cmp2)
+		smm.t.set(key1, submap)
+	}
+	submap.t.set(key2, value)
+}
+
+// SetByTuple sets by the key tuple.
+func (smm *SortedMapMap[K1, K2, V]) SetByTuple(key Tuple2[K1, K2], value V) {
+	smm.Set(key.Key1, key.Key2, value)
+}
+
+// Get returns the value for the given keys. The second value is true if
+// the key exists, false otherwise.
+func (smm *SortedMapMap[K1, K2, V]) Get(key1 K1, key2 K2) (V, bool) {
+	return smm.Submap(key1).Get(key2)
+}
+
+// GetByTuple retrieves by the given tuple. The second value is true if
+// the key exists, false otherwise.
+func (smm *SortedMapMap[K1, K2, V]) GetByTuple(key Tuple2[K1, K2]) (V, bool) {
+	return smm.Get(key.Key1, key.Key2)
+}
+
+// Delete deletes the value from the map, deleting the submap if it is
+// now empty.
+func (smm *SortedMapMap[K1, K2, V]) Delete(key1 K1, key2 K2) {
+	submap := smm.Submap(key1)
+	if submap == nil {
+		return
+	}
+	submap.Delete(key2)
+	if submap.Len() == 0 {
+		smm.t.delete(key1)
+	}
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func (smm *SortedMapMap[K1, K2, V]) DeleteByTuple(key Tuple2[K1, K2]) {
+	smm.Delete(key.Key1, key.Key2)
+}
+
+// DeleteFunc deletes from the map the values for which the function
+// returns true. If all values from a submap are deleted, the submap will
+// be deleted from the SortedMapMap.
+func (smm *SortedMapMap[K1, K2, V]) DeleteFunc(f func(K1, K2, V) bool) {
+	var keys []Tuple2[K1, K2]
+	for key, val := range smm.All() {
+		if f(key.Key1, key.Key2, val) {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		smm.DeleteByTuple(key)
+	}
+}
+
+// Submap returns the submap for the given first key, or nil if there is
+// none. The submap is shared with the SortedMapMap, not copied.
+func (smm *SortedMapMap[K1, K2, V]) Submap(key1 K1) *SortedMap[K2, V] {
+	submap, _ := smm.tree().get(key1)
+	return submap
+}
+
+// Submaps returns an iterator over the first keys and their submaps, in
+// ascending key order.
+func (smm *SortedMapMap[K1, K2, V]) Submaps() iter.Seq2[K1, *SortedMap[K2, V]] {
+	return smm.tree().all
+}
+
+// Clone returns a copy of the SortedMapMap structure. It's a shallow copy
+// of the full SortedMapMap.
+func (smm *SortedMapMap[K1, K2, V]) Clone() *SortedMapMap[K1, K2, V] {
+	if smm == nil {
+		return nil
+	}
+	return &SortedMapMap[K1, K2, V]{
+		smm.t.clone((*SortedMap[K2, V]).Clone),
+		smm.cmp2,
+	}
+}
+
+// EqualFunc returns true if the two maps have the same keys, and the
+// function returns true for the values of each key.
+func (smm *SortedMapMap[K1, K2, V]) EqualFunc(
+	r *SortedMapMap[K1, K2, V],
+	eq func(v1, v2 V) bool,
+) bool {
+	if smm.tree().size != r.tree().size {
+		return false
+	}
+	for key1, submap := range smm.tree().all {
+		if !submap.EqualFunc(r.Submap(key1), eq) {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the total number of values in the SortedMapMap.
+func (smm *SortedMapMap[K1, K2, V]) Len() int {
+	l := 0
+	for _, submap := range smm.tree().all {
+		l += submap.Len()
+	}
+	return l
+}
+
+// All returns an iterator over the map that yields the keys as a Tuple2,
+// and the value in the value slot, in lexicographic key order.
+func (smm *SortedMapMap[K1, K2, V]) All() iter.Seq2[Tuple2[K1, K2], V] {
+	return smm.iterate(nil, nil, false)
+}
+
+// Backward returns an iterator over the map that yields the keys as a
+// Tuple2, and the value in the value slot, in reverse lexicographic key
+// order; both levels of keys descend.
+func (smm *SortedMapMap[K1, K2, V]) Backward() iter.Seq2[Tuple2[K1, K2], V] {
+	return smm.iterate(nil, nil, true)
+}
+
+// Range returns an iterator over the map like All, restricted to the
+// first keys that are greater than or equal to lo and less than hi.
+func (smm *SortedMapMap[K1, K2, V]) Range(lo, hi K1) iter.Seq2[Tuple2[K1, K2], V] {
+	return smm.iterate(&lo, &hi, false)
+}
+
+func (smm *SortedMapMap[K1, K2, V]) iterate(
+	lo, hi *K1,
+	backward bool,
+) iter.Seq2[Tuple2[K1, K2], V] {
+	return func(yield func(Tuple2[K1, K2], V) bool) {
+		visit := func(key1 K1, submap *SortedMap[K2, V]) bool {
+			level := submap.All()
+			if backward {
+				level = submap.Backward()
+			}
+			for key2, val := range level {
+				if !yield(Tuple2[K1, K2]{key1, key2}, val) {
+					return false
+				}
+			}
+			return true
+		}
+		if backward {
+			smm.tree().descend(visit)
+		} else {
+			smm.tree().ascend(lo, hi, visit)
+		}
+	}
+}
+
+// Keys returns an iterator on the keys as a Tuple2, in lexicographic
+// order.
+func (smm *SortedMapMap[K1, K2, V]) Keys() iter.Seq[Tuple2[K1, K2]] {
+	return func(yield func(Tuple2[K1, K2]) bool) {
+		for key := range smm.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// KeySlice returns the keys of the SortedMapMap as a slice of Tuple2
+// values, in lexicographic order.
+//
+// A nil map will return a nil slice.
+func (smm *SortedMapMap[K1, K2, V]) KeySlice() []Tuple2[K1, K2] {
+	if smm == nil {
+		return nil
+	}
+
+	r := make([]Tuple2[K1, K2], 0, smm.Len())
+	for key := range smm.All() {
+		r = append(r, key)
+	}
+	return r
+}
+
+// KeyTree returns the keys of the SortedMapMap as a 2-level tree of the
+// various keys, with each level in order.
+//
+// A nil map will return a nil slice.
+func (smm *SortedMapMap[K1, K2, V]) KeyTree() []KeyTree[K1, K2] {
+	if smm == nil {
+		return nil
+	}
+
+	r := make([]KeyTree[K1, K2], 0, smm.t.size)
+	for key1, submap := range smm.t.all {
+		key2Slice := make([]K2, 0, submap.Len())
+		for key2 := range submap.Keys() {
+			key2Slice = append(key2Slice, key2)
+		}
+		r = append(r, KeyTree[K1, K2]{key1, key2Slice})
+	}
+	return r
+}
+
+// Values returns an iterator for all values in this SortedMapMap, in
+// lexicographic key order.
+func (smm *SortedMapMap[K1, K2, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range smm.All() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// ValueSlice returns a slice containing all values for this SortedMapMap,
+// in lexicographic key order.
+func (smm *SortedMapMap[K1, K2, V]) ValueSlice() []V {
+	result := make([]V, 0, smm.Len())
+	for _, val := range smm.All() {
+		result = append(result, val)
+	}
+	return result
+}
+
+// Floor returns the largest first key in the map that is less than or
+// equal to the given key, and its submap. The last value is false if
+// there is none.
+func (smm *SortedMapMap[K1, K2, V]) Floor(key1 K1) (K1, *SortedMap[K2, V], bool) {
+	item, exists := smm.tree().floor(key1)
+	return item.key, item.val, exists
+}
+
+// Ceiling returns the smallest first key in the map that is greater than
+// or equal to the given key, and its submap. The last value is false if
+// there is none.
+func (smm *SortedMapMap[K1, K2, V]) Ceiling(key1 K1) (K1, *SortedMap[K2, V], bool) {
+	item, exists := smm.tree().ceiling(key1)
+	return item.key, item.val, exists
+}
+
+// Min returns the smallest first key in the map and its submap. The last
+// value is false if the map is empty.
+func (smm *SortedMapMap[K1, K2, V]) Min() (K1, *SortedMap[K2, V], bool) {
+	item, exists := smm.tree().first()
+	return item.key, item.val, exists
+}
+
+// Max returns the largest first key in the map and its submap. The last
+// value is false if the map is empty.
+func (smm *SortedMapMap[K1, K2, V]) Max() (K1, *SortedMap[K2, V], bool) {
+	item, exists := smm.tree().last()
+	return item.key, item.val, exists
+}
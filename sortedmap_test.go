@@ -0,0 +1,203 @@
+package cm
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSortedMap(t *testing.T) {
+	sm := NewSortedMap[string, int]()
+	sm.Set("b", 2)
+	sm.Set("d", 4)
+	sm.Set("a", 1)
+	sm.Set("c", 3)
+	sm.Delete("z")
+
+	if val, exists := sm.Get("c"); !exists || val != 3 || sm.Len() != 4 {
+		t.Fatal("incorrect Get")
+	}
+	if !slices.Equal(slices.Collect(sm.Keys()), []string{"a", "b", "c", "d"}) {
+		t.Fatal("incorrect Keys")
+	}
+	if !slices.Equal(slices.Collect(sm.Values()), []int{1, 2, 3, 4}) {
+		t.Fatal("incorrect Values")
+	}
+	var backward []int
+	for _, val := range sm.Backward() {
+		backward = append(backward, val)
+	}
+	if !slices.Equal(backward, []int{4, 3, 2, 1}) {
+		t.Fatal("incorrect Backward")
+	}
+	var ranged []string
+	for key := range sm.Range("b", "d") {
+		ranged = append(ranged, key)
+	}
+	if !slices.Equal(ranged, []string{"b", "c"}) {
+		t.Fatal("incorrect Range")
+	}
+	for range sm.Keys() {
+		break
+	}
+	for range sm.Values() {
+		break
+	}
+
+	if key, val, exists := sm.Floor("bb"); !exists || key != "b" || val != 2 {
+		t.Fatal("incorrect Floor")
+	}
+	if key, _, exists := sm.Ceiling("bb"); !exists || key != "c" {
+		t.Fatal("incorrect Ceiling")
+	}
+	if key, _, exists := sm.Min(); !exists || key != "a" {
+		t.Fatal("incorrect Min")
+	}
+	if key, _, exists := sm.Max(); !exists || key != "d" {
+		t.Fatal("incorrect Max")
+	}
+
+	eq := func(a, b int) bool { return a == b }
+	clone := sm.Clone()
+	if !clone.EqualFunc(sm, eq) {
+		t.Fatal("clone isn't equal")
+	}
+	clone.Set("a", 10)
+	if clone.EqualFunc(sm, eq) {
+		t.Fatal("clone isn't independent")
+	}
+	clone.Delete("a")
+	clone.Set("e", 1)
+	if clone.EqualFunc(sm, eq) || clone.EqualFunc(nil, eq) {
+		t.Fatal("maps with different keys are equal")
+	}
+
+	sm.DeleteFunc(func(key string, val int) bool { return val%2 == 0 })
+	if !slices.Equal(slices.Collect(sm.Keys()), []string{"a", "c"}) {
+		t.Fatal("incorrect DeleteFunc")
+	}
+
+	var nilMap *SortedMap[string, int]
+	nilMap.Delete("a")
+	nilMap.DeleteFunc(func(string, int) bool { return true })
+	if _, exists := nilMap.Get("a"); exists || nilMap.Len() != 0 ||
+		nilMap.Clone() != nil || !nilMap.EqualFunc(nil, eq) {
+		t.Fatal("nil map isn't empty")
+	}
+	panics(t, "Set called on a nil SortedMap", func() { nilMap.Set("a", 1) })
+}
+
+func TestSortedMapMap(t *testing.T) {
+	smm := NewSortedMapMap[int, string, int]()
+	smm.Set(3, "b", 1)
+	smm.Set(3, "a", 2)
+	smm.SetByTuple(Tuple2[int, string]{1, "z"}, 3)
+	smm.Set(2, "c", 4)
+	smm.Set(2, "a", 5)
+
+	expectedKeys := []Tuple2[int, string]{
+		{1, "z"}, {2, "a"}, {2, "c"}, {3, "a"}, {3, "b"},
+	}
+	if !reflect.DeepEqual(smm.KeySlice(), expectedKeys) ||
+		!reflect.DeepEqual(slices.Collect(smm.Keys()), expectedKeys) {
+		t.Fatal("incorrect keys")
+	}
+	if !slices.Equal(smm.ValueSlice(), []int{3, 5, 4, 2, 1}) ||
+		!slices.Equal(slices.Collect(smm.Values()), []int{3, 5, 4, 2, 1}) {
+		t.Fatal("incorrect values")
+	}
+	if !reflect.DeepEqual(smm.KeyTree(), []KeyTree[int, string]{
+		{1, []string{"z"}},
+		{2, []string{"a", "c"}},
+		{3, []string{"a", "b"}},
+	}) {
+		t.Fatal("incorrect KeyTree")
+	}
+	if smm.Len() != 5 {
+		t.Fatal("incorrect Len")
+	}
+
+	var backward []Tuple2[int, string]
+	for key := range smm.Backward() {
+		backward = append(backward, key)
+	}
+	slices.Reverse(backward)
+	if !reflect.DeepEqual(backward, expectedKeys) {
+		t.Fatal("incorrect Backward")
+	}
+	var ranged []Tuple2[int, string]
+	for key := range smm.Range(2, 3) {
+		ranged = append(ranged, key)
+	}
+	if !reflect.DeepEqual(ranged, expectedKeys[1:3]) {
+		t.Fatal("incorrect Range")
+	}
+	for range smm.Keys() {
+		break
+	}
+	for range smm.Values() {
+		break
+	}
+	for range smm.Backward() {
+		break
+	}
+
+	if val, exists := smm.GetByTuple(Tuple2[int, string]{2, "c"}); !exists || val != 4 {
+		t.Fatal("incorrect GetByTuple")
+	}
+	if _, exists := smm.Get(4, "a"); exists {
+		t.Fatal("missing key exists")
+	}
+	if key, submap, exists := smm.Floor(5); !exists || key != 3 || submap.Len() != 2 {
+		t.Fatal("incorrect Floor")
+	}
+	if key, _, exists := smm.Ceiling(0); !exists || key != 1 {
+		t.Fatal("incorrect Ceiling")
+	}
+	if key, _, exists := smm.Min(); !exists || key != 1 {
+		t.Fatal("incorrect Min")
+	}
+	if key, submap, exists := smm.Max(); !exists || key != 3 ||
+		!slices.Equal(slices.Collect(submap.Keys()), []string{"a", "b"}) {
+		t.Fatal("incorrect Max")
+	}
+	var key1s []int
+	for key1 := range smm.Submaps() {
+		key1s = append(key1s, key1)
+	}
+	if !slices.Equal(key1s, []int{1, 2, 3}) {
+		t.Fatal("incorrect Submaps")
+	}
+
+	eq := func(a, b int) bool { return a == b }
+	clone := smm.Clone()
+	if !clone.EqualFunc(smm, eq) {
+		t.Fatal("clone isn't equal")
+	}
+	clone.Set(2, "a", 10)
+	if clone.EqualFunc(smm, eq) {
+		t.Fatal("clone isn't independent")
+	}
+	clone.Delete(1, "z")
+	if clone.EqualFunc(smm, eq) || clone.Submap(1) != nil {
+		t.Fatal("Delete didn't clean up the submap")
+	}
+	clone.Delete(1, "z")
+	clone.DeleteByTuple(Tuple2[int, string]{2, "b"})
+
+	smm.DeleteFunc(func(key1 int, key2 string, val int) bool {
+		return key1 == 3 || key2 == "c"
+	})
+	if !reflect.DeepEqual(smm.KeySlice(), []Tuple2[int, string]{{1, "z"}, {2, "a"}}) {
+		t.Fatal("incorrect DeleteFunc")
+	}
+
+	var nilMap *SortedMapMap[int, string, int]
+	nilMap.Delete(1, "a")
+	if nilMap.Len() != 0 || nilMap.KeySlice() != nil || nilMap.KeyTree() != nil ||
+		nilMap.Clone() != nil || !nilMap.EqualFunc(nil, eq) ||
+		len(nilMap.ValueSlice()) != 0 {
+		t.Fatal("nil map isn't empty")
+	}
+	panics(t, "Set called on a nil SortedMapMap", func() { nilMap.Set(1, "a", 1) })
+}
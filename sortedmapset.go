@@ -0,0 +1,242 @@
+package cm
+
+import (
+	"cmp"
+	"iter"
+)
+
+// A SortedMapSet is a map that contains sets, like MapSet, that keeps
+// both its keys and the values in each set in order. Every iteration is
+// in key order and then value order, and it adds range queries on the
+// keys; the SortedSet returned by Get offers the same on the values.
+//
+// As with MapSet, a set is deleted when Delete removes its last value. A
+// set obtained from Get may be modified directly, but emptying it that
+// way leaves the empty set in place.
+//
+// A SortedMapSet must be created with NewSortedMapSet or
+// NewSortedMapSetFunc, and must not be modified while it is being
+// iterated over. A nil *SortedMapSet functions as an empty MapSet, except
+// that it can not be Added or Unioned to.
+type SortedMapSet[K, V comparable] struct {
+	t    *btree[K, *SortedSet[V]]
+	cmpV func(V, V) int
+}
+
+// NewSortedMapSet returns a new, empty SortedMapSet in the natural order
+// of its keys and values.
+func NewSortedMapSet[K, V cmp.Ordered]() *SortedMapSet[K, V] {
+	return NewSortedMapSetFunc(cmp.Compare[K], cmp.Compare[V])
+}
+
+// NewSortedMapSetFunc returns a new, empty SortedMapSet ordered by the
+// given comparison functions.
+func NewSortedMapSetFunc[K, V comparable](
+	cmpK func(K, K) int,
+	cmpV func(V, V) int,
+) *SortedMapSet[K, V] {
+	return &SortedMapSet[K, V]{newBtree[K, *SortedSet[V]](cmpK), cmpV}
+}
+
+// tree returns the MapSet's B-tree, or an empty one for a nil MapSet.
+func (sms *SortedMapSet[K, V]) tree() *btree[K, *SortedSet[V]] {
+	if sms == nil {
+		return &btree[K, *SortedSet[V]]{}
+	}
+	return sms.t
+}
+
+// set returns the set for the given key, creating it if necessary.
+func (sms *SortedMapSet[K, V]) set(key K, method string) *SortedSet[V] {
+	if sms == nil {
+		panic(method + " called on a nil SortedMapSet")
+	}
+	s, _ := sms.t.get(key)
+	if s == nil {
+		s = NewSortedSetFunc(sms.cmpV)
+		sms.t.set(key, s)
+	}
+	return s
+}
+
+// Add adds the given value to the set for the given key.
+func (sms *SortedMapSet[K, V]) Add(key K, val V) {
+	sms.set(key, "Add").t.set(val, void)
+}
+
+// AddByTuple will add to the set via the given tuple.
+func (sms *SortedMapSet[K, V]) AddByTuple(key Tuple2[K, V]) {
+	sms.Add(key.Key1, key.Key2)
+}
+
+// Union will union the passed-in set into the set for the given key,
+// creating it if necessary.
+func (sms *SortedMapSet[K, V]) Union(key K, r *SortedSet[V]) {
+	sms.set(key, "Union").Union(r)
+}
+
+// Delete removes the value from the set for the given key, deleting the
+// set if it is now empty.
+func (sms *SortedMapSet[K, V]) Delete(key K, val V) {
+	s := sms.Get(key)
+	if s == nil {
+		return
+	}
+	s.t.delete(val)
+	if s.Len() == 0 {
+		sms.t.delete(key)
+	}
+}
+
+// Contains returns true if the set for the given key contains the value.
+func (sms *SortedMapSet[K, V]) Contains(key K, val V) bool {
+	return sms.Get(key).Contains(val)
+}
+
+// Get returns the set for the given key, or nil if there is none. As a
+// nil SortedSet is an empty set, the result can be used directly. The
+// set is shared with the SortedMapSet, not copied.
+func (sms *SortedMapSet[K, V]) Get(key K) *SortedSet[V] {
+	s, _ := sms.tree().get(key)
+	return s
+}
+
+// Len returns the total number of values in all the sets of the
+// SortedMapSet.
+func (sms *SortedMapSet[K, V]) Len() int {
+	l := 0
+	for _, s := range sms.tree().all {
+		l += s.Len()
+	}
+	return l
+}
+
+// KeyLen returns the number of keys in the SortedMapSet.
+func (sms *SortedMapSet[K, V]) KeyLen() int {
+	return sms.tree().size
+}
+
+// Clone returns a copy of the SortedMapSet, including copies of all of
+// its sets.
+func (sms *SortedMapSet[K, V]) Clone() *SortedMapSet[K, V] {
+	if sms == nil {
+		return nil
+	}
+	return &SortedMapSet[K, V]{sms.t.clone((*SortedSet[V]).Clone), sms.cmpV}
+}
+
+// AllValueSet returns a single set containing all values in the
+// SortedMapSet. A SortedMapSet with no keys returns nil.
+func (sms *SortedMapSet[K, V]) AllValueSet() *SortedSet[V] {
+	var retSet *SortedSet[V]
+	for _, s := range sms.tree().all {
+		if retSet == nil {
+			retSet = s.Clone()
+		} else {
+			retSet.Union(s)
+		}
+	}
+	return retSet
+}
+
+// Keys returns an iterator over the keys of the SortedMapSet, in
+// ascending order.
+func (sms *SortedMapSet[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range sms.tree().all {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Sets returns an iterator over the keys of the SortedMapSet and their
+// sets, in ascending key order.
+func (sms *SortedMapSet[K, V]) Sets() iter.Seq2[K, *SortedSet[V]] {
+	return sms.tree().all
+}
+
+// Values yields all the values in the SortedMapSet, in key order and
+// then value order. A value in the sets of several keys is yielded once
+// for each of them.
+func (sms *SortedMapSet[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, val := range sms.All() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator that yields every key and value pair in the
+// SortedMapSet, in key order and then value order.
+func (sms *SortedMapSet[K, V]) All() iter.Seq2[K, V] {
+	return sms.iterate(nil, nil, false)
+}
+
+// Backward returns an iterator that yields every key and value pair in
+// the SortedMapSet, in descending key order and then descending value
+// order.
+func (sms *SortedMapSet[K, V]) Backward() iter.Seq2[K, V] {
+	return sms.iterate(nil, nil, true)
+}
+
+// Range returns an iterator like All, restricted to the keys that are
+// greater than or equal to lo and less than hi.
+func (sms *SortedMapSet[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return sms.iterate(&lo, &hi, false)
+}
+
+func (sms *SortedMapSet[K, V]) iterate(lo, hi *K, backward bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		visit := func(key K, s *SortedSet[V]) bool {
+			level := s.All()
+			if backward {
+				level = s.Backward()
+			}
+			for val := range level {
+				if !yield(key, val) {
+					return false
+				}
+			}
+			return true
+		}
+		if backward {
+			sms.tree().descend(visit)
+		} else {
+			sms.tree().ascend(lo, hi, visit)
+		}
+	}
+}
+
+// Floor returns the largest key in the SortedMapSet that is less than or
+// equal to the given key, and its set. The last value is false if there
+// is none.
+func (sms *SortedMapSet[K, V]) Floor(key K) (K, *SortedSet[V], bool) {
+	item, exists := sms.tree().floor(key)
+	return item.key, item.val, exists
+}
+
+// Ceiling returns the smallest key in the SortedMapSet that is greater
+// than or equal to the given key, and its set. The last value is false
+// if there is none.
+func (sms *SortedMapSet[K, V]) Ceiling(key K) (K, *SortedSet[V], bool) {
+	item, exists := sms.tree().ceiling(key)
+	return item.key, item.val, exists
+}
+
+// Min returns the smallest key in the SortedMapSet and its set. The last
+// value is false if the SortedMapSet is empty.
+func (sms *SortedMapSet[K, V]) Min() (K, *SortedSet[V], bool) {
+	item, exists := sms.tree().first()
+	return item.key, item.val, exists
+}
+
+// Max returns the largest key in the SortedMapSet and its set. The last
+// value is false if the SortedMapSet is empty.
+func (sms *SortedMapSet[K, V]) Max() (K, *SortedSet[V], bool) {
+	item, exists := sms.tree().last()
+	return item.key, item.val, exists
+}
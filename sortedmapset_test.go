@@ -0,0 +1,109 @@
+package cm
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSortedMapSet(t *testing.T) {
+	sms := NewSortedMapSet[string, int]()
+	sms.Add("b", 2)
+	sms.Add("b", 1)
+	sms.AddByTuple(Tuple2[string, int]{"a", 3})
+	sms.Union("c", sortedSetOf(5, 4))
+	sms.Union("c", nil)
+
+	type pair struct {
+		key string
+		val int
+	}
+	collect := func(seq func(func(string, int) bool)) []pair {
+		var r []pair
+		for key, val := range seq {
+			r = append(r, pair{key, val})
+		}
+		return r
+	}
+
+	all := []pair{{"a", 3}, {"b", 1}, {"b", 2}, {"c", 4}, {"c", 5}}
+	if !slices.Equal(collect(sms.All()), all) {
+		t.Fatal("incorrect All")
+	}
+	backward := collect(sms.Backward())
+	slices.Reverse(backward)
+	if !slices.Equal(backward, all) {
+		t.Fatal("incorrect Backward")
+	}
+	if !slices.Equal(collect(sms.Range("b", "c")), all[1:3]) {
+		t.Fatal("incorrect Range")
+	}
+	for range sms.All() {
+		break
+	}
+	for range sms.Backward() {
+		break
+	}
+	if !slices.Equal(slices.Collect(sms.Values()), []int{3, 1, 2, 4, 5}) {
+		t.Fatal("incorrect Values")
+	}
+	for range sms.Values() {
+		break
+	}
+	if !slices.Equal(slices.Collect(sms.Keys()), []string{"a", "b", "c"}) {
+		t.Fatal("incorrect Keys")
+	}
+	for range sms.Keys() {
+		break
+	}
+	var setLens []int
+	for _, s := range sms.Sets() {
+		setLens = append(setLens, s.Len())
+	}
+	if !slices.Equal(setLens, []int{1, 2, 2}) {
+		t.Fatal("incorrect Sets")
+	}
+	if !slices.Equal(sms.AllValueSet().AsSlice(), []int{1, 2, 3, 4, 5}) {
+		t.Fatal("incorrect AllValueSet")
+	}
+
+	if sms.Len() != 5 || sms.KeyLen() != 3 || !sms.Contains("b", 2) || sms.Contains("b", 3) ||
+		sms.Contains("z", 1) {
+		t.Fatal("incorrect contents")
+	}
+	if key, s, exists := sms.Floor("bb"); !exists || key != "b" || s.Len() != 2 {
+		t.Fatal("incorrect Floor")
+	}
+	if key, _, exists := sms.Ceiling("bb"); !exists || key != "c" {
+		t.Fatal("incorrect Ceiling")
+	}
+	if key, _, exists := sms.Min(); !exists || key != "a" {
+		t.Fatal("incorrect Min")
+	}
+	if key, _, exists := sms.Max(); !exists || key != "c" {
+		t.Fatal("incorrect Max")
+	}
+
+	clone := sms.Clone()
+	clone.Add("a", 10)
+	if sms.Contains("a", 10) {
+		t.Fatal("clone isn't independent")
+	}
+
+	sms.Delete("b", 1)
+	sms.Delete("b", 2)
+	sms.Delete("z", 1)
+	if sms.Get("b") != nil || sms.Len() != 3 || sms.KeyLen() != 2 {
+		t.Fatal("Delete didn't clean up the set")
+	}
+
+	var nilMapSet *SortedMapSet[string, int]
+	nilMapSet.Delete("a", 1)
+	if nilMapSet.Len() != 0 || nilMapSet.KeyLen() != 0 || nilMapSet.Contains("a", 1) ||
+		nilMapSet.Clone() != nil || nilMapSet.AllValueSet() != nil {
+		t.Fatal("nil SortedMapSet isn't empty")
+	}
+	panics(t, "Add called on a nil SortedMapSet", func() { nilMapSet.Add("a", 1) })
+	panics(t, "Union called on a nil SortedMapSet", func() {
+		nilMapSet.Union("a", nil)
+	})
+}
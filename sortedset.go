@@ -0,0 +1,248 @@
+package cm
+
+import (
+	"cmp"
+	"iter"
+)
+
+// A SortedSet is a set that keeps its values in order, as determined by a
+// comparison function in the style of slices.SortFunc. It offers the
+// operations of Set, plus ordered iteration and range queries.
+//
+// It is backed by a B-tree, so Add, Remove and Contains take O(log n)
+// time rather than Set's O(1); use a SortedSet when the ordering is
+// actually needed.
+//
+// Unlike Set, a SortedSet must be created with NewSortedSet or
+// NewSortedSetFunc, as it needs to know how to order its values. The
+// comparison function must be consistent with ==; two values that
+// compare equal are the same value as far as the set is concerned.
+//
+// Unlike a map, a SortedSet must not be modified while it is being
+// iterated over.
+//
+// As with a nil Set, a nil *SortedSet functions as an empty set, except
+// that it can not be Added or Unioned to.
+type SortedSet[M comparable] struct {
+	t *btree[M, struct{}]
+}
+
+// NewSortedSet returns a new, empty SortedSet in the natural order of
+// its values.
+func NewSortedSet[M cmp.Ordered]() *SortedSet[M] {
+	return NewSortedSetFunc(cmp.Compare[M])
+}
+
+// NewSortedSetFunc returns a new, empty SortedSet ordered by the given
+// comparison function.
+func NewSortedSetFunc[M comparable](cmp func(M, M) int) *SortedSet[M] {
+	return &SortedSet[M]{newBtree[M, struct{}](cmp)}
+}
+
+// tree returns the set's B-tree, or an empty one for a nil set.
+func (s *SortedSet[M]) tree() *btree[M, struct{}] {
+	if s == nil {
+		return &btree[M, struct{}]{}
+	}
+	return s.t
+}
+
+// empty returns a new, empty set with the same ordering as this set, or
+// as the passed-in set if this one is nil. If both are nil, it returns
+// nil.
+func (s *SortedSet[M]) empty(r *SortedSet[M]) *SortedSet[M] {
+	switch {
+	case s != nil:
+		return NewSortedSetFunc(s.t.cmp)
+	case r != nil:
+		return NewSortedSetFunc(r.t.cmp)
+	default:
+		return nil
+	}
+}
+
+// Add will add the given value in to the set.
+func (s *SortedSet[M]) Add(v M) {
+	if s == nil {
+		panic("Add called on nil SortedSet")
+	}
+	s.t.set(v, void)
+}
+
+// Remove will remove the given value from the set if it exists.
+func (s *SortedSet[M]) Remove(v M) {
+	if s != nil {
+		s.t.delete(v)
+	}
+}
+
+// Contains returns true if the set contains the given value.
+func (s *SortedSet[M]) Contains(v M) bool {
+	_, exists := s.tree().get(v)
+	return exists
+}
+
+// Len returns the number of values in the set.
+func (s *SortedSet[M]) Len() int {
+	return s.tree().size
+}
+
+// AsSlice returns the values in the set as a slice, in order.
+func (s *SortedSet[M]) AsSlice() []M {
+	vals := make([]M, 0, s.Len())
+	for val := range s.All() {
+		vals = append(vals, val)
+	}
+	return vals
+}
+
+// Clone returns a copy of the set.
+func (s *SortedSet[M]) Clone() *SortedSet[M] {
+	if s == nil {
+		return nil
+	}
+	return &SortedSet[M]{s.t.clone(nil)}
+}
+
+// Equal returns if two sets contain the same values.
+func (s *SortedSet[M]) Equal(r *SortedSet[M]) bool {
+	return s.Len() == r.Len() && s.SubsetOf(r)
+}
+
+// SubsetOf returns true if the set this is called on is a subset of the
+// passed-in set.
+func (s *SortedSet[M]) SubsetOf(r *SortedSet[M]) bool {
+	if s.Len() > r.Len() {
+		return false
+	}
+	for val := range s.All() {
+		if !r.Contains(val) {
+			return false
+		}
+	}
+	return true
+}
+
+// SupersetOf returns true if the set this is called on is a superset of
+// the passed-in set.
+func (s *SortedSet[M]) SupersetOf(r *SortedSet[M]) bool {
+	return r.SubsetOf(s)
+}
+
+// Union will add all elements of the passed-in set to this set. This set
+// is then returned, allowing chaining.
+func (s *SortedSet[M]) Union(r *SortedSet[M]) *SortedSet[M] {
+	if s == nil {
+		panic("Union called on nil SortedSet")
+	}
+	for val := range r.All() {
+		s.t.set(val, void)
+	}
+	return s
+}
+
+// Subtract removes all elements from this set that are in the passed-in
+// set.
+//
+// The set this is called on is returned, allowing for chaining.
+func (s *SortedSet[M]) Subtract(r *SortedSet[M]) *SortedSet[M] {
+	if s == nil {
+		return s
+	}
+	for val := range r.All() {
+		s.t.delete(val)
+	}
+	return s
+}
+
+// Intersect returns a new set containing the elements that are in both
+// sets, ordered as this set is.
+func (s *SortedSet[M]) Intersect(r *SortedSet[M]) *SortedSet[M] {
+	result := s.empty(r)
+	small, large := s, r
+	if small.Len() > large.Len() {
+		small, large = large, small
+	}
+	for val := range small.All() {
+		if large.Contains(val) {
+			result.t.set(val, void)
+		}
+	}
+	return result
+}
+
+// XOR returns a new set containing the values in either the set this
+// method is called on, or the set passed in, but not both. It is ordered
+// as this set is.
+func (s *SortedSet[M]) XOR(r *SortedSet[M]) *SortedSet[M] {
+	result := s.empty(r)
+	for val := range s.All() {
+		if !r.Contains(val) {
+			result.t.set(val, void)
+		}
+	}
+	for val := range r.All() {
+		if !s.Contains(val) {
+			result.t.set(val, void)
+		}
+	}
+	return result
+}
+
+// All returns an iterator over the values in the set, in ascending
+// order.
+func (s *SortedSet[M]) All() iter.Seq[M] {
+	return func(yield func(M) bool) {
+		s.tree().ascend(nil, nil, func(val M, _ struct{}) bool {
+			return yield(val)
+		})
+	}
+}
+
+// Backward returns an iterator over the values in the set, in descending
+// order.
+func (s *SortedSet[M]) Backward() iter.Seq[M] {
+	return func(yield func(M) bool) {
+		s.tree().descend(func(val M, _ struct{}) bool {
+			return yield(val)
+		})
+	}
+}
+
+// Range returns an iterator over the values in the set that are greater
+// than or equal to lo and less than hi, in ascending order.
+func (s *SortedSet[M]) Range(lo, hi M) iter.Seq[M] {
+	return func(yield func(M) bool) {
+		s.tree().ascend(&lo, &hi, func(val M, _ struct{}) bool {
+			return yield(val)
+		})
+	}
+}
+
+// Floor returns the largest value in the set that is less than or equal
+// to the given value. The second value is false if there is none.
+func (s *SortedSet[M]) Floor(v M) (M, bool) {
+	item, exists := s.tree().floor(v)
+	return item.key, exists
+}
+
+// Ceiling returns the smallest value in the set that is greater than or
+// equal to the given value. The second value is false if there is none.
+func (s *SortedSet[M]) Ceiling(v M) (M, bool) {
+	item, exists := s.tree().ceiling(v)
+	return item.key, exists
+}
+
+// Min returns the smallest value in the set. The second value is false
+// if the set is empty.
+func (s *SortedSet[M]) Min() (M, bool) {
+	item, exists := s.tree().first()
+	return item.key, exists
+}
+
+// Max returns the largest value in the set. The second value is false if
+// the set is empty.
+func (s *SortedSet[M]) Max() (M, bool) {
+	item, exists := s.tree().last()
+	return item.key, exists
+}
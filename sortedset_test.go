@@ -0,0 +1,138 @@
+package cm
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+)
+
+func sortedSetOf(vals ...int) *SortedSet[int] {
+	s := NewSortedSet[int]()
+	for _, val := range vals {
+		s.Add(val)
+	}
+	return s
+}
+
+func TestSortedSet(t *testing.T) {
+	s := sortedSetOf(5, 1, 3, 9, 7)
+	s.Add(3)
+	s.Remove(9)
+	s.Remove(100)
+
+	if s.Len() != 4 || !s.Contains(5) || s.Contains(9) {
+		t.Fatal("incorrect contents")
+	}
+	if !slices.Equal(s.AsSlice(), []int{1, 3, 5, 7}) {
+		t.Fatal("incorrect AsSlice")
+	}
+	if !slices.Equal(slices.Collect(s.Backward()), []int{7, 5, 3, 1}) {
+		t.Fatal("incorrect Backward")
+	}
+	if !slices.Equal(slices.Collect(s.Range(2, 7)), []int{3, 5}) {
+		t.Fatal("incorrect Range")
+	}
+	for range s.All() {
+		break
+	}
+	for range s.Backward() {
+		break
+	}
+
+	if v, exists := s.Floor(4); !exists || v != 3 {
+		t.Fatal("incorrect Floor")
+	}
+	if _, exists := s.Floor(0); exists {
+		t.Fatal("incorrect Floor below the minimum")
+	}
+	if v, exists := s.Ceiling(4); !exists || v != 5 {
+		t.Fatal("incorrect Ceiling")
+	}
+	if v, exists := s.Min(); !exists || v != 1 {
+		t.Fatal("incorrect Min")
+	}
+	if v, exists := s.Max(); !exists || v != 7 {
+		t.Fatal("incorrect Max")
+	}
+
+	clone := s.Clone()
+	if !clone.Equal(s) {
+		t.Fatal("clone isn't equal")
+	}
+	clone.Remove(1)
+	if clone.Equal(s) || !s.Contains(1) {
+		t.Fatal("clone isn't independent")
+	}
+
+	desc := NewSortedSetFunc(func(a, b int) int { return cmp.Compare(b, a) })
+	desc.Union(s)
+	if !slices.Equal(desc.AsSlice(), []int{7, 5, 3, 1}) {
+		t.Fatal("comparison function not used")
+	}
+}
+
+func TestSortedSetNil(t *testing.T) {
+	var s *SortedSet[int]
+
+	s.Remove(1)
+	if s.Len() != 0 || s.Contains(1) || len(s.AsSlice()) != 0 {
+		t.Fatal("nil set isn't empty")
+	}
+	if s.Clone() != nil {
+		t.Fatal("clone of nil set isn't nil")
+	}
+	if _, exists := s.Min(); exists {
+		t.Fatal("nil set has a Min")
+	}
+	if _, exists := s.Max(); exists {
+		t.Fatal("nil set has a Max")
+	}
+	if _, exists := s.Ceiling(1); exists {
+		t.Fatal("nil set has a Ceiling")
+	}
+	if !s.Equal(NewSortedSet[int]()) || !s.Equal(nil) {
+		t.Fatal("nil set isn't equal to an empty set")
+	}
+	if s.Subtract(sortedSetOf(1)) != nil {
+		t.Fatal("subtracting from a nil set isn't nil")
+	}
+	if s.Intersect(nil) != nil || s.XOR(nil) != nil {
+		t.Fatal("operations on two nil sets aren't nil")
+	}
+	if !slices.Equal(s.XOR(sortedSetOf(2, 1)).AsSlice(), []int{1, 2}) {
+		t.Fatal("XOR with a nil set is wrong")
+	}
+
+	panics(t, "Add called on nil SortedSet", func() { s.Add(1) })
+	panics(t, "Union called on nil SortedSet", func() { s.Union(nil) })
+}
+
+func TestSortedSetOperations(t *testing.T) {
+	s1 := sortedSetOf(1, 2, 3)
+	s2 := sortedSetOf(1, 2)
+	s3 := sortedSetOf(1, 4)
+
+	if !s2.SubsetOf(s1) || s1.SubsetOf(s2) || s3.SubsetOf(s1) {
+		t.Fatal("incorrect SubsetOf")
+	}
+	if !s1.SupersetOf(s2) || s2.SupersetOf(s1) {
+		t.Fatal("incorrect SupersetOf")
+	}
+	if s1.Equal(sortedSetOf(1, 2, 4)) {
+		t.Fatal("unequal sets are equal")
+	}
+
+	if !slices.Equal(s1.Intersect(s3).AsSlice(), []int{1}) ||
+		!slices.Equal(s3.Intersect(s1).AsSlice(), []int{1}) {
+		t.Fatal("incorrect Intersect")
+	}
+	if !slices.Equal(s1.XOR(s3).AsSlice(), []int{2, 3, 4}) {
+		t.Fatal("incorrect XOR")
+	}
+	if !slices.Equal(s1.Clone().Subtract(s3).AsSlice(), []int{2, 3}) {
+		t.Fatal("incorrect Subtract")
+	}
+	if !slices.Equal(s1.Clone().Union(s3).AsSlice(), []int{1, 2, 3, 4}) {
+		t.Fatal("incorrect Union")
+	}
+}
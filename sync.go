@@ -0,0 +1,886 @@
+package cm
+
+import (
+	"hash/maphash"
+	"iter"
+	"sync"
+
+	"golang.org/x/exp/maps"
+)
+
+// The Sync* types in this file wrap the maps in this package with
+// locking, for the case where a map is shared between goroutines.
+//
+// Rather than a single lock around the entire map, which tends to become
+// a point of contention, these are sharded by the first key. Each shard
+// is a normal map from this package with its own sync.RWMutex, so
+// operations on different first keys will generally not contend with
+// each other.
+//
+// Individual operations are atomic. For compound operations, use the
+// provided GetOrSet, Update, and (for comparable values) CompareAndSwap
+// methods rather than a Get followed by a Set, which would race. The
+// function passed to Update is called with the shard's lock held, so it
+// must be fast and must not call back into the map.
+//
+// The iteration methods iterate over a snapshot of the map, taken by
+// briefly locking all the shards at once. No locks are held while your
+// loop body runs, so it is free to modify the map, but those
+// modifications will not be seen by the iteration in progress.
+//
+// The zero value of each of these types is ready to use, with a default
+// number of shards. The New* constructors allow specifying the number of
+// shards. None of them may be copied after first use.
+
+// DefaultShards is the number of shards used by the zero value of the
+// Sync* types.
+const DefaultShards = 32
+
+type lockedMap[M any] struct {
+	sync.RWMutex
+	m M
+}
+
+// shards implements the striping shared by the Sync* types.
+type shards[K comparable, M any] struct {
+	once   sync.Once
+	seed   maphash.Seed
+	shards []lockedMap[M]
+}
+
+func (s *shards[K, M]) init(count int, newMap func() M) {
+	s.once.Do(func() {
+		if count < 1 {
+			count = 1
+		}
+		s.seed = maphash.MakeSeed()
+		s.shards = make([]lockedMap[M], count)
+		for i := range s.shards {
+			s.shards[i].m = newMap()
+		}
+	})
+}
+
+func (s *shards[K, M]) index(key K) int {
+	return int(hashOf(s.seed, key) % uint64(len(s.shards)))
+}
+
+func (s *shards[K, M]) get(key K) *lockedMap[M] {
+	return &s.shards[s.index(key)]
+}
+
+func (s *shards[K, M]) rLockAll() {
+	for i := range s.shards {
+		s.shards[i].RLock()
+	}
+}
+
+func (s *shards[K, M]) rUnlockAll() {
+	for i := range s.shards {
+		s.shards[i].RUnlock()
+	}
+}
+
+// SyncMapMapAny is a MapMapAny that is safe for concurrent use, sharded
+// by the first key.
+type SyncMapMapAny[K1, K2 comparable, V any] struct {
+	s shards[K1, MapMapAny[K1, K2, V]]
+}
+
+// NewSyncMapMapAny returns a new SyncMapMapAny with the given number of
+// shards.
+func NewSyncMapMapAny[K1, K2 comparable, V any](shardCount int) *SyncMapMapAny[K1, K2, V] {
+	smm := &SyncMapMapAny[K1, K2, V]{}
+	smm.s.init(shardCount, newMapMapAny[K1, K2, V])
+	return smm
+}
+
+func newMapMapAny[K1, K2 comparable, V any]() MapMapAny[K1, K2, V] {
+	return MapMapAny[K1, K2, V]{}
+}
+
+func (smm *SyncMapMapAny[K1, K2, V]) init() {
+	smm.s.init(DefaultShards, newMapMapAny[K1, K2, V])
+}
+
+func (smm *SyncMapMapAny[K1, K2, V]) shard(key1 K1) *lockedMap[MapMapAny[K1, K2, V]] {
+	smm.init()
+	return smm.s.get(key1)
+}
+
+// Get retrieves the value for the given keys. The second value is true
+// if the key exists, false otherwise.
+func (smm *SyncMapMapAny[K1, K2, V]) Get(key1 K1, key2 K2) (val V, exists bool) {
+	shard := smm.shard(key1)
+	shard.RLock()
+	defer shard.RUnlock()
+	val, exists = shard.m[key1][key2]
+	return val, exists
+}
+
+// GetByTuple retrieves by the given tuple.
+func (smm *SyncMapMapAny[K1, K2, V]) GetByTuple(key Tuple2[K1, K2]) (val V, exists bool) {
+	return smm.Get(key.Key1, key.Key2)
+}
+
+// Set will set the given value with the given keys.
+func (smm *SyncMapMapAny[K1, K2, V]) Set(key1 K1, key2 K2, value V) {
+	shard := smm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.m.Set(key1, key2, value)
+}
+
+// SetByTuple sets by the key tuple.
+func (smm *SyncMapMapAny[K1, K2, V]) SetByTuple(key Tuple2[K1, K2], value V) {
+	smm.Set(key.Key1, key.Key2, value)
+}
+
+// Delete deletes the value from the map.
+func (smm *SyncMapMapAny[K1, K2, V]) Delete(key1 K1, key2 K2) {
+	shard := smm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.m.Delete(key1, key2)
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func (smm *SyncMapMapAny[K1, K2, V]) DeleteByTuple(key Tuple2[K1, K2]) {
+	smm.Delete(key.Key1, key.Key2)
+}
+
+// GetOrSet returns the existing value for the keys if present, with
+// loaded true. Otherwise, it sets the given value and returns it, with
+// loaded false.
+func (smm *SyncMapMapAny[K1, K2, V]) GetOrSet(
+	key1 K1,
+	key2 K2,
+	value V,
+) (actual V, loaded bool) {
+	shard := smm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	actual, loaded = shard.m[key1][key2]
+	if loaded {
+		return actual, true
+	}
+	shard.m.Set(key1, key2, value)
+	return value, false
+}
+
+// Update atomically updates the value for the given keys. The function
+// is passed the current value and whether it exists, and returns the new
+// value and whether it should exist. If it returns false, the value is
+// deleted.
+//
+// Update returns the new value and whether it exists.
+//
+// The function is called with the lock held and must not call back in to
+// this map.
+func (smm *SyncMapMapAny[K1, K2, V]) Update(
+	key1 K1,
+	key2 K2,
+	f func(old V, exists bool) (V, bool),
+) (V, bool) {
+	shard := smm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	old, exists := shard.m[key1][key2]
+	val, keep := f(old, exists)
+	if keep {
+		shard.m.Set(key1, key2, val)
+	} else {
+		shard.m.Delete(key1, key2)
+	}
+	return val, keep
+}
+
+// Submap returns a copy of the submap for the given first key, or nil
+// if there is none.
+func (smm *SyncMapMapAny[K1, K2, V]) Submap(key1 K1) map[K2]V {
+	shard := smm.shard(key1)
+	shard.RLock()
+	defer shard.RUnlock()
+	submap := shard.m[key1]
+	if submap == nil {
+		return nil
+	}
+	return maps.Clone(submap)
+}
+
+// Len returns the total number of values in the map.
+//
+// As other goroutines may be modifying the map, this may be out of date
+// by the time it is returned.
+func (smm *SyncMapMapAny[K1, K2, V]) Len() int {
+	smm.init()
+	l := 0
+	for i := range smm.s.shards {
+		shard := &smm.s.shards[i]
+		shard.RLock()
+		l += shard.m.Len()
+		shard.RUnlock()
+	}
+	return l
+}
+
+// Snapshot returns a consistent copy of the full map as a MapMapAny.
+func (smm *SyncMapMapAny[K1, K2, V]) Snapshot() MapMapAny[K1, K2, V] {
+	smm.init()
+	smm.s.rLockAll()
+	defer smm.s.rUnlockAll()
+
+	snapshot := MapMapAny[K1, K2, V]{}
+	for i := range smm.s.shards {
+		for key1, submap := range smm.s.shards[i].m.Clone() {
+			snapshot[key1] = submap
+		}
+	}
+	return snapshot
+}
+
+// All returns an iterator over a snapshot of the map, yielding the keys
+// as a Tuple2 and the value in the value slot.
+func (smm *SyncMapMapAny[K1, K2, V]) All() iter.Seq2[Tuple2[K1, K2], V] {
+	return func(yield func(Tuple2[K1, K2], V) bool) {
+		for key, val := range smm.Snapshot().All() {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of a snapshot of the map.
+func (smm *SyncMapMapAny[K1, K2, V]) Keys() iter.Seq[Tuple2[K1, K2]] {
+	return func(yield func(Tuple2[K1, K2]) bool) {
+		for key := range smm.Snapshot().Keys() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of a snapshot of the map.
+func (smm *SyncMapMapAny[K1, K2, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for val := range smm.Snapshot().Values() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// SyncMapMap is a SyncMapMapAny with a comparable value type, which
+// allows for the CompareAndSwap and CompareAndDelete methods.
+type SyncMapMap[K1, K2, V comparable] struct {
+	SyncMapMapAny[K1, K2, V]
+}
+
+// NewSyncMapMap returns a new SyncMapMap with the given number of
+// shards.
+func NewSyncMapMap[K1, K2, V comparable](shardCount int) *SyncMapMap[K1, K2, V] {
+	smm := &SyncMapMap[K1, K2, V]{}
+	smm.s.init(shardCount, newMapMapAny[K1, K2, V])
+	return smm
+}
+
+// CompareAndSwap swaps the old and new values for the given keys if the
+// value stored in the map is equal to old. It returns whether the swap
+// was performed.
+func (smm *SyncMapMap[K1, K2, V]) CompareAndSwap(key1 K1, key2 K2, old, new V) bool {
+	shard := smm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	current, exists := shard.m[key1][key2]
+	if !exists || current != old {
+		return false
+	}
+	shard.m[key1][key2] = new
+	return true
+}
+
+// CompareAndDelete deletes the value for the given keys if it is equal
+// to old. It returns whether the value was deleted.
+func (smm *SyncMapMap[K1, K2, V]) CompareAndDelete(key1 K1, key2 K2, old V) bool {
+	shard := smm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	current, exists := shard.m[key1][key2]
+	if !exists || current != old {
+		return false
+	}
+	shard.m.Delete(key1, key2)
+	return true
+}
+
+// SyncMapMapMapAny is a MapMapMapAny that is safe for concurrent use,
+// sharded by the first key.
+type SyncMapMapMapAny[K1, K2, K3 comparable, V any] struct {
+	s shards[K1, MapMapMapAny[K1, K2, K3, V]]
+}
+
+// NewSyncMapMapMapAny returns a new SyncMapMapMapAny with the given
+// number of shards.
+func NewSyncMapMapMapAny[K1, K2, K3 comparable, V any](
+	shardCount int,
+) *SyncMapMapMapAny[K1, K2, K3, V] {
+	smmm := &SyncMapMapMapAny[K1, K2, K3, V]{}
+	smmm.s.init(shardCount, newMapMapMapAny[K1, K2, K3, V])
+	return smmm
+}
+
+func newMapMapMapAny[K1, K2, K3 comparable, V any]() MapMapMapAny[K1, K2, K3, V] {
+	return MapMapMapAny[K1, K2, K3, V]{}
+}
+
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) init() {
+	smmm.s.init(DefaultShards, newMapMapMapAny[K1, K2, K3, V])
+}
+
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) shard(
+	key1 K1,
+) *lockedMap[MapMapMapAny[K1, K2, K3, V]] {
+	smmm.init()
+	return smmm.s.get(key1)
+}
+
+// Get retrieves the value for the given keys. The second value is true
+// if the key exists, false otherwise.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Get(
+	key1 K1,
+	key2 K2,
+	key3 K3,
+) (val V, exists bool) {
+	shard := smmm.shard(key1)
+	shard.RLock()
+	defer shard.RUnlock()
+	val, exists = shard.m[key1][key2][key3]
+	return val, exists
+}
+
+// GetByTuple retrieves by the given tuple.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) GetByTuple(
+	key Tuple3[K1, K2, K3],
+) (val V, exists bool) {
+	return smmm.Get(key.Key1, key.Key2, key.Key3)
+}
+
+// Set will set the given value with the given keys.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Set(key1 K1, key2 K2, key3 K3, value V) {
+	shard := smmm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.m.Set(key1, key2, key3, value)
+}
+
+// SetByTuple sets by the key tuple.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) SetByTuple(key Tuple3[K1, K2, K3], value V) {
+	smmm.Set(key.Key1, key.Key2, key.Key3, value)
+}
+
+// Delete deletes the value from the map.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Delete(key1 K1, key2 K2, key3 K3) {
+	shard := smmm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.m.Delete(key1, key2, key3)
+}
+
+// DeleteByTuple deletes by the tuple version of the key.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) DeleteByTuple(key Tuple3[K1, K2, K3]) {
+	smmm.Delete(key.Key1, key.Key2, key.Key3)
+}
+
+// GetOrSet returns the existing value for the keys if present, with
+// loaded true. Otherwise, it sets the given value and returns it, with
+// loaded false.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) GetOrSet(
+	key1 K1,
+	key2 K2,
+	key3 K3,
+	value V,
+) (actual V, loaded bool) {
+	shard := smmm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	actual, loaded = shard.m[key1][key2][key3]
+	if loaded {
+		return actual, true
+	}
+	shard.m.Set(key1, key2, key3, value)
+	return value, false
+}
+
+// Update atomically updates the value for the given keys. See
+// SyncMapMapAny.Update.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Update(
+	key1 K1,
+	key2 K2,
+	key3 K3,
+	f func(old V, exists bool) (V, bool),
+) (V, bool) {
+	shard := smmm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	old, exists := shard.m[key1][key2][key3]
+	val, keep := f(old, exists)
+	if keep {
+		shard.m.Set(key1, key2, key3, val)
+	} else {
+		shard.m.Delete(key1, key2, key3)
+	}
+	return val, keep
+}
+
+// Submap returns a copy of the MapMapAny for the given first key, or nil
+// if there is none.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Submap(key1 K1) MapMapAny[K2, K3, V] {
+	shard := smmm.shard(key1)
+	shard.RLock()
+	defer shard.RUnlock()
+	submap := shard.m[key1]
+	if submap == nil {
+		return nil
+	}
+	return submap.Clone()
+}
+
+// Len returns the total number of values in the map.
+//
+// As other goroutines may be modifying the map, this may be out of date
+// by the time it is returned.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Len() int {
+	smmm.init()
+	l := 0
+	for i := range smmm.s.shards {
+		shard := &smmm.s.shards[i]
+		shard.RLock()
+		l += shard.m.Len()
+		shard.RUnlock()
+	}
+	return l
+}
+
+// Snapshot returns a consistent copy of the full map as a MapMapMapAny.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Snapshot() MapMapMapAny[K1, K2, K3, V] {
+	smmm.init()
+	smmm.s.rLockAll()
+	defer smmm.s.rUnlockAll()
+
+	snapshot := MapMapMapAny[K1, K2, K3, V]{}
+	for i := range smmm.s.shards {
+		for key1, mapmap := range smmm.s.shards[i].m {
+			snapshot[key1] = mapmap.Clone()
+		}
+	}
+	return snapshot
+}
+
+// All returns an iterator over a snapshot of the map, yielding the keys
+// as a Tuple3 and the value in the value slot.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) All() iter.Seq2[Tuple3[K1, K2, K3], V] {
+	return func(yield func(Tuple3[K1, K2, K3], V) bool) {
+		for key, val := range smmm.Snapshot().All() {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of a snapshot of the map.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Keys() iter.Seq[Tuple3[K1, K2, K3]] {
+	return func(yield func(Tuple3[K1, K2, K3]) bool) {
+		for key := range smmm.Snapshot().Keys() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of a snapshot of the map.
+func (smmm *SyncMapMapMapAny[K1, K2, K3, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for val := range smmm.Snapshot().Values() {
+			if !yield(val) {
+				return
+			}
+		}
+	}
+}
+
+// SyncMapMapMap is a SyncMapMapMapAny with a comparable value type,
+// which allows for the CompareAndSwap and CompareAndDelete methods.
+type SyncMapMapMap[K1, K2, K3, V comparable] struct {
+	SyncMapMapMapAny[K1, K2, K3, V]
+}
+
+// NewSyncMapMapMap returns a new SyncMapMapMap with the given number of
+// shards.
+func NewSyncMapMapMap[K1, K2, K3, V comparable](
+	shardCount int,
+) *SyncMapMapMap[K1, K2, K3, V] {
+	smmm := &SyncMapMapMap[K1, K2, K3, V]{}
+	smmm.s.init(shardCount, newMapMapMapAny[K1, K2, K3, V])
+	return smmm
+}
+
+// CompareAndSwap swaps the old and new values for the given keys if the
+// value stored in the map is equal to old. It returns whether the swap
+// was performed.
+func (smmm *SyncMapMapMap[K1, K2, K3, V]) CompareAndSwap(
+	key1 K1,
+	key2 K2,
+	key3 K3,
+	old, new V,
+) bool {
+	shard := smmm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	current, exists := shard.m[key1][key2][key3]
+	if !exists || current != old {
+		return false
+	}
+	shard.m[key1][key2][key3] = new
+	return true
+}
+
+// CompareAndDelete deletes the value for the given keys if it is equal
+// to old. It returns whether the value was deleted.
+func (smmm *SyncMapMapMap[K1, K2, K3, V]) CompareAndDelete(
+	key1 K1,
+	key2 K2,
+	key3 K3,
+	old V,
+) bool {
+	shard := smmm.shard(key1)
+	shard.Lock()
+	defer shard.Unlock()
+	current, exists := shard.m[key1][key2][key3]
+	if !exists || current != old {
+		return false
+	}
+	shard.m.Delete(key1, key2, key3)
+	return true
+}
+
+// SyncMapSet is a MapSet that is safe for concurrent use, sharded by
+// the key.
+type SyncMapSet[K, V comparable] struct {
+	s shards[K, MapSet[K, V]]
+}
+
+// NewSyncMapSet returns a new SyncMapSet with the given number of
+// shards.
+func NewSyncMapSet[K, V comparable](shardCount int) *SyncMapSet[K, V] {
+	sms := &SyncMapSet[K, V]{}
+	sms.s.init(shardCount, newMapSet[K, V])
+	return sms
+}
+
+func newMapSet[K, V comparable]() MapSet[K, V] {
+	return MapSet[K, V]{}
+}
+
+func (sms *SyncMapSet[K, V]) init() {
+	sms.s.init(DefaultShards, newMapSet[K, V])
+}
+
+func (sms *SyncMapSet[K, V]) shard(key K) *lockedMap[MapSet[K, V]] {
+	sms.init()
+	return sms.s.get(key)
+}
+
+// Contains returns true if the set for the given key contains the given
+// value.
+func (sms *SyncMapSet[K, V]) Contains(key K, val V) bool {
+	shard := sms.shard(key)
+	shard.RLock()
+	defer shard.RUnlock()
+	return shard.m[key].Contains(val)
+}
+
+// Get returns a copy of the set for the given key, or nil if there is
+// none.
+func (sms *SyncMapSet[K, V]) Get(key K) Set[V] {
+	shard := sms.shard(key)
+	shard.RLock()
+	defer shard.RUnlock()
+	return shard.m[key].Clone()
+}
+
+// Add adds the given value to the set for the given key. It returns true
+// if the value was added, and false if it was already present.
+func (sms *SyncMapSet[K, V]) Add(key K, val V) bool {
+	shard := sms.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if shard.m[key].Contains(val) {
+		return false
+	}
+	shard.m.Add(key, val)
+	return true
+}
+
+// AddByTuple will add to the set via the given tuple.
+func (sms *SyncMapSet[K, V]) AddByTuple(key Tuple2[K, V]) bool {
+	return sms.Add(key.Key1, key.Key2)
+}
+
+// Union will union the passed-in set into the set for the given key.
+// The passed-in set is copied, not retained.
+func (sms *SyncMapSet[K, V]) Union(key K, r Set[V]) {
+	shard := sms.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.m.Union(key, r)
+}
+
+// Delete removes the given value from the set for the given key,
+// removing the set if it is empty. It returns true if the value was
+// present.
+func (sms *SyncMapSet[K, V]) Delete(key K, val V) bool {
+	shard := sms.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	if !shard.m[key].Contains(val) {
+		return false
+	}
+	shard.m.Delete(key, val)
+	return true
+}
+
+// Len returns the total number of values in all the sets.
+//
+// As other goroutines may be modifying the map, this may be out of date
+// by the time it is returned.
+func (sms *SyncMapSet[K, V]) Len() int {
+	sms.init()
+	l := 0
+	for i := range sms.s.shards {
+		shard := &sms.s.shards[i]
+		shard.RLock()
+		for _, set := range shard.m {
+			l += len(set)
+		}
+		shard.RUnlock()
+	}
+	return l
+}
+
+// Snapshot returns a consistent copy of the full map as a MapSet.
+func (sms *SyncMapSet[K, V]) Snapshot() MapSet[K, V] {
+	sms.init()
+	sms.s.rLockAll()
+	defer sms.s.rUnlockAll()
+
+	snapshot := MapSet[K, V]{}
+	for i := range sms.s.shards {
+		for key, set := range sms.s.shards[i].m {
+			snapshot[key] = set.Clone()
+		}
+	}
+	return snapshot
+}
+
+// All returns an iterator over a snapshot of the map, yielding each key
+// and value pair.
+func (sms *SyncMapSet[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for key, set := range sms.Snapshot() {
+			for val := range set {
+				if !yield(key, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// SyncDualMap is a DualMap that is safe for concurrent use.
+//
+// The Primary map is sharded by the primary key, and the Reverse map by
+// the secondary key. Writes lock one shard of each, always the primary
+// shard first, so that both directions are always updated together.
+type SyncDualMap[P, S comparable, V any] struct {
+	primary shards[P, MapMapAny[P, S, V]]
+	reverse shards[S, MapMapAny[S, P, V]]
+}
+
+// NewSyncDualMap returns a new SyncDualMap with the given number of
+// shards for each direction.
+func NewSyncDualMap[P, S comparable, V any](shardCount int) *SyncDualMap[P, S, V] {
+	sdm := &SyncDualMap[P, S, V]{}
+	sdm.init(shardCount)
+	return sdm
+}
+
+func (sdm *SyncDualMap[P, S, V]) init(shardCount int) {
+	sdm.primary.init(shardCount, newMapMapAny[P, S, V])
+	sdm.reverse.init(shardCount, newMapMapAny[S, P, V])
+}
+
+// lock locks the shards for the given keys, returning them.
+func (sdm *SyncDualMap[P, S, V]) lock(
+	p P,
+	s S,
+) (*lockedMap[MapMapAny[P, S, V]], *lockedMap[MapMapAny[S, P, V]]) {
+	sdm.init(DefaultShards)
+	primary := sdm.primary.get(p)
+	reverse := sdm.reverse.get(s)
+	primary.Lock()
+	reverse.Lock()
+	return primary, reverse
+}
+
+// GetByTuple retrieves by the given primary/secondary tuple.
+func (sdm *SyncDualMap[P, S, V]) GetByTuple(key Tuple2[P, S]) (val V, exists bool) {
+	sdm.init(DefaultShards)
+	shard := sdm.primary.get(key.Key1)
+	shard.RLock()
+	defer shard.RUnlock()
+	val, exists = shard.m[key.Key1][key.Key2]
+	return val, exists
+}
+
+// GetByPrimary returns a copy of all the secondary keys and values for
+// the given primary key, or nil if there are none.
+func (sdm *SyncDualMap[P, S, V]) GetByPrimary(p P) map[S]V {
+	sdm.init(DefaultShards)
+	shard := sdm.primary.get(p)
+	shard.RLock()
+	defer shard.RUnlock()
+	if shard.m[p] == nil {
+		return nil
+	}
+	return maps.Clone(shard.m[p])
+}
+
+// GetBySecondary returns a copy of all the primary keys and values for
+// the given secondary key, or nil if there are none.
+func (sdm *SyncDualMap[P, S, V]) GetBySecondary(s S) map[P]V {
+	sdm.init(DefaultShards)
+	shard := sdm.reverse.get(s)
+	shard.RLock()
+	defer shard.RUnlock()
+	if shard.m[s] == nil {
+		return nil
+	}
+	return maps.Clone(shard.m[s])
+}
+
+// Set sets the given value with the keys in primary/secondary order.
+func (sdm *SyncDualMap[P, S, V]) Set(p P, s S, value V) {
+	primary, reverse := sdm.lock(p, s)
+	defer primary.Unlock()
+	defer reverse.Unlock()
+	primary.m.Set(p, s, value)
+	reverse.m.Set(s, p, value)
+}
+
+// SetByTuple sets by the primary/secondary tuple.
+func (sdm *SyncDualMap[P, S, V]) SetByTuple(key Tuple2[P, S], value V) {
+	sdm.Set(key.Key1, key.Key2, value)
+}
+
+// Delete deletes by the keys in primary/secondary order.
+func (sdm *SyncDualMap[P, S, V]) Delete(p P, s S) {
+	primary, reverse := sdm.lock(p, s)
+	defer primary.Unlock()
+	defer reverse.Unlock()
+	primary.m.Delete(p, s)
+	reverse.m.Delete(s, p)
+}
+
+// DeleteByTuple deletes by the primary/secondary tuple.
+func (sdm *SyncDualMap[P, S, V]) DeleteByTuple(key Tuple2[P, S]) {
+	sdm.Delete(key.Key1, key.Key2)
+}
+
+// GetOrSet returns the existing value for the keys if present, with
+// loaded true. Otherwise, it sets the given value and returns it, with
+// loaded false.
+func (sdm *SyncDualMap[P, S, V]) GetOrSet(p P, s S, value V) (actual V, loaded bool) {
+	primary, reverse := sdm.lock(p, s)
+	defer primary.Unlock()
+	defer reverse.Unlock()
+	actual, loaded = primary.m[p][s]
+	if loaded {
+		return actual, true
+	}
+	primary.m.Set(p, s, value)
+	reverse.m.Set(s, p, value)
+	return value, false
+}
+
+// Update atomically updates the value for the given keys. See
+// SyncMapMapAny.Update.
+func (sdm *SyncDualMap[P, S, V]) Update(
+	p P,
+	s S,
+	f func(old V, exists bool) (V, bool),
+) (V, bool) {
+	primary, reverse := sdm.lock(p, s)
+	defer primary.Unlock()
+	defer reverse.Unlock()
+	old, exists := primary.m[p][s]
+	val, keep := f(old, exists)
+	if keep {
+		primary.m.Set(p, s, val)
+		reverse.m.Set(s, p, val)
+	} else {
+		primary.m.Delete(p, s)
+		reverse.m.Delete(s, p)
+	}
+	return val, keep
+}
+
+// Len returns the total number of values in the map.
+//
+// As other goroutines may be modifying the map, this may be out of date
+// by the time it is returned.
+func (sdm *SyncDualMap[P, S, V]) Len() int {
+	sdm.init(DefaultShards)
+	l := 0
+	for i := range sdm.primary.shards {
+		shard := &sdm.primary.shards[i]
+		shard.RLock()
+		l += shard.m.Len()
+		shard.RUnlock()
+	}
+	return l
+}
+
+// Snapshot returns a consistent copy of the full map as a DualMap.
+func (sdm *SyncDualMap[P, S, V]) Snapshot() *DualMap[P, S, V] {
+	sdm.init(DefaultShards)
+	sdm.primary.rLockAll()
+	defer sdm.primary.rUnlockAll()
+
+	dm := &DualMap[P, S, V]{
+		Primary: MapMapAny[P, S, V]{},
+		Reverse: MapMapAny[S, P, V]{},
+	}
+	for i := range sdm.primary.shards {
+		for key, val := range sdm.primary.shards[i].m.All() {
+			dm.SetByTuple(key, val)
+		}
+	}
+	return dm
+}
+
+// All returns an iterator over a snapshot of the map, yielding the keys
+// as a primary/secondary Tuple2 and the value in the value slot.
+func (sdm *SyncDualMap[P, S, V]) All() iter.Seq2[Tuple2[P, S], V] {
+	return func(yield func(Tuple2[P, S], V) bool) {
+		for key, val := range sdm.Snapshot().Primary.All() {
+			if !yield(key, val) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package cm
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSyncMapMapAny(t *testing.T) {
+	var smm SyncMapMapAny[int, int, string]
+
+	smm.Set(0, 1, "a")
+	smm.SetByTuple(Tuple2[int, int]{0, 2}, "b")
+	smm.Set(1, 1, "c")
+
+	val, exists := smm.Get(0, 1)
+	if val != "a" || !exists {
+		t.Fatal("couldn't get a set value")
+	}
+	val, exists = smm.GetByTuple(Tuple2[int, int]{0, 2})
+	if val != "b" || !exists {
+		t.Fatal("couldn't get a set value by tuple")
+	}
+	if smm.Len() != 3 {
+		t.Fatal("incorrect Len")
+	}
+
+	actual, loaded := smm.GetOrSet(0, 1, "z")
+	if actual != "a" || !loaded {
+		t.Fatal("GetOrSet overwrote an existing value")
+	}
+	actual, loaded = smm.GetOrSet(2, 2, "d")
+	if actual != "d" || loaded {
+		t.Fatal("GetOrSet didn't set a new value")
+	}
+
+	val, exists = smm.Update(2, 2, func(old string, exists bool) (string, bool) {
+		return old + "d", true
+	})
+	if val != "dd" || !exists {
+		t.Fatal("Update didn't update")
+	}
+	_, exists = smm.Update(2, 2, func(string, bool) (string, bool) {
+		return "", false
+	})
+	if exists {
+		t.Fatal("Update didn't delete")
+	}
+	if smm.Submap(2) != nil {
+		t.Fatal("Update didn't clean up the submap")
+	}
+
+	if !reflect.DeepEqual(smm.Submap(0), map[int]string{1: "a", 2: "b"}) {
+		t.Fatal("incorrect Submap")
+	}
+
+	expected := MapMapAny[int, int, string]{}
+	expected.Set(0, 1, "a")
+	expected.Set(0, 2, "b")
+	expected.Set(1, 1, "c")
+	if !reflect.DeepEqual(smm.Snapshot(), expected) {
+		t.Fatal("incorrect Snapshot")
+	}
+
+	count := 0
+	for key, val := range smm.All() {
+		count++
+		// not holding locks, so this is legal
+		smm.SetByTuple(key, val+"!")
+	}
+	if count != 3 {
+		t.Fatal("incorrect All")
+	}
+	count = 0
+	for range smm.Keys() {
+		count++
+	}
+	for val := range smm.Values() {
+		count++
+		if val[len(val)-1] != '!' {
+			t.Fatal("changes made during iteration didn't stick")
+		}
+	}
+	if count != 6 {
+		t.Fatal("incorrect Keys or Values")
+	}
+	for range smm.All() {
+		break
+	}
+	for range smm.Keys() {
+		break
+	}
+	for range smm.Values() {
+		break
+	}
+
+	smm.Delete(0, 1)
+	smm.DeleteByTuple(Tuple2[int, int]{0, 2})
+	if smm.Submap(0) != nil || smm.Len() != 1 {
+		t.Fatal("Delete didn't work")
+	}
+}
+
+func TestSyncMapMap(t *testing.T) {
+	smm := NewSyncMapMap[int, int, int](0)
+
+	smm.Set(0, 1, 2)
+	if smm.CompareAndSwap(0, 1, 3, 4) {
+		t.Fatal("CompareAndSwap swapped the wrong value")
+	}
+	if smm.CompareAndSwap(9, 9, 0, 4) {
+		t.Fatal("CompareAndSwap swapped a nonexistent value")
+	}
+	if !smm.CompareAndSwap(0, 1, 2, 4) {
+		t.Fatal("CompareAndSwap didn't swap")
+	}
+	if val, _ := smm.Get(0, 1); val != 4 {
+		t.Fatal("CompareAndSwap didn't set the new value")
+	}
+	if smm.CompareAndDelete(0, 1, 2) {
+		t.Fatal("CompareAndDelete deleted the wrong value")
+	}
+	if !smm.CompareAndDelete(0, 1, 4) {
+		t.Fatal("CompareAndDelete didn't delete")
+	}
+	if smm.Len() != 0 || len(smm.Snapshot()) != 0 {
+		t.Fatal("CompareAndDelete didn't clean up")
+	}
+
+	anyMap := NewSyncMapMapAny[int, int, int](4)
+	anyMap.Set(0, 1, 2)
+	if anyMap.Len() != 1 {
+		t.Fatal("NewSyncMapMapAny doesn't work")
+	}
+}
+
+func TestSyncMapMapConcurrency(t *testing.T) {
+	smm := NewSyncMapMap[int, int, int](4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				smm.Update(j%10, 0, func(old int, _ bool) (int, bool) {
+					return old + 1, true
+				})
+				for range smm.All() {
+					break
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for val := range smm.Values() {
+		total += val
+	}
+	if total != 800 {
+		t.Fatalf("lost updates: %d", total)
+	}
+}
+
+func TestSyncMapMapMapAny(t *testing.T) {
+	var smmm SyncMapMapMapAny[int, int, int, string]
+
+	smmm.Set(0, 1, 2, "a")
+	smmm.SetByTuple(Tuple3[int, int, int]{0, 1, 3}, "b")
+	smmm.Set(1, 1, 1, "c")
+
+	val, exists := smmm.Get(0, 1, 2)
+	if val != "a" || !exists {
+		t.Fatal("couldn't get a set value")
+	}
+	val, exists = smmm.GetByTuple(Tuple3[int, int, int]{0, 1, 3})
+	if val != "b" || !exists {
+		t.Fatal("couldn't get a set value by tuple")
+	}
+	if smmm.Len() != 3 {
+		t.Fatal("incorrect Len")
+	}
+
+	actual, loaded := smmm.GetOrSet(0, 1, 2, "z")
+	if actual != "a" || !loaded {
+		t.Fatal("GetOrSet overwrote an existing value")
+	}
+	actual, loaded = smmm.GetOrSet(2, 2, 2, "d")
+	if actual != "d" || loaded {
+		t.Fatal("GetOrSet didn't set a new value")
+	}
+	val, exists = smmm.Update(2, 2, 2, func(old string, _ bool) (string, bool) {
+		return old + "d", true
+	})
+	if val != "dd" || !exists {
+		t.Fatal("Update didn't update")
+	}
+	smmm.Update(2, 2, 2, func(string, bool) (string, bool) { return "", false })
+	if smmm.Submap(2) != nil {
+		t.Fatal("Update didn't delete and clean up")
+	}
+
+	expected := MapMapMapAny[int, int, int, string]{}
+	expected.Set(0, 1, 2, "a")
+	expected.Set(0, 1, 3, "b")
+	expected.Set(1, 1, 1, "c")
+	if !reflect.DeepEqual(smmm.Snapshot(), expected) {
+		t.Fatal("incorrect Snapshot")
+	}
+	if !reflect.DeepEqual(smmm.Submap(0), expected[0]) {
+		t.Fatal("incorrect Submap")
+	}
+
+	count := 0
+	for range smmm.All() {
+		count++
+	}
+	for range smmm.Keys() {
+		count++
+	}
+	for range smmm.Values() {
+		count++
+	}
+	if count != 9 {
+		t.Fatal("incorrect iteration")
+	}
+	for range smmm.All() {
+		break
+	}
+	for range smmm.Keys() {
+		break
+	}
+	for range smmm.Values() {
+		break
+	}
+
+	smmm.Delete(0, 1, 2)
+	smmm.DeleteByTuple(Tuple3[int, int, int]{0, 1, 3})
+	if smmm.Submap(0) != nil || smmm.Len() != 1 {
+		t.Fatal("Delete didn't work")
+	}
+}
+
+func TestSyncMapMapMap(t *testing.T) {
+	smmm := NewSyncMapMapMap[int, int, int, int](2)
+
+	smmm.Set(0, 1, 2, 3)
+	if smmm.CompareAndSwap(0, 1, 2, 4, 5) {
+		t.Fatal("CompareAndSwap swapped the wrong value")
+	}
+	if !smmm.CompareAndSwap(0, 1, 2, 3, 5) {
+		t.Fatal("CompareAndSwap didn't swap")
+	}
+	if val, _ := smmm.Get(0, 1, 2); val != 5 {
+		t.Fatal("CompareAndSwap didn't set the new value")
+	}
+	if smmm.CompareAndDelete(0, 1, 2, 3) {
+		t.Fatal("CompareAndDelete deleted the wrong value")
+	}
+	if !smmm.CompareAndDelete(0, 1, 2, 5) {
+		t.Fatal("CompareAndDelete didn't delete")
+	}
+	if len(smmm.Snapshot()) != 0 {
+		t.Fatal("CompareAndDelete didn't clean up")
+	}
+
+	anyMap := NewSyncMapMapMapAny[int, int, int, int](2)
+	anyMap.Set(0, 1, 2, 3)
+	if anyMap.Len() != 1 {
+		t.Fatal("NewSyncMapMapMapAny doesn't work")
+	}
+}
+
+func TestSyncMapSet(t *testing.T) {
+	var sms SyncMapSet[string, int]
+
+	if !sms.Add("a", 1) || sms.Add("a", 1) {
+		t.Fatal("Add reports incorrectly")
+	}
+	sms.AddByTuple(Tuple2[string, int]{"a", 2})
+	sms.Union("b", SetFromSlice([]int{3, 4}))
+
+	if !sms.Contains("a", 1) || sms.Contains("a", 3) {
+		t.Fatal("incorrect Contains")
+	}
+	if !sms.Get("b").Equal(SetFromSlice([]int{3, 4})) {
+		t.Fatal("incorrect Get")
+	}
+	if sms.Len() != 4 {
+		t.Fatal("incorrect Len")
+	}
+
+	expected := MapSet[string, int]{}
+	expected.Add("a", 1)
+	expected.Add("a", 2)
+	expected.Add("b", 3)
+	expected.Add("b", 4)
+	if !reflect.DeepEqual(sms.Snapshot(), expected) {
+		t.Fatal("incorrect Snapshot")
+	}
+
+	count := 0
+	for range sms.All() {
+		count++
+	}
+	if count != 4 {
+		t.Fatal("incorrect All")
+	}
+	for range sms.All() {
+		break
+	}
+
+	if sms.Delete("c", 1) {
+		t.Fatal("deleted something that doesn't exist")
+	}
+	if !sms.Delete("b", 3) || !sms.Delete("b", 4) {
+		t.Fatal("couldn't delete")
+	}
+	if sms.Get("b") != nil {
+		t.Fatal("Delete didn't clean up the set")
+	}
+
+	sms2 := NewSyncMapSet[int, int](2)
+	sms2.Add(1, 2)
+	if sms2.Len() != 1 {
+		t.Fatal("NewSyncMapSet doesn't work")
+	}
+}
+
+func TestSyncDualMap(t *testing.T) {
+	var sdm SyncDualMap[int, string, int]
+
+	sdm.Set(1, "a", 10)
+	sdm.SetByTuple(Tuple2[int, string]{2, "a"}, 20)
+	sdm.Set(1, "b", 30)
+
+	val, exists := sdm.GetByTuple(Tuple2[int, string]{1, "a"})
+	if val != 10 || !exists {
+		t.Fatal("couldn't get a set value")
+	}
+	if !reflect.DeepEqual(sdm.GetByPrimary(1), map[string]int{"a": 10, "b": 30}) {
+		t.Fatal("incorrect GetByPrimary")
+	}
+	if !reflect.DeepEqual(sdm.GetBySecondary("a"), map[int]int{1: 10, 2: 20}) {
+		t.Fatal("incorrect GetBySecondary")
+	}
+	if sdm.Len() != 3 {
+		t.Fatal("incorrect Len")
+	}
+
+	actual, loaded := sdm.GetOrSet(1, "a", 99)
+	if actual != 10 || !loaded {
+		t.Fatal("GetOrSet overwrote an existing value")
+	}
+	actual, loaded = sdm.GetOrSet(3, "c", 40)
+	if actual != 40 || loaded {
+		t.Fatal("GetOrSet didn't set a new value")
+	}
+	if sdm.GetBySecondary("c")[3] != 40 {
+		t.Fatal("GetOrSet didn't set the reverse map")
+	}
+
+	val, exists = sdm.Update(3, "c", func(old int, _ bool) (int, bool) {
+		return old + 1, true
+	})
+	if val != 41 || !exists || sdm.GetBySecondary("c")[3] != 41 {
+		t.Fatal("Update didn't update both directions")
+	}
+	sdm.Update(3, "c", func(int, bool) (int, bool) { return 0, false })
+	if sdm.GetByPrimary(3) != nil || sdm.GetBySecondary("c") != nil {
+		t.Fatal("Update didn't delete both directions")
+	}
+
+	expected := &DualMap[int, string, int]{}
+	expected.Set(1, "a", 10)
+	expected.Set(2, "a", 20)
+	expected.Set(1, "b", 30)
+	if !reflect.DeepEqual(sdm.Snapshot(), expected) {
+		t.Fatal("incorrect Snapshot")
+	}
+
+	count := 0
+	for range sdm.All() {
+		count++
+	}
+	if count != 3 {
+		t.Fatal("incorrect All")
+	}
+	for range sdm.All() {
+		break
+	}
+
+	sdm.Delete(1, "a")
+	sdm.DeleteByTuple(Tuple2[int, string]{2, "a"})
+	if sdm.GetBySecondary("a") != nil || sdm.Len() != 1 {
+		t.Fatal("Delete didn't work")
+	}
+
+	sdm2 := NewSyncDualMap[int, int, int](2)
+	sdm2.Set(1, 2, 3)
+	if sdm2.Len() != 1 {
+		t.Fatal("NewSyncDualMap doesn't work")
+	}
+}
+
+func TestSyncDualMapConcurrency(t *testing.T) {
+	sdm := NewSyncDualMap[int, int, int](4)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sdm.Set(i, j%10, j)
+				sdm.Delete(j%10, i)
+				snapshot := sdm.Snapshot()
+				if snapshot.Primary.Len() != snapshot.Reverse.Len() {
+					panic("inconsistent snapshot")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
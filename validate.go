@@ -0,0 +1,251 @@
+package cm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// InvariantKind identifies which invariant an InvariantError reports as
+// violated.
+type InvariantKind int
+
+const (
+	// InvariantEmptySubmap is an empty map or set stored inside another
+	// map. The Delete methods never leave these behind, but direct
+	// writes can. In a DualMap, this is an empty submap of the Primary
+	// map.
+	InvariantEmptySubmap InvariantKind = iota + 1
+
+	// InvariantMissingReverse is a value in a DualMap's Primary map that
+	// is not in its Reverse map.
+	InvariantMissingReverse
+
+	// InvariantMissingPrimary is a value in a DualMap's Reverse map that
+	// is not in its Primary map.
+	InvariantMissingPrimary
+
+	// InvariantValueMismatch is a key in a DualMap whose value differs
+	// between the Primary and the Reverse maps.
+	InvariantValueMismatch
+
+	// InvariantEmptyReverseSubmap is an empty submap of a DualMap's
+	// Reverse map.
+	InvariantEmptyReverseSubmap
+)
+
+func (ik InvariantKind) String() string {
+	switch ik {
+	case InvariantEmptySubmap:
+		return "empty submap"
+	case InvariantMissingReverse:
+		return "missing from Reverse"
+	case InvariantMissingPrimary:
+		return "missing from Primary"
+	case InvariantValueMismatch:
+		return "value mismatch"
+	case InvariantEmptyReverseSubmap:
+		return "empty Reverse submap"
+	default:
+		return fmt.Sprintf("InvariantKind(%d)", int(ik))
+	}
+}
+
+// ErrInvariantViolated is the error wrapped by all InvariantErrors.
+var ErrInvariantViolated = errors.New("invariant violated")
+
+// InvariantError describes a single violation of one of the invariants
+// the methods of this package maintain, as found by a Validate method.
+//
+// Keys is the path of keys to the violation, outermost first. For an
+// empty submap, it is the keys leading to the empty submap, so for an
+// InvariantEmptyReverseSubmap it is the secondary key. For the other
+// DualMap violations, it is the primary and secondary key, in that
+// order, regardless of which map the violation was found in.
+type InvariantError struct {
+	Kind InvariantKind
+	Keys []any
+}
+
+// Error implements the error interface.
+func (ie *InvariantError) Error() string {
+	return fmt.Sprintf("%s at %v", ie.Kind, ie.Keys)
+}
+
+// Unwrap returns ErrInvariantViolated.
+func (ie *InvariantError) Unwrap() error {
+	return ErrInvariantViolated
+}
+
+func invariantError(kind InvariantKind, keys ...any) error {
+	return &InvariantError{kind, keys}
+}
+
+// Validate checks the MapMap for empty submaps, which can be left behind
+// by writing to the maps directly, and returns an *InvariantError for
+// each one, combined with errors.Join. It returns nil if there are none.
+func (mma MapMapAny[K1, K2, V]) Validate() error {
+	var errs []error
+	for key1, submap := range mma {
+		if len(submap) == 0 {
+			errs = append(errs, invariantError(InvariantEmptySubmap, key1))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Normalize removes any empty submaps from the MapMap, after which
+// Validate will return nil.
+func (mma MapMapAny[K1, K2, V]) Normalize() {
+	for key1, submap := range mma {
+		if len(submap) == 0 {
+			delete(mma, key1)
+		}
+	}
+}
+
+// Validate checks the MapMap for empty submaps. See MapMapAny.Validate.
+func (mm MapMap[K1, K2, V]) Validate() error {
+	return MapMapAny[K1, K2, V](mm).Validate()
+}
+
+// Normalize removes any empty submaps from the MapMap.
+func (mm MapMap[K1, K2, V]) Normalize() {
+	MapMapAny[K1, K2, V](mm).Normalize()
+}
+
+// Validate checks the MapMapMap for empty submaps at either level, which
+// can be left behind by writing to the maps directly, and returns an
+// *InvariantError for each one, combined with errors.Join. It returns nil
+// if there are none.
+func (mmma MapMapMapAny[K1, K2, K3, V]) Validate() error {
+	var errs []error
+	for key1, mma := range mmma {
+		if len(mma) == 0 {
+			errs = append(errs, invariantError(InvariantEmptySubmap, key1))
+		}
+		for key2, submap := range mma {
+			if len(submap) == 0 {
+				errs = append(errs,
+					invariantError(InvariantEmptySubmap, key1, key2))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Normalize removes any empty submaps from the MapMapMap, including any
+// that become empty as their own empty submaps are removed. Afterwards,
+// Validate will return nil.
+func (mmma MapMapMapAny[K1, K2, K3, V]) Normalize() {
+	for key1, mma := range mmma {
+		mma.Normalize()
+		if len(mma) == 0 {
+			delete(mmma, key1)
+		}
+	}
+}
+
+// Validate checks the MapMapMap for empty submaps. See
+// MapMapMapAny.Validate.
+func (mmm MapMapMap[K1, K2, K3, V]) Validate() error {
+	return MapMapMapAny[K1, K2, K3, V](mmm).Validate()
+}
+
+// Normalize removes any empty submaps from the MapMapMap.
+func (mmm MapMapMap[K1, K2, K3, V]) Normalize() {
+	MapMapMapAny[K1, K2, K3, V](mmm).Normalize()
+}
+
+// Validate checks the MapSet for empty sets, which can be left behind by
+// writing to the sets directly, and returns an *InvariantError for each
+// one, combined with errors.Join. It returns nil if there are none.
+func (ms MapSet[K, V]) Validate() error {
+	var errs []error
+	for key, set := range ms {
+		if len(set) == 0 {
+			errs = append(errs, invariantError(InvariantEmptySubmap, key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Normalize removes any empty sets from the MapSet, after which Validate
+// will return nil.
+func (ms MapSet[K, V]) Normalize() {
+	for key, set := range ms {
+		if len(set) == 0 {
+			delete(ms, key)
+		}
+	}
+}
+
+// Validate checks that the Primary and Reverse maps of the DualMap
+// contain the same keys and that neither contains empty submaps,
+// returning an *InvariantError for each violation, combined with
+// errors.Join. It returns nil if there are none.
+//
+// As the value type is not comparable, this does not check that the
+// values match. Use ValidateFunc for that.
+func (dm *DualMap[P, S, V]) Validate() error {
+	return dm.validate(nil)
+}
+
+// ValidateFunc does everything Validate does, and also uses the
+// passed-in function to check that the values in the Primary and
+// Reverse maps are equal.
+func (dm *DualMap[P, S, V]) ValidateFunc(eq func(v1, v2 V) bool) error {
+	return dm.validate(eq)
+}
+
+func (dm *DualMap[P, S, V]) validate(eq func(v1, v2 V) bool) error {
+	var errs []error
+
+	for p, submap := range dm.Primary {
+		if len(submap) == 0 {
+			errs = append(errs, invariantError(InvariantEmptySubmap, p))
+		}
+		for s, val := range submap {
+			rVal, exists := dm.Reverse[s][p]
+			switch {
+			case !exists:
+				errs = append(errs, invariantError(InvariantMissingReverse, p, s))
+			case eq != nil && !eq(val, rVal):
+				errs = append(errs, invariantError(InvariantValueMismatch, p, s))
+			}
+		}
+	}
+	for s, submap := range dm.Reverse {
+		if len(submap) == 0 {
+			errs = append(errs, invariantError(InvariantEmptyReverseSubmap, s))
+		}
+		for p := range submap {
+			if _, exists := dm.Primary[p][s]; !exists {
+				errs = append(errs, invariantError(InvariantMissingPrimary, p, s))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Repair restores the DualMap's invariants by treating the Primary map
+// as authoritative: empty submaps are removed from it and the Reverse map
+// is rebuilt from it. Afterwards, ValidateFunc will return nil.
+//
+// Anything that only exists in the Reverse map is lost. If the Reverse
+// map is the one that is correct, Repair a DualMap[S, P, V] with the two
+// maps swapped instead.
+func (dm *DualMap[P, S, V]) Repair() {
+	if dm.Primary == nil {
+		dm.Reverse = nil
+		return
+	}
+
+	dm.Primary.Normalize()
+	dm.Reverse = MapMapAny[S, P, V]{}
+	for p, submap := range dm.Primary {
+		for s, val := range submap {
+			dm.Reverse.Set(s, p, val)
+		}
+	}
+}
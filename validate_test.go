@@ -0,0 +1,148 @@
+package cm
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// violations returns the strings of the InvariantErrors in the error
+// returned by a Validate method, sorted.
+func violations(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrInvariantViolated) {
+		t.Fatal("validation error doesn't wrap ErrInvariantViolated")
+	}
+	var r []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ie *InvariantError
+		if !errors.As(e, &ie) {
+			t.Fatal("validation error isn't an InvariantError")
+		}
+		r = append(r, ie.Error())
+	}
+	slices.Sort(r)
+	return r
+}
+
+func TestMapMapValidate(t *testing.T) {
+	mm := MapMap[int, int, int]{}
+	mm.Set(1, 1, 1)
+	if mm.Validate() != nil {
+		t.Fatal("valid MapMap doesn't validate")
+	}
+
+	mm[2] = map[int]int{}
+	mm[3] = map[int]int{}
+	if !reflect.DeepEqual(violations(t, mm.Validate()), []string{
+		"empty submap at [2]", "empty submap at [3]",
+	}) {
+		t.Fatal("incorrect violations")
+	}
+
+	mm.Normalize()
+	if mm.Validate() != nil || mm.Len() != 1 || len(mm) != 1 {
+		t.Fatal("Normalize didn't fix the MapMap")
+	}
+}
+
+func TestMapMapMapValidate(t *testing.T) {
+	mmm := MapMapMap[int, int, int, int]{}
+	mmm.Set(1, 1, 1, 1)
+	if mmm.Validate() != nil {
+		t.Fatal("valid MapMapMap doesn't validate")
+	}
+
+	mmm[1][2] = map[int]int{}
+	mmm[2] = MapMapAny[int, int, int]{}
+	mmm[3] = MapMapAny[int, int, int]{3: {}}
+	if !reflect.DeepEqual(violations(t, mmm.Validate()), []string{
+		"empty submap at [1 2]", "empty submap at [2]", "empty submap at [3 3]",
+	}) {
+		t.Fatal("incorrect violations")
+	}
+
+	mmm.Normalize()
+	expected := MapMapMap[int, int, int, int]{}
+	expected.Set(1, 1, 1, 1)
+	if mmm.Validate() != nil || !reflect.DeepEqual(mmm, expected) {
+		t.Fatal("Normalize didn't fix the MapMapMap")
+	}
+}
+
+func TestMapSetValidate(t *testing.T) {
+	ms := MapSet[int, int]{}
+	ms.Add(1, 1)
+	ms[2] = Set[int]{}
+	if !reflect.DeepEqual(violations(t, ms.Validate()), []string{
+		"empty submap at [2]",
+	}) {
+		t.Fatal("incorrect violations")
+	}
+	ms.Normalize()
+	if ms.Validate() != nil || len(ms) != 1 {
+		t.Fatal("Normalize didn't fix the MapSet")
+	}
+}
+
+func TestDualMapValidate(t *testing.T) {
+	dm := &DualMap[int, string, int]{}
+	if dm.Validate() != nil {
+		t.Fatal("zero DualMap doesn't validate")
+	}
+	dm.Repair()
+	if dm.Primary != nil || dm.Reverse != nil {
+		t.Fatal("repairing a zero DualMap created maps")
+	}
+
+	dm.Set(1, "a", 1)
+	dm.Set(2, "b", 2)
+	dm.Set(3, "c", 3)
+	eq := func(v1, v2 int) bool { return v1 == v2 }
+	if dm.Validate() != nil || dm.ValidateFunc(eq) != nil {
+		t.Fatal("valid DualMap doesn't validate")
+	}
+
+	dm.Primary[4] = map[string]int{}
+	dm.Reverse["d"] = map[int]int{}
+	dm.Primary[1]["z"] = 5
+	dm.Reverse["y"] = map[int]int{2: 6}
+	dm.Reverse["c"][3] = 7
+
+	if !reflect.DeepEqual(violations(t, dm.Validate()), []string{
+		"empty Reverse submap at [d]",
+		"empty submap at [4]",
+		"missing from Primary at [2 y]",
+		"missing from Reverse at [1 z]",
+	}) {
+		t.Fatal("incorrect violations")
+	}
+	found := violations(t, dm.ValidateFunc(eq))
+	if len(found) != 5 || !slices.Contains(found, "value mismatch at [3 c]") {
+		t.Fatal("incorrect violations with values")
+	}
+
+	dm.Repair()
+	if dm.ValidateFunc(eq) != nil {
+		t.Fatal("Repair didn't fix the DualMap")
+	}
+	expected := &DualMap[int, string, int]{}
+	expected.Set(1, "a", 1)
+	expected.Set(1, "z", 5)
+	expected.Set(2, "b", 2)
+	expected.Set(3, "c", 3)
+	if !reflect.DeepEqual(dm, expected) {
+		t.Fatal("Repair didn't treat the Primary as authoritative")
+	}
+}
+
+func TestInvariantKind(t *testing.T) {
+	if !strings.Contains(InvariantKind(0).String(), "0") {
+		t.Fatal("unknown InvariantKind doesn't stringify")
+	}
+}